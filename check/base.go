@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/fzipi/go-ftw/config"
+	"github.com/fzipi/go-ftw/ftwhttp"
 	"github.com/fzipi/go-ftw/test"
 	"github.com/fzipi/go-ftw/waflog"
 )
@@ -13,6 +14,7 @@ type FTWCheck struct {
 	log       *waflog.FTWLogLines
 	expected  *test.Output
 	overrides *config.FTWTestOverride
+	logMargin time.Duration
 }
 
 // NewCheck creates a new FTWCheck, allowing to inject the configuration
@@ -29,15 +31,25 @@ func NewCheck(c *config.FTWConfiguration) *FTWCheck {
 		},
 		expected:  &test.Output{},
 		overrides: &c.TestOverride,
+		logMargin: c.LogMargin,
+	}
+	if check.logMargin == 0 {
+		check.logMargin = config.DefaultLogMargin
 	}
 
 	return check
 }
 
-// SetRoundTripTime sets the time the roundtrip took so we can check logs with it
+// SetRoundTripTime sets the time the roundtrip took so we can check logs with it.
+// The log margin is added to until, so logs written right after the response are found.
 func (c *FTWCheck) SetRoundTripTime(since time.Time, until time.Time) {
 	c.log.Since = since
-	c.log.Until = until
+	c.log.Until = until.Add(c.logMargin)
+}
+
+// SetLogSink makes the log checks use the in-memory sink instead of the log file
+func (c *FTWCheck) SetLogSink(sink *waflog.MemorySink) {
+	c.log.Sink = sink
 }
 
 // SetExpectTestOutput sets the combined expected output from this test
@@ -55,9 +67,18 @@ func (c *FTWCheck) SetExpectResponse(response string) {
 	c.expected.ResponseContains = response
 }
 
-// SetExpectError sets the boolean if we are expecting an error from the server
+// SetExpectError sets the boolean if we are expecting any error from the server
 func (c *FTWCheck) SetExpectError(expect bool) {
-	c.expected.ExpectError = expect
+	if expect {
+		c.expected.ExpectError = test.AnyError
+	} else {
+		c.expected.ExpectError = test.NoError
+	}
+}
+
+// SetExpectErrorClass sets the class of the error we are expecting from the server
+func (c *FTWCheck) SetExpectErrorClass(class ftwhttp.ErrorClass) {
+	c.expected.ExpectError = test.ExpectError(class)
 }
 
 // SetLogContains sets the string to look for in logs
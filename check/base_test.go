@@ -57,11 +57,11 @@ func TestNewCheck(t *testing.T) {
 		ResponseContains: "",
 		LogContains:      "nothing",
 		NoLogContains:    "",
-		ExpectError:      true,
+		ExpectError:      test.AnyError,
 	}
 	c.SetExpectTestOutput(&to)
 
-	if c.expected.ExpectError != true {
+	if c.expected.ExpectError != test.AnyError {
 		t.Error("Problem setting expected output")
 	}
 
@@ -135,3 +135,25 @@ func TestCloudMode(t *testing.T) {
 	}
 
 }
+
+func TestLogMargin(t *testing.T) {
+	err := config.NewConfigFromString(yamlApacheConfig)
+	if err != nil {
+		t.Error(err)
+	}
+
+	c := NewCheck(config.FTWConfig)
+	now := time.Now()
+	c.SetRoundTripTime(now, now)
+	if c.log.Until.Sub(now) != config.DefaultLogMargin {
+		t.Errorf("Default log margin not applied: %s", c.log.Until.Sub(now))
+	}
+
+	config.FTWConfig.LogMargin = time.Second
+	c = NewCheck(config.FTWConfig)
+	c.SetRoundTripTime(now, now)
+	if !c.log.Since.Equal(now) || c.log.Until.Sub(now) != time.Second {
+		t.Errorf("Configured log margin not applied: %s", c.log.Until.Sub(now))
+	}
+	config.FTWConfig.LogMargin = 0
+}
@@ -1,15 +1,19 @@
 package check
 
-import "github.com/rs/zerolog/log"
+import (
+	"github.com/fzipi/go-ftw/ftwhttp"
+	"github.com/rs/zerolog/log"
+)
 
-// AssertExpectError helper to check if this error was expected or not
+// AssertExpectError helper to check if this error was expected or not.
+// When a class of error is expected, only errors of that class match.
 func (c *FTWCheck) AssertExpectError(err error) bool {
 	if err != nil {
-		log.Debug().Msgf("ftw/check: expected error? -> %t, and error is %s", c.expected.ExpectError, err.Error())
+		log.Debug().Msgf("ftw/check: expected error? -> %q, and error is %s (%s)", c.expected.ExpectError, err.Error(), ftwhttp.ClassifyError(err))
 	} else {
-		log.Debug().Msgf("ftw/check: expected error? -> %t, and error is nil", c.expected.ExpectError)
+		log.Debug().Msgf("ftw/check: expected error? -> %q, and error is nil", c.expected.ExpectError)
 	}
-	if c.expected.ExpectError && err != nil {
+	if err != nil && c.expected.ExpectError.Matches(ftwhttp.ClassifyError(err)) {
 		return true
 	}
 	return false
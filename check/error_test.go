@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/fzipi/go-ftw/config"
+	"github.com/fzipi/go-ftw/ftwhttp"
 )
 
 var expectedOKTests = []struct {
@@ -54,3 +55,27 @@ func TestAssertResponseFail(t *testing.T) {
 		}
 	}
 }
+
+func TestAssertResponseErrorClass(t *testing.T) {
+	err := config.NewConfigFromString(yamlApacheConfig)
+
+	if err != nil {
+		t.Errorf("Failed!")
+	}
+
+	c := NewCheck(config.FTWConfig)
+	c.SetExpectErrorClass(ftwhttp.ConnectionReset)
+
+	reset := &ftwhttp.ClassifiedError{Class: ftwhttp.ConnectionReset, Err: errors.New("a")}
+	if !c.AssertExpectError(reset) {
+		t.Errorf("Failed !")
+	}
+
+	dns := &ftwhttp.ClassifiedError{Class: ftwhttp.DNSFailure, Err: errors.New("a")}
+	if c.AssertExpectError(dns) {
+		t.Errorf("Failed !")
+	}
+	if explanation := c.ExplainError(dns); explanation != "expected a connection_reset error, got dns_failure: a" {
+		t.Errorf("Failed ! %s", explanation)
+	}
+}
@@ -0,0 +1,62 @@
+package check
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fzipi/go-ftw/ftwhttp"
+	"github.com/fzipi/go-ftw/test"
+)
+
+// Explain describes what the test expected, compared with the status received, so failures can be reported
+func (c *FTWCheck) Explain(status int) string {
+	var reasons []string
+
+	if len(c.expected.Status) > 0 {
+		reasons = append(reasons, fmt.Sprintf("expected status in %v, got %d", c.expected.Status, status))
+	}
+	if c.expected.ResponseContains != "" {
+		reasons = append(reasons, fmt.Sprintf("expected response to contain %q", c.expected.ResponseContains))
+	}
+	if c.expected.LogContains != "" {
+		reasons = append(reasons, fmt.Sprintf("expected logs to contain %q", c.expected.LogContains))
+	}
+	if c.expected.NoLogContains != "" {
+		reasons = append(reasons, fmt.Sprintf("expected logs not to contain %q", c.expected.NoLogContains))
+	}
+	if len(c.expected.GRPCStatus) > 0 {
+		reasons = append(reasons, fmt.Sprintf("expected grpc-status in %v", c.expected.GRPCStatus))
+	}
+	if ws := c.expected.WebSocket; ws != nil {
+		if ws.MessageContains != "" {
+			reasons = append(reasons, fmt.Sprintf("expected a websocket message containing %q", ws.MessageContains))
+		}
+		if ws.Closed != nil && *ws.Closed {
+			reasons = append(reasons, "expected the websocket connection to be closed")
+		}
+		if ws.Closed != nil && !*ws.Closed {
+			reasons = append(reasons, "expected the websocket connection to stay open")
+		}
+	}
+	switch c.expected.ExpectError {
+	case test.NoError:
+	case test.AnyError:
+		reasons = append(reasons, "expected an error, but got a response")
+	default:
+		reasons = append(reasons, fmt.Sprintf("expected a %s error, but got a response", c.expected.ExpectError))
+	}
+	if len(reasons) == 0 {
+		return "the test output has nothing to check"
+	}
+
+	return strings.Join(reasons, "; ")
+}
+
+// ExplainError describes the error received, and the error class the test expected, if any
+func (c *FTWCheck) ExplainError(err error) string {
+	class := ftwhttp.ClassifyError(err)
+	if c.expected.ExpectError.Expected() && c.expected.ExpectError != test.AnyError {
+		return fmt.Sprintf("expected a %s error, got %s: %s", c.expected.ExpectError, class, err.Error())
+	}
+	return fmt.Sprintf("unexpected %s error: %s", class, err.Error())
+}
@@ -0,0 +1,39 @@
+package check
+
+import (
+	"testing"
+
+	"github.com/fzipi/go-ftw/config"
+	"github.com/fzipi/go-ftw/ftwhttp"
+	"github.com/fzipi/go-ftw/test"
+)
+
+var explainTests = []struct {
+	expected    test.Output
+	explanation string
+}{
+	{test.Output{Status: []int{403}}, "expected status in [403], got 200"},
+	{test.Output{ResponseContains: "blocked"}, `expected response to contain "blocked"`},
+	{test.Output{Status: []int{403, 406}, LogContains: `id "949110"`}, `expected status in [403 406], got 200; expected logs to contain "id \"949110\""`},
+	{test.Output{NoLogContains: "920100"}, `expected logs not to contain "920100"`},
+	{test.Output{ExpectError: test.AnyError}, "expected an error, but got a response"},
+	{test.Output{ExpectError: test.ExpectError(ftwhttp.ConnectionReset)}, "expected a connection_reset error, but got a response"},
+	{test.Output{}, "the test output has nothing to check"},
+}
+
+func TestExplain(t *testing.T) {
+	err := config.NewConfigFromString(yamlApacheConfig)
+	if err != nil {
+		t.Errorf("Failed!")
+	}
+
+	c := NewCheck(config.FTWConfig)
+
+	for _, e := range explainTests {
+		expected := e.expected
+		c.SetExpectTestOutput(&expected)
+		if got := c.Explain(200); got != e.explanation {
+			t.Errorf("Got explanation %q, expected %q", got, e.explanation)
+		}
+	}
+}
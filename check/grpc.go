@@ -0,0 +1,17 @@
+package check
+
+import "github.com/fzipi/go-ftw/ftwhttp"
+
+// AssertGRPCStatus will match the expected grpc-status list with the one received in the response
+func (c *FTWCheck) AssertGRPCStatus(response *ftwhttp.Response) bool {
+	status, ok := response.GRPCStatus()
+	if !ok {
+		return false
+	}
+	for _, i := range c.expected.GRPCStatus {
+		if i == status {
+			return true
+		}
+	}
+	return false
+}
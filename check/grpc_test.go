@@ -0,0 +1,42 @@
+package check
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/fzipi/go-ftw/config"
+	"github.com/fzipi/go-ftw/ftwhttp"
+	"github.com/fzipi/go-ftw/test"
+)
+
+func grpcResponse(trailer http.Header, header http.Header) *ftwhttp.Response {
+	return &ftwhttp.Response{Parsed: http.Response{StatusCode: 200, Header: header, Trailer: trailer}}
+}
+
+var grpcStatusTests = []struct {
+	expected []int
+	response *ftwhttp.Response
+	passes   bool
+}{
+	{[]int{7}, grpcResponse(http.Header{"Grpc-Status": {"7"}}, http.Header{}), true},
+	{[]int{0, 7}, grpcResponse(http.Header{}, http.Header{"Grpc-Status": {"7"}}), true},
+	{[]int{0}, grpcResponse(http.Header{"Grpc-Status": {"7"}}, http.Header{}), false},
+	{[]int{0}, grpcResponse(http.Header{}, http.Header{}), false},
+	{nil, grpcResponse(http.Header{"Grpc-Status": {"0"}}, http.Header{}), false},
+}
+
+func TestAssertGRPCStatus(t *testing.T) {
+	err := config.NewConfigFromString(yamlApacheConfig)
+	if err != nil {
+		t.Errorf("Failed!")
+	}
+
+	c := NewCheck(config.FTWConfig)
+
+	for i, g := range grpcStatusTests {
+		c.SetExpectTestOutput(&test.Output{GRPCStatus: g.expected})
+		if c.AssertGRPCStatus(g.response) != g.passes {
+			t.Errorf("Failed ! case %d", i)
+		}
+	}
+}
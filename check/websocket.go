@@ -0,0 +1,35 @@
+package check
+
+import (
+	"bytes"
+
+	"github.com/fzipi/go-ftw/ftwhttp"
+)
+
+// AssertWebSocket checks what happened after the websocket handshake. All the expectations set must match.
+func (c *FTWCheck) AssertWebSocket(result *ftwhttp.WebSocketResult) bool {
+	expected := c.expected.WebSocket
+	if expected == nil || result == nil {
+		return false
+	}
+	if expected.MessageContains == "" && expected.Closed == nil {
+		return false
+	}
+
+	if expected.MessageContains != "" && !messageContains(result.Messages, expected.MessageContains) {
+		return false
+	}
+	if expected.Closed != nil && *expected.Closed != result.Closed {
+		return false
+	}
+	return true
+}
+
+func messageContains(messages []ftwhttp.WebSocketMessage, needle string) bool {
+	for _, m := range messages {
+		if bytes.Contains(m.Data, []byte(needle)) {
+			return true
+		}
+	}
+	return false
+}
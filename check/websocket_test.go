@@ -0,0 +1,47 @@
+package check
+
+import (
+	"testing"
+
+	"github.com/fzipi/go-ftw/config"
+	"github.com/fzipi/go-ftw/ftwhttp"
+	"github.com/fzipi/go-ftw/test"
+)
+
+func boolPointer(b bool) *bool {
+	return &b
+}
+
+var websocketTests = []struct {
+	expected *test.WebSocketOutput
+	result   *ftwhttp.WebSocketResult
+	passes   bool
+}{
+	{nil, &ftwhttp.WebSocketResult{Closed: true}, false},
+	{&test.WebSocketOutput{Closed: boolPointer(true)}, nil, false},
+	{&test.WebSocketOutput{}, &ftwhttp.WebSocketResult{Closed: true}, false},
+	{&test.WebSocketOutput{Closed: boolPointer(true)}, &ftwhttp.WebSocketResult{Closed: true}, true},
+	{&test.WebSocketOutput{Closed: boolPointer(false)}, &ftwhttp.WebSocketResult{Closed: true}, false},
+	{&test.WebSocketOutput{MessageContains: "echo"}, &ftwhttp.WebSocketResult{
+		Messages: []ftwhttp.WebSocketMessage{{Opcode: ftwhttp.TextFrame, Data: []byte("echo: hi")}},
+	}, true},
+	{&test.WebSocketOutput{MessageContains: "echo", Closed: boolPointer(true)}, &ftwhttp.WebSocketResult{
+		Messages: []ftwhttp.WebSocketMessage{{Opcode: ftwhttp.TextFrame, Data: []byte("echo: hi")}},
+	}, false},
+}
+
+func TestAssertWebSocket(t *testing.T) {
+	err := config.NewConfigFromString(yamlApacheConfig)
+	if err != nil {
+		t.Errorf("Failed!")
+	}
+
+	c := NewCheck(config.FTWConfig)
+
+	for i, w := range websocketTests {
+		c.SetExpectTestOutput(&test.Output{WebSocket: w.expected})
+		if c.AssertWebSocket(w.result) != w.passes {
+			t.Errorf("Failed ! case %d", i)
+		}
+	}
+}
@@ -3,9 +3,11 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/fzipi/go-ftw/test"
 	"github.com/rs/zerolog/log"
+	"github.com/yargevad/filepathx"
 
 	"github.com/kyokomi/emoji"
 	"github.com/spf13/cobra"
@@ -15,29 +17,44 @@ import (
 var checkCmd = &cobra.Command{
 	Use:   "check",
 	Short: "Checks ftw test files for syntax errors.",
-	Long:  ``,
+	Long: `Checks ftw test files strictly: yaml syntax, unknown fields, and values that cannot work,
+like conflicting body sources, invalid status codes or log regexps that do not compile.
+Problems are shown as file:line:column.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		dir, _ := cmd.Flags().GetString("dir")
-		checkFiles(dir)
+		schema, _ := cmd.Flags().GetBool("schema")
+		output, _ := cmd.Flags().GetString("output")
+		if schema {
+			fmt.Print(string(test.Schema))
+			return
+		}
+		checkFiles(dir, output)
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(checkCmd)
 	checkCmd.Flags().StringP("dir", "d", ".", "recursively find yaml tests in this directory")
+	checkCmd.Flags().BoolP("schema", "", false, "print the JSON Schema for test files, and exit")
+	checkCmd.Flags().StringP("output", "o", test.TextFormat, fmt.Sprintf("output format, one of %s", strings.Join(test.Formats, ", ")))
 }
 
-func checkFiles(dir string) {
-	var exit int
+// checkFiles checks all the files, reporting every problem found. The exit code is 1 when there were problems.
+func checkFiles(dir string, output string) {
 	files := fmt.Sprintf("%s/**/*.yaml", dir)
 	log.Trace().Msgf("ftw/check: checking files using glob pattern: %s", files)
-	tests, err := test.GetTestsFromFiles(files)
+	testFiles, err := filepathx.Glob(files)
 	if err != nil {
 		emoji.Printf("ftw/check: :collision: oops, found %s\n", err.Error())
-		exit = 1
-	} else {
-		emoji.Printf("ftw/check: checked %d files, everything looks good!\n", len(tests))
-		exit = 0
+		os.Exit(1)
+	}
+
+	report := test.CheckFiles(testFiles)
+	if err = test.WriteReport(os.Stdout, report, output); err != nil {
+		emoji.Printf("ftw/check: :collision: oops, found %s\n", err.Error())
+		os.Exit(1)
+	}
+	if !report.OK() {
+		os.Exit(1)
 	}
-	os.Exit(exit)
 }
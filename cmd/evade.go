@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/kyokomi/emoji"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+
+	"github.com/fzipi/go-ftw/evasion"
+	"github.com/fzipi/go-ftw/runner"
+	"github.com/fzipi/go-ftw/test"
+)
+
+// evadeCmd represents the evade command
+var evadeCmd = &cobra.Command{
+	Use:   "evade",
+	Short: "Checks evasions of tests with an attack string",
+	Long: `Runs the tests that mark their payload using 'attack', and when they pass, variants of them
+using evasion techniques: other encodings, mixed case, whitespace and comments, parameter pollution, and other body content types.
+Variants that fail bypassed detection. The exit code is the number of them, plus the number of tests
+and variants that could not be run, up to 255.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		excludes, _ := cmd.Flags().GetStringArray("exclude")
+		includes, _ := cmd.Flags().GetStringArray("include")
+		excludeFiles, _ := cmd.Flags().GetStringArray("exclude-file")
+		includeFiles, _ := cmd.Flags().GetStringArray("include-file")
+		tags, _ := cmd.Flags().GetString("tags")
+		dir, _ := cmd.Flags().GetString("dir")
+		quiet, _ := cmd.Flags().GetBool("quiet")
+		names, _ := cmd.Flags().GetStringSlice("technique")
+		list, _ := cmd.Flags().GetBool("list")
+		if list {
+			listTechniques()
+			return
+		}
+		if !quiet {
+			log.Info().Msgf(emoji.Sprintf(":hammer_and_wrench: Starting tests!\n"))
+		} else {
+			zerolog.SetGlobalLevel(zerolog.Disabled)
+		}
+		techniques, err := evasion.Select(names)
+		if err != nil {
+			log.Fatal().Msgf("ftw/evade: %s", err.Error())
+		}
+		include, err := inclusion(includes, includeFiles)
+		if err != nil {
+			log.Fatal().Msgf("ftw/evade: bad --include: %s", err.Error())
+		}
+		exclude, err := selection(excludes, excludeFiles)
+		if err != nil {
+			log.Fatal().Msgf("ftw/evade: bad --exclude: %s", err.Error())
+		}
+		if _, err = runner.ParseTagExpression(tags); err != nil {
+			log.Fatal().Msgf("ftw/evade: %s", err.Error())
+		}
+		files := fmt.Sprintf("%s/**/*.yaml", dir)
+		tests, err := test.GetTestsFromFiles(files)
+		if err != nil {
+			log.Fatal().Msgf("ftw/evade: %s", err.Error())
+		}
+
+		bypasses := runner.RunEvasions(tests, runner.Config{
+			Include: include,
+			Exclude: exclude,
+			Tags:    tags,
+			Quiet:   quiet,
+		}, techniques)
+		// exit codes are a byte, so larger numbers would wrap around
+		if bypasses > 255 {
+			bypasses = 255
+		}
+		os.Exit(bypasses)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(evadeCmd)
+	evadeCmd.Flags().StringArrayP("exclude", "e", nil, "exclude tests matching this Go regexp. Can be repeated, and combined with --include.")
+	evadeCmd.Flags().StringArrayP("include", "i", nil, "include only tests matching this Go regexp. Can be repeated.")
+	evadeCmd.Flags().StringArrayP("exclude-file", "", nil, "exclude the tests listed in this file, one test id per line. Can be repeated.")
+	evadeCmd.Flags().StringArrayP("include-file", "", nil, "include only the tests listed in this file, one test id per line. Can be repeated.")
+	evadeCmd.Flags().StringP("tags", "", "", "run only tests whose tags match this expression, e.g. 'sqli && !slow'")
+	evadeCmd.Flags().StringP("dir", "d", ".", "recursively find yaml tests in this directory")
+	evadeCmd.Flags().BoolP("quiet", "q", false, "do not show test by test, only results")
+	evadeCmd.Flags().StringSliceP("technique", "", nil, "use only this technique. Can be repeated, see --list")
+	evadeCmd.Flags().BoolP("list", "", false, "list the evasion techniques, and exit")
+}
+
+func listTechniques() {
+	for _, t := range evasion.Techniques {
+		fmt.Printf("%-20s %s\n", t.Name, t.Description)
+	}
+}
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/fzipi/go-ftw/test"
+	"github.com/rs/zerolog/log"
+	"github.com/yargevad/filepathx"
+
+	"github.com/kyokomi/emoji"
+	"github.com/spf13/cobra"
+)
+
+// fmtCmd represents the fmt command
+var fmtCmd = &cobra.Command{
+	Use:   "fmt",
+	Short: "Formats ftw test files.",
+	Long: `Rewrites ftw test files in a canonical layout: keys in a fixed order, two spaces of indentation,
+and the simplest quoting that keeps every value byte-identical. Comments are kept.
+With --check, files are not changed, and the exit code is 1 when some file is not formatted.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		dir, _ := cmd.Flags().GetString("dir")
+		check, _ := cmd.Flags().GetBool("check")
+		formatFiles(dir, check)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(fmtCmd)
+	fmtCmd.Flags().StringP("dir", "d", ".", "recursively find yaml tests in this directory")
+	fmtCmd.Flags().BoolP("check", "", false, "only list the files that are not formatted, and fail if there are any")
+}
+
+// formatFiles formats all the files, showing the ones that changed. Files that cannot be formatted are left as they are.
+func formatFiles(dir string, check bool) {
+	files := fmt.Sprintf("%s/**/*.yaml", dir)
+	log.Trace().Msgf("ftw/fmt: formatting files using glob pattern: %s", files)
+	testFiles, err := filepathx.Glob(files)
+	if err != nil {
+		emoji.Printf("ftw/fmt: :collision: oops, found %s\n", err.Error())
+		os.Exit(1)
+	}
+
+	failed := false
+	for _, file := range testFiles {
+		formatted, changed, err := test.FormatFile(file)
+		if err != nil {
+			emoji.Printf("ftw/fmt: :collision: oops, found %s\n", err.Error())
+			failed = true
+			continue
+		}
+		if !changed {
+			continue
+		}
+		if check {
+			fmt.Printf("%s: not formatted\n", file)
+			failed = true
+			continue
+		}
+		info, err := os.Stat(file)
+		if err == nil {
+			err = os.WriteFile(file, formatted, info.Mode())
+		}
+		if err != nil {
+			emoji.Printf("ftw/fmt: :collision: oops, found %s\n", err.Error())
+			failed = true
+			continue
+		}
+		fmt.Println(file)
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/fzipi/go-ftw/config"
+	"github.com/fzipi/go-ftw/lint"
+	"github.com/fzipi/go-ftw/test"
+	"github.com/rs/zerolog/log"
+	"github.com/yargevad/filepathx"
+
+	"github.com/kyokomi/emoji"
+	"github.com/spf13/cobra"
+)
+
+// lintCmd represents the lint command
+var lintCmd = &cobra.Command{
+	Use:   "lint",
+	Short: "Checks the conventions of a test corpus.",
+	Long: `Checks the conventions of a test corpus: unique test titles, titles numbered in order after
+the rule id in the file name, meta.name matching the file, descriptions, and stages expecting something.
+Rules are configured in the lint section of the config file, or using --rule name=level.
+Add '# ftw-lint-disable rule' to a line, or in the line before, for ignoring a problem there.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		dir, _ := cmd.Flags().GetString("dir")
+		output, _ := cmd.Flags().GetString("output")
+		rules, _ := cmd.Flags().GetStringSlice("rule")
+		fix, _ := cmd.Flags().GetBool("fix")
+		list, _ := cmd.Flags().GetBool("list")
+		if list {
+			listRules()
+			return
+		}
+		lintFiles(dir, output, rules, fix)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(lintCmd)
+	lintCmd.Flags().StringP("dir", "d", ".", "recursively find yaml tests in this directory")
+	lintCmd.Flags().StringP("output", "o", test.TextFormat, fmt.Sprintf("output format, one of %s", strings.Join(test.Formats, ", ")))
+	lintCmd.Flags().StringSliceP("rule", "", nil, "set the level of a rule, like 'desc=off', overriding the config. Can be repeated")
+	lintCmd.Flags().BoolP("fix", "", false, "fix the problems that can be fixed safely, like test numbering, before linting")
+	lintCmd.Flags().BoolP("list", "", false, "list the rules with their level, and exit")
+}
+
+// lintLevels merges the levels in the config with the ones passed as flags
+func lintLevels(rules []string) (map[string]string, error) {
+	levels := make(map[string]string)
+	for name, level := range config.FTWConfig.Lint.Rules {
+		levels[name] = level
+	}
+	for _, rule := range rules {
+		parts := strings.SplitN(rule, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("bad rule %q, use name=level", rule)
+		}
+		levels[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+	return levels, nil
+}
+
+func newLinter(rules []string) *lint.Linter {
+	levels, err := lintLevels(rules)
+	if err == nil {
+		var linter *lint.Linter
+		if linter, err = lint.NewLinter(levels); err == nil {
+			return linter
+		}
+	}
+	emoji.Printf("ftw/lint: :collision: oops, found %s\n", err.Error())
+	os.Exit(1)
+	return nil
+}
+
+func listRules() {
+	linter := newLinter(nil)
+	for _, r := range lint.Rules {
+		fmt.Printf("%-18s %-8s %s\n", r.Name, linter.Level(r.Name), r.Description)
+	}
+}
+
+// lintFiles lints all the files, fixing them first if asked. The exit code is 1 when there were errors.
+func lintFiles(dir string, output string, rules []string, fix bool) {
+	linter := newLinter(rules)
+
+	files := fmt.Sprintf("%s/**/*.yaml", dir)
+	log.Trace().Msgf("ftw/lint: linting files using glob pattern: %s", files)
+	testFiles, err := filepathx.Glob(files)
+	if err != nil {
+		emoji.Printf("ftw/lint: :collision: oops, found %s\n", err.Error())
+		os.Exit(1)
+	}
+
+	if fix {
+		fixed, err := linter.Fix(testFiles)
+		if err != nil {
+			emoji.Printf("ftw/lint: :collision: oops, found %s\n", err.Error())
+			os.Exit(1)
+		}
+		log.Info().Msgf("ftw/lint: fixed %d problems", fixed)
+	}
+
+	report := linter.Lint(testFiles)
+	if err = test.WriteReport(os.Stdout, report, output); err != nil {
+		emoji.Printf("ftw/lint: :collision: oops, found %s\n", err.Error())
+		os.Exit(1)
+	}
+	if !report.OK() {
+		os.Exit(1)
+	}
+}
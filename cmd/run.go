@@ -3,12 +3,14 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/kyokomi/emoji"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 
+	"github.com/fzipi/go-ftw/ftwhttp"
 	"github.com/fzipi/go-ftw/runner"
 	"github.com/fzipi/go-ftw/test"
 )
@@ -19,12 +21,17 @@ var runCmd = &cobra.Command{
 	Short: "Run Tests",
 	Long:  `Run all tests below a certain subdirectory. The command will search all y[a]ml files recursively and pass it to the test engine.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		exclude, _ := cmd.Flags().GetString("exclude")
-		include, _ := cmd.Flags().GetString("include")
+		excludes, _ := cmd.Flags().GetStringArray("exclude")
+		includes, _ := cmd.Flags().GetStringArray("include")
+		excludeFiles, _ := cmd.Flags().GetStringArray("exclude-file")
+		includeFiles, _ := cmd.Flags().GetStringArray("include-file")
+		tags, _ := cmd.Flags().GetString("tags")
 		id, _ := cmd.Flags().GetString("id")
 		dir, _ := cmd.Flags().GetString("dir")
 		showTime, _ := cmd.Flags().GetBool("time")
 		quiet, _ := cmd.Flags().GetBool("quiet")
+		virtualHosts, _ := cmd.Flags().GetStringSlice("virtual-host")
+		captureFile, _ := cmd.Flags().GetString("capture")
 		if !quiet {
 			log.Info().Msgf(emoji.Sprintf(":hammer_and_wrench: Starting tests!\n"))
 		} else {
@@ -33,8 +40,16 @@ var runCmd = &cobra.Command{
 		if id != "" {
 			log.Fatal().Msgf("--id is deprecated in favour of --include|-i")
 		}
-		if exclude != "" && include != "" {
-			log.Fatal().Msgf("You need to choose one: use --include (%s) or --exclude (%s)", include, exclude)
+		include, err := inclusion(includes, includeFiles)
+		if err != nil {
+			log.Fatal().Msgf("ftw/run: bad --include: %s", err.Error())
+		}
+		exclude, err := selection(excludes, excludeFiles)
+		if err != nil {
+			log.Fatal().Msgf("ftw/run: bad --exclude: %s", err.Error())
+		}
+		if _, err := runner.ParseTagExpression(tags); err != nil {
+			log.Fatal().Msgf("ftw/run: %s", err.Error())
 		}
 		files := fmt.Sprintf("%s/**/*.yaml", dir)
 		tests, err := test.GetTestsFromFiles(files)
@@ -43,16 +58,72 @@ var runCmd = &cobra.Command{
 			log.Fatal().Err(err)
 		}
 
-		os.Exit(runner.Run(include, exclude, showTime, quiet, tests))
+		var capture *ftwhttp.Capture
+		var f *os.File
+		if captureFile != "" {
+			if f, err = os.Create(captureFile); err != nil {
+				log.Fatal().Msgf("ftw/run: can't create capture file: %s", err.Error())
+			}
+			if capture, err = ftwhttp.NewCapture(f); err != nil {
+				log.Fatal().Msgf("ftw/run: can't write capture file: %s", err.Error())
+			}
+		}
+
+		failed := runner.RunWithConfig(tests, runner.Config{
+			Include:      include,
+			Exclude:      exclude,
+			Tags:         tags,
+			ShowTime:     showTime,
+			Quiet:        quiet,
+			VirtualHosts: virtualHosts,
+			Capture:      capture,
+		})
+
+		if capture != nil {
+			if err = capture.Close(); err != nil {
+				log.Error().Msgf("ftw/run: error writing capture file: %s", err.Error())
+			}
+			f.Close()
+		}
+		os.Exit(failed)
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(runCmd)
-	runCmd.Flags().StringP("exclude", "e", "", "exclude tests matching this Go regexp (e.g. to exclude all tests beginning with \"91\", use \"91.*\"). \nIf you want more permanent exclusion, check the 'testmodify' option in the config file.")
-	runCmd.Flags().StringP("include", "i", "", "include only tests matching this Go regexp (e.g. to include only tests beginning with \"91\", use \"91.*\").")
+	runCmd.Flags().StringArrayP("exclude", "e", nil, "exclude tests matching this Go regexp (e.g. to exclude all tests beginning with \"91\", use \"91.*\"). Can be repeated, and combined with --include. \nIf you want more permanent exclusion, check the 'testmodify' option in the config file.")
+	runCmd.Flags().StringArrayP("include", "i", nil, "include only tests matching this Go regexp (e.g. to include only tests beginning with \"91\", use \"91.*\"). Can be repeated.")
+	runCmd.Flags().StringArrayP("exclude-file", "", nil, "exclude the tests listed in this file, one test id per line. Can be repeated.")
+	runCmd.Flags().StringArrayP("include-file", "", nil, "include only the tests listed in this file, one test id per line. Can be repeated.")
+	runCmd.Flags().StringP("tags", "", "", "run only tests whose tags match this expression, e.g. 'sqli && pl2 && !slow'. Use &&, ||, ! and parentheses. Tests inherit the tags in the meta of their file.")
 	runCmd.Flags().StringP("id", "", "", "(deprecated). Use --include matching your test only.")
 	runCmd.Flags().StringP("dir", "d", ".", "recursively find yaml tests in this directory")
 	runCmd.Flags().BoolP("quiet", "q", false, "do not show test by test, only results")
 	runCmd.Flags().BoolP("time", "t", false, "show time spent per test")
+	runCmd.Flags().StringP("capture", "", "", "write all the traffic to this pcapng file, which can be opened in Wireshark")
+	runCmd.Flags().StringSliceP("virtual-host", "", nil, "run all tests once for each virtual host, setting the Host header and SNI. Can be repeated, and overrides 'virtualhosts' in the config file.")
+}
+
+// selection builds a single regexp from the regexps and the test ids in the files
+func selection(patterns []string, files []string) (string, error) {
+	var ids []string
+	for _, file := range files {
+		fileIDs, err := runner.ReadIDs(file)
+		if err != nil {
+			return "", err
+		}
+		ids = append(ids, fileIDs...)
+	}
+	return runner.MatchAny(patterns, ids)
+}
+
+// inclusion builds the regexp for the tests to include. When only id files were given, and they have no ids,
+// no test is included, instead of all of them.
+func inclusion(patterns []string, files []string) (string, error) {
+	include, err := selection(patterns, files)
+	if err == nil && include == "" && len(files) > 0 {
+		log.Warn().Msgf("ftw/run: no test ids in %s, no tests will be run", strings.Join(files, ", "))
+		include = runner.MatchNone
+	}
+	return include, err
 }
@@ -0,0 +1,267 @@
+// Package evasion generates variants of tests with an attack string, encoding or changing the attack
+// in the ways used for evading detection, so you can check a rule still catches them
+package evasion
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/fzipi/go-ftw/ftwhttp"
+	"github.com/fzipi/go-ftw/test"
+)
+
+// Technique is a way of changing the attack string in a request
+type Technique struct {
+	Name        string
+	Description string
+	// apply changes the input where the attack string is, returning false when the technique cannot be used
+	apply func(input *test.Input, attack string) bool
+}
+
+// Variant is a test changed using a technique
+type Variant struct {
+	Technique Technique
+	Test      test.Test
+}
+
+// TechniqueNames returns the names of all the techniques
+func TechniqueNames() []string {
+	var names []string
+	for _, t := range Techniques {
+		names = append(names, t.Name)
+	}
+	return names
+}
+
+// Select returns the techniques with the names, in the order they were given. No names means all the techniques.
+func Select(names []string) ([]Technique, error) {
+	if len(names) == 0 {
+		return Techniques, nil
+	}
+	var techniques []Technique
+	for _, name := range names {
+		found := false
+		for _, t := range Techniques {
+			if t.Name == name {
+				techniques = append(techniques, t)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown technique %q, use one of %s", name, strings.Join(TechniqueNames(), ", "))
+		}
+	}
+	return techniques, nil
+}
+
+// Variants returns a variant of the test for each technique that can be used with it, titled like `942100-1~mixed-case`.
+// Only the stages with the attack string change. Tests without attack have no variants.
+func Variants(t test.Test, techniques []Technique) []Variant {
+	if t.Attack == "" {
+		return nil
+	}
+	var variants []Variant
+	for _, technique := range techniques {
+		v := t
+		v.TestTitle = fmt.Sprintf("%s~%s", t.TestTitle, technique.Name)
+		v.Stages = make([]test.Stage, len(t.Stages))
+		changed := false
+		for s, stage := range t.Stages {
+			input := stage.Stage.Input
+			input.Headers = input.Headers.Clone()
+			if technique.apply(&input, t.Attack) {
+				stage.Stage.Input = input
+				changed = true
+			}
+			v.Stages[s] = stage
+		}
+		if changed {
+			variants = append(variants, Variant{Technique: technique, Test: v})
+		}
+	}
+	return variants
+}
+
+// HasAttack is true when the attack string of the test is in the uri, headers or data of some stage,
+// as it is or url-encoded
+func HasAttack(t test.Test) bool {
+	for _, stage := range t.Stages {
+		input := stage.Stage.Input
+		if len(uriLocations(&input, t.Attack)) > 0 || len(headerLocations(&input, t.Attack)) > 0 ||
+			len(bodyLocations(&input, t.Attack)) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// location is a place where the attack string was found, with the way it is written there
+type location struct {
+	// found is the attack string as it is written
+	found string
+	// encode writes a new attack string in the same way
+	encode func(string) string
+}
+
+// forms are the ways an attack string can be written in urls and form bodies
+func forms(attack string) []location {
+	escaped := url.QueryEscape(attack)
+	percent := strings.ReplaceAll(escaped, "+", "%20")
+	locations := []location{{found: escaped, encode: url.QueryEscape}}
+	if percent != escaped {
+		locations = append(locations, location{found: percent, encode: func(s string) string {
+			return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+		}})
+	}
+	if attack != escaped {
+		locations = append(locations, location{found: attack, encode: escapeRequestLine})
+	}
+	return locations
+}
+
+// uriLocations returns the ways the attack string is written in the uri
+func uriLocations(input *test.Input, attack string) []location {
+	var found []location
+	if input.URI == nil || attack == "" {
+		return nil
+	}
+	for _, l := range forms(attack) {
+		if strings.Contains(*input.URI, l.found) {
+			found = append(found, l)
+		}
+	}
+	return found
+}
+
+// headerLocations returns the names of the headers with the attack string, as it is
+func headerLocations(input *test.Input, attack string) []string {
+	var names []string
+	if attack == "" {
+		return nil
+	}
+	for name, value := range input.Headers {
+		if strings.Contains(value, attack) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// bodyLocations returns the ways the attack string is written in data. Attacks in form bodies are url-encoded
+// when they are changed, like the runner does with data that is not encoded.
+func bodyLocations(input *test.Input, attack string) []location {
+	if input.Data == nil || attack == "" {
+		return nil
+	}
+	body := string(input.ParseData())
+	var found []location
+	for _, l := range forms(attack) {
+		if !strings.Contains(body, l.found) {
+			continue
+		}
+		if l.found == attack {
+			l.encode = func(s string) string { return s }
+			if isForm(input) {
+				l.encode = url.QueryEscape
+			}
+		}
+		found = append(found, l)
+	}
+	return found
+}
+
+// isForm is true when the data of the input is sent as application/x-www-form-urlencoded, which is the default
+func isForm(input *test.Input) bool {
+	contentType := headerValue(input.Headers, ftwhttp.ContentTypeHeader)
+	return contentType == "" || strings.HasPrefix(contentType, "application/x-www-form-urlencoded")
+}
+
+// replace changes the attack string in the uri, the body and the headers to text, which is written like the
+// attack was. When encoded is used instead, it is written as it is, and only urls and form bodies change.
+func replace(input *test.Input, attack string, text string, encoded string) bool {
+	changed := false
+	for _, l := range uriLocations(input, attack) {
+		value := encoded
+		if value == "" {
+			value = l.encode(text)
+		}
+		uri := strings.ReplaceAll(*input.URI, l.found, value)
+		input.URI = &uri
+		changed = true
+	}
+	if locations := bodyLocations(input, attack); len(locations) > 0 && (encoded == "" || isForm(input)) {
+		body := string(input.ParseData())
+		for _, l := range locations {
+			value := encoded
+			if value == "" {
+				value = l.encode(text)
+			}
+			body = strings.ReplaceAll(body, l.found, value)
+		}
+		setBody(input, body)
+		changed = true
+	}
+	if encoded == "" && !strings.ContainsAny(text, "\r\n") {
+		for _, name := range headerLocations(input, attack) {
+			input.Headers[name] = strings.ReplaceAll(input.Headers[name], attack, text)
+			changed = true
+		}
+	}
+	return changed
+}
+
+// setBody sends the body as it is, keeping the content type of data
+func setBody(input *test.Input, body string) {
+	if isForm(input) {
+		setHeader(input, ftwhttp.ContentTypeHeader, "application/x-www-form-urlencoded")
+	}
+	input.Data = nil
+	input.DataBase64 = base64.StdEncoding.EncodeToString([]byte(body))
+	// the length changes
+	deleteHeader(input.Headers, "Content-Length")
+}
+
+// escapeRequestLine percent-encodes the bytes that cannot be written in a request line
+func escapeRequestLine(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c <= ' ' || c >= 0x7f || c == '#' {
+			fmt.Fprintf(&b, "%%%02X", c)
+		} else {
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// headerValue returns the value of the header, comparing names without case
+func headerValue(headers ftwhttp.Header, name string) string {
+	for h, v := range headers {
+		if strings.EqualFold(h, name) {
+			return v
+		}
+	}
+	return ""
+}
+
+// deleteHeader deletes the header, comparing names without case
+func deleteHeader(headers ftwhttp.Header, name string) {
+	for h := range headers {
+		if strings.EqualFold(h, name) {
+			delete(headers, h)
+		}
+	}
+}
+
+// setHeader sets the header, replacing the one with the same name in any case
+func setHeader(input *test.Input, name string, value string) {
+	if input.Headers == nil {
+		input.Headers = make(ftwhttp.Header)
+	}
+	deleteHeader(input.Headers, name)
+	input.Headers[name] = value
+}
@@ -0,0 +1,92 @@
+package evasion
+
+import (
+	"testing"
+
+	"github.com/fzipi/go-ftw/test"
+)
+
+func attackTest(uri string, data string) test.Test {
+	t := test.Test{TestTitle: "942100-1", Attack: "' or 1=1"}
+	input := test.Input{Headers: map[string]string{"Host": "localhost"}}
+	if uri != "" {
+		input.URI = &uri
+	}
+	if data != "" {
+		input.Data = &data
+	}
+	other := "/"
+	t.Stages = []test.Stage{
+		{Stage: test.StageData{Input: test.Input{URI: &other}}},
+		{Stage: test.StageData{Input: input}},
+	}
+	return t
+}
+
+func TestSelect(t *testing.T) {
+	techniques, err := Select(nil)
+	if err != nil || len(techniques) != len(Techniques) {
+		t.Errorf("Failed ! expected all the techniques, got %d: %v", len(techniques), err)
+	}
+
+	techniques, err = Select([]string{"mixed-case", "double-url"})
+	if err != nil || len(techniques) != 2 || techniques[0].Name != "mixed-case" || techniques[1].Name != "double-url" {
+		t.Errorf("Failed ! got %v: %v", techniques, err)
+	}
+
+	if _, err = Select([]string{"rot13"}); err == nil {
+		t.Errorf("Failed ! an unknown technique should fail")
+	}
+}
+
+func TestVariants(t *testing.T) {
+	original := attackTest("/?q=%27+or+1%3D1", "")
+	variants := Variants(original, Techniques)
+
+	// the body techniques cannot be used without a body
+	if len(variants) != len(Techniques)-2 {
+		t.Fatalf("Failed ! got %d variants", len(variants))
+	}
+	for _, v := range variants {
+		if v.Test.TestTitle != "942100-1~"+v.Technique.Name {
+			t.Errorf("Failed ! got title %s", v.Test.TestTitle)
+		}
+		if v.Test.Stages[0].Stage.Input.GetURI() != "/" {
+			t.Errorf("Failed ! stages without the attack should not change, got %s", v.Test.Stages[0].Stage.Input.GetURI())
+		}
+	}
+	if uri := variants[0].Test.Stages[1].Stage.Input.GetURI(); uri != "/?q=%2527%2520or%25201%253D1" {
+		t.Errorf("Failed ! got uri %s", uri)
+	}
+	if uri := original.Stages[1].Stage.Input.GetURI(); uri != "/?q=%27+or+1%3D1" {
+		t.Errorf("Failed ! the original test should not change, got uri %s", uri)
+	}
+
+	original.Attack = ""
+	if variants = Variants(original, Techniques); len(variants) != 0 {
+		t.Errorf("Failed ! tests without attack should have no variants")
+	}
+}
+
+func TestHasAttack(t *testing.T) {
+	for _, c := range []struct {
+		test     test.Test
+		expected bool
+	}{
+		{attackTest("/?q=' or 1=1", ""), true},
+		{attackTest("/?q=%27+or+1%3D1", ""), true},
+		{attackTest("/?q=%27%20or%201%3D1", ""), true},
+		{attackTest("", "q=' or 1=1"), true},
+		{attackTest("/?q=1", ""), false},
+	} {
+		if HasAttack(c.test) != c.expected {
+			t.Errorf("Failed ! expected %t for %s", c.expected, c.test.Stages[1].Stage.Input.GetURI())
+		}
+	}
+
+	withHeader := attackTest("/", "")
+	withHeader.Stages[1].Stage.Input.Headers["Referer"] = "' or 1=1"
+	if !HasAttack(withHeader) {
+		t.Errorf("Failed ! the attack in a header should be found")
+	}
+}
@@ -0,0 +1,275 @@
+package evasion
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/fzipi/go-ftw/ftwhttp"
+	"github.com/fzipi/go-ftw/test"
+)
+
+// Techniques are all the techniques, in the order their variants are run
+var Techniques = []Technique{
+	{
+		Name:        "double-url",
+		Description: "url-encodes the attack twice, like %2527 for '",
+		apply:       encoding(doubleURLEncode),
+	},
+	{
+		Name:        "unicode",
+		Description: "uses IIS %u encoding, like %u0027 for '",
+		apply:       encoding(unicodeEncode),
+	},
+	{
+		Name:        "overlong-utf8",
+		Description: "uses overlong two byte UTF-8 sequences, like %C0%A7 for '",
+		apply:       encoding(overlongEncode),
+	},
+	{
+		Name:        "mixed-case",
+		Description: "alternates upper and lower case letters, like UnIoN",
+		apply:       text(mixedCase),
+	},
+	{
+		Name:        "whitespace",
+		Description: "replaces spaces with tabs, and adds spaces around =",
+		apply:       text(whitespace),
+	},
+	{
+		Name:        "comments",
+		Description: "replaces spaces with /**/ comments",
+		apply:       text(comments),
+	},
+	{
+		Name:        "parameter-pollution",
+		Description: "splits the attack in two parameters with the same name, like q=' or&q= 1=1",
+		apply:       parameterPollution,
+	},
+	{
+		Name:        "json-body",
+		Description: "sends the form in the body as a JSON object",
+		apply:       jsonBody,
+	},
+	{
+		Name:        "multipart-body",
+		Description: "sends the form in the body as multipart/form-data",
+		apply:       multipartBody,
+	},
+}
+
+// encoding returns a technique writing the attack in urls and form bodies using the encoder
+func encoding(encode func(string) string) func(*test.Input, string) bool {
+	return func(input *test.Input, attack string) bool {
+		return replace(input, attack, "", encode(attack))
+	}
+}
+
+// text returns a technique changing the attack string using change, which returns false when the attack does not change
+func text(change func(string) (string, bool)) func(*test.Input, string) bool {
+	return func(input *test.Input, attack string) bool {
+		changed, ok := change(attack)
+		if !ok {
+			return false
+		}
+		return replace(input, attack, changed, "")
+	}
+}
+
+// isPlain is true for the characters never encoded by the encoding techniques
+func isPlain(c rune) bool {
+	return c < utf8.RuneSelf && (unicode.IsLetter(c) || unicode.IsDigit(c))
+}
+
+func doubleURLEncode(attack string) string {
+	var b strings.Builder
+	for i := 0; i < len(attack); i++ {
+		if c := attack[i]; isPlain(rune(c)) {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%25%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// unicodeEncode writes %u with the UTF-16 code units of each character, so characters outside
+// the BMP are written as surrogate pairs, like %uD83D%uDE00
+func unicodeEncode(attack string) string {
+	var b strings.Builder
+	for _, c := range attack {
+		switch {
+		case isPlain(c):
+			b.WriteRune(c)
+		case c > 0xffff:
+			high, low := utf16.EncodeRune(c)
+			fmt.Fprintf(&b, "%%u%04X%%u%04X", high, low)
+		default:
+			fmt.Fprintf(&b, "%%u%04X", c)
+		}
+	}
+	return b.String()
+}
+
+func overlongEncode(attack string) string {
+	var b strings.Builder
+	for i := 0; i < len(attack); i++ {
+		c := attack[i]
+		switch {
+		case isPlain(rune(c)):
+			b.WriteByte(c)
+		case c < utf8.RuneSelf:
+			fmt.Fprintf(&b, "%%%02X%%%02X", 0xc0|c>>6, 0x80|c&0x3f)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func mixedCase(attack string) (string, bool) {
+	var b strings.Builder
+	upper := true
+	for _, c := range attack {
+		if unicode.IsLetter(c) {
+			if upper {
+				c = unicode.ToUpper(c)
+			} else {
+				c = unicode.ToLower(c)
+			}
+			upper = !upper
+		}
+		b.WriteRune(c)
+	}
+	return b.String(), b.String() != attack
+}
+
+func whitespace(attack string) (string, bool) {
+	changed := strings.NewReplacer(" ", "\t", "=", " = ").Replace(attack)
+	return changed, changed != attack
+}
+
+func comments(attack string) (string, bool) {
+	changed := strings.ReplaceAll(attack, " ", "/**/")
+	return changed, changed != attack
+}
+
+// parameterPollution splits the attack in the value of a query or form parameter in two parameters with the same name
+func parameterPollution(input *test.Input, attack string) bool {
+	runes := []rune(attack)
+	if len(runes) < 2 {
+		return false
+	}
+	first, second := string(runes[:len(runes)/2]), string(runes[len(runes)/2:])
+
+	changed := false
+	for _, l := range uriLocations(input, attack) {
+		if uri, ok := pollute(*input.URI, l, first, second, "?&"); ok {
+			input.URI = &uri
+			changed = true
+		}
+	}
+	if isForm(input) {
+		body := string(input.ParseData())
+		polluted := false
+		for _, l := range bodyLocations(input, attack) {
+			if b, ok := pollute(body, l, first, second, "&"); ok {
+				body = b
+				polluted = true
+			}
+		}
+		if polluted {
+			setBody(input, body)
+			changed = true
+		}
+	}
+	return changed
+}
+
+// pollute splits the attack in s, when it is in the value of a parameter after one of the separators
+func pollute(s string, l location, first string, second string, separators string) (string, bool) {
+	i := strings.Index(s, l.found)
+	if i < 0 {
+		return s, false
+	}
+	start := strings.LastIndexAny(s[:i], separators) + 1
+	eq := strings.Index(s[start:i], "=")
+	if eq < 0 {
+		return s, false
+	}
+	name := s[start : start+eq]
+	split := l.encode(first) + "&" + name + "=" + l.encode(second)
+	return s[:i] + split + s[i+len(l.found):], true
+}
+
+// formValues reads the form in the body, keeping the order of the parameters
+func formValues(body string) [][2]string {
+	var values [][2]string
+	for _, pair := range strings.Split(body, "&") {
+		if pair == "" {
+			continue
+		}
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) == 1 {
+			kv = append(kv, "")
+		}
+		for i := range kv {
+			if decoded, err := url.QueryUnescape(kv[i]); err == nil {
+				kv[i] = decoded
+			}
+		}
+		values = append(values, [2]string{kv[0], kv[1]})
+	}
+	return values
+}
+
+// formWithAttack returns the form in the body, when the body is a form with the attack in it
+func formWithAttack(input *test.Input, attack string) ([][2]string, bool) {
+	if !isForm(input) || len(bodyLocations(input, attack)) == 0 {
+		return nil, false
+	}
+	values := formValues(string(input.ParseData()))
+	return values, len(values) > 0
+}
+
+func jsonBody(input *test.Input, attack string) bool {
+	values, ok := formWithAttack(input, attack)
+	if !ok {
+		return false
+	}
+	var b strings.Builder
+	b.WriteString("{")
+	for i, kv := range values {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		name, _ := json.Marshal(kv[0])
+		value, _ := json.Marshal(kv[1])
+		fmt.Fprintf(&b, "%s:%s", name, value)
+	}
+	b.WriteString("}")
+	setHeader(input, ftwhttp.ContentTypeHeader, "application/json")
+	setBody(input, b.String())
+	return true
+}
+
+func multipartBody(input *test.Input, attack string) bool {
+	values, ok := formWithAttack(input, attack)
+	if !ok {
+		return false
+	}
+	var parts []ftwhttp.Part
+	for _, kv := range values {
+		parts = append(parts, ftwhttp.Part{Name: kv[0], Content: kv[1]})
+	}
+	input.Data = nil
+	input.Multipart = &ftwhttp.MultipartBody{Parts: parts}
+	// the multipart body sets both
+	deleteHeader(input.Headers, ftwhttp.ContentTypeHeader)
+	deleteHeader(input.Headers, "Content-Length")
+	return true
+}
@@ -0,0 +1,139 @@
+package evasion
+
+import (
+	"encoding/base64"
+	"net/url"
+	"testing"
+
+	"github.com/fzipi/go-ftw/test"
+)
+
+func apply(t *testing.T, name string, tt test.Test) (test.Input, bool) {
+	techniques, err := Select([]string{name})
+	if err != nil {
+		t.Fatal(err)
+	}
+	variants := Variants(tt, techniques)
+	if len(variants) == 0 {
+		return test.Input{}, false
+	}
+	return variants[0].Test.Stages[1].Stage.Input, true
+}
+
+func body(t *testing.T, input test.Input) string {
+	data, err := base64.StdEncoding.DecodeString(input.DataBase64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestEncodings(t *testing.T) {
+	for name, expected := range map[string]string{
+		"double-url":    "%2527%2520or%25201%253D1",
+		"unicode":       "%u0027%u0020or%u00201%u003D1",
+		"overlong-utf8": "%C0%A7%C0%A0or%C0%A01%C0%BD1",
+	} {
+		input, ok := apply(t, name, attackTest("/?q=' or 1=1&x=1", "a=b&q=' or 1=1"))
+		if !ok {
+			t.Fatalf("Failed ! no variant using %s", name)
+		}
+		if input.GetURI() != "/?q="+expected+"&x=1" {
+			t.Errorf("Failed ! %s got uri %s", name, input.GetURI())
+		}
+		if b := body(t, input); b != "a=b&q="+expected || input.Headers["Content-Type"] != "application/x-www-form-urlencoded" {
+			t.Errorf("Failed ! %s got body %s with %v", name, b, input.Headers)
+		}
+	}
+
+	// characters outside the BMP are written as surrogate pairs
+	if encoded := unicodeEncode("é😀"); encoded != "%u00E9%uD83D%uDE00" {
+		t.Errorf("Failed ! unicode got %s", encoded)
+	}
+
+	// headers are not url-decoded
+	tt := attackTest("/", "")
+	tt.Stages[1].Stage.Input.Headers["Referer"] = "' or 1=1"
+	if _, ok := apply(t, "double-url", tt); ok {
+		t.Errorf("Failed ! encodings should not change headers")
+	}
+}
+
+func TestTextTechniques(t *testing.T) {
+	for name, expected := range map[string]string{
+		"mixed-case": "' Or 1=1",
+		"whitespace": "'\tor\t1 = 1",
+		"comments":   "'/**/or/**/1=1",
+	} {
+		tt := attackTest("/?q=%27+or+1%3D1", "")
+		tt.Stages[1].Stage.Input.Headers["Referer"] = "x' or 1=1"
+		input, ok := apply(t, name, tt)
+		if !ok {
+			t.Fatalf("Failed ! no variant using %s", name)
+		}
+		if input.Headers["Referer"] != "x"+expected {
+			t.Errorf("Failed ! %s got header %q", name, input.Headers["Referer"])
+		}
+		if q := queryValue(t, input.GetURI()); q != expected {
+			t.Errorf("Failed ! %s got uri %s", name, input.GetURI())
+		}
+	}
+
+	if _, ok := apply(t, "comments", test.Test{Attack: "alert", Stages: attackTest("/?q=alert", "").Stages}); ok {
+		t.Errorf("Failed ! comments need spaces in the attack")
+	}
+	if _, ok := mixedCase("1=1"); ok {
+		t.Errorf("Failed ! mixed case needs letters in the attack")
+	}
+}
+
+func TestParameterPollution(t *testing.T) {
+	input, ok := apply(t, "parameter-pollution", attackTest("/?x=1&q=%27+or+1%3D1", "q=' or 1=1"))
+	if !ok {
+		t.Fatalf("Failed ! no variant")
+	}
+	if input.GetURI() != "/?x=1&q=%27+or&q=+1%3D1" {
+		t.Errorf("Failed ! got uri %s", input.GetURI())
+	}
+	if b := body(t, input); b != "q=%27+or&q=+1%3D1" {
+		t.Errorf("Failed ! got body %s", b)
+	}
+
+	// not in a parameter
+	if _, ok = apply(t, "parameter-pollution", attackTest("/' or 1=1", "")); ok {
+		t.Errorf("Failed ! attacks outside parameters cannot be split")
+	}
+}
+
+func TestBodyTechniques(t *testing.T) {
+	input, ok := apply(t, "json-body", attackTest("", "a=b&q=' or 1=1"))
+	if !ok {
+		t.Fatalf("Failed ! no json variant")
+	}
+	if b := body(t, input); b != `{"a":"b","q":"' or 1=1"}` || input.Headers["Content-Type"] != "application/json" {
+		t.Errorf("Failed ! got body %s with %v", b, input.Headers)
+	}
+
+	input, ok = apply(t, "multipart-body", attackTest("", "a=b&q=%27+or+1%3D1"))
+	if !ok {
+		t.Fatalf("Failed ! no multipart variant")
+	}
+	if input.Data != nil || len(input.Multipart.Parts) != 2 || input.Multipart.Parts[1].Name != "q" || input.Multipart.Parts[1].Content != "' or 1=1" {
+		t.Errorf("Failed ! got %+v", input.Multipart)
+	}
+
+	// only forms are changed
+	tt := attackTest("", `{"q": "' or 1=1"}`)
+	tt.Stages[1].Stage.Input.Headers["Content-Type"] = "application/json"
+	if _, ok = apply(t, "multipart-body", tt); ok {
+		t.Errorf("Failed ! json bodies are not forms")
+	}
+}
+
+func queryValue(t *testing.T, uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u.Query().Get("q")
+}
@@ -0,0 +1,285 @@
+package ftwhttp
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+)
+
+// TCP flags used in the synthesized packets
+const (
+	tcpFIN byte = 0x01
+	tcpSYN byte = 0x02
+	tcpPSH byte = 0x08
+	tcpACK byte = 0x10
+)
+
+// captureSegmentSize is the maximum payload in each synthesized packet
+const captureSegmentSize = 1460
+
+// Capture writes a pcapng file with packets synthesized from the data sent and received by the client,
+// one TCP stream per connection. There is no need for capture privileges, and TLS connections are
+// written decrypted, as the client sees them.
+type Capture struct {
+	mu      sync.Mutex
+	w       *bufio.Writer
+	err     error
+	streams []*captureStream
+	// nextPort is used for connections without TCP addresses, like unix sockets
+	nextPort uint16
+}
+
+// captureStream tracks the addresses and sequence numbers of one connection
+type captureStream struct {
+	capture    *Capture
+	client     *net.TCPAddr
+	server     *net.TCPAddr
+	clientSeq  uint32
+	serverSeq  uint32
+	comment    string
+	clientDone bool
+	serverDone bool
+}
+
+// captureConn records everything read and written in the stream
+type captureConn struct {
+	net.Conn
+	stream *captureStream
+}
+
+// NewCapture writes the pcapng headers to w, and returns the Capture writing packets to it.
+// Call Close when done, so all streams are finished and the data is flushed.
+func NewCapture(w io.Writer) (*Capture, error) {
+	c := &Capture{w: bufio.NewWriter(w), nextPort: 49152}
+	c.write(pcapngSectionHeaderBlock())
+	c.write(pcapngInterfaceBlock())
+	return c, c.err
+}
+
+// Close finishes the streams still open, and flushes the file
+func (c *Capture) Close() error {
+	c.mu.Lock()
+	streams := c.streams
+	c.streams = nil
+	c.mu.Unlock()
+
+	for _, s := range streams {
+		s.finish(true)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err == nil {
+		c.err = c.w.Flush()
+	}
+	return c.err
+}
+
+// write writes the block, keeping the first error
+func (c *Capture) write(block []byte) {
+	if c.err == nil {
+		_, c.err = c.w.Write(block)
+	}
+}
+
+// wrap starts a stream for the connection, and returns the connection recording it.
+// Addresses are taken from transport, the connection below TLS.
+// The comment is added to the first packet.
+func (c *Capture) wrap(conn net.Conn, transport net.Conn, d Destination, comment string) net.Conn {
+	c.mu.Lock()
+	client, _ := transport.LocalAddr().(*net.TCPAddr)
+	server, _ := transport.RemoteAddr().(*net.TCPAddr)
+	if client == nil || server == nil || (client.IP.To4() == nil) != (server.IP.To4() == nil) {
+		// unix sockets, in-process handlers, or mixed address families
+		port := d.Port
+		if port == 0 {
+			port = 80
+		}
+		client = &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: int(c.nextPort)}
+		server = &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: port}
+		c.nextPort++
+		if c.nextPort == 0 {
+			c.nextPort = 49152
+		}
+	}
+	s := &captureStream{
+		capture:   c,
+		client:    client,
+		server:    server,
+		clientSeq: rand.Uint32(),
+		serverSeq: rand.Uint32(),
+		comment:   comment,
+	}
+	c.streams = append(c.streams, s)
+	c.mu.Unlock()
+
+	s.handshake()
+	return &captureConn{Conn: conn, stream: s}
+}
+
+// Read records the data received from the server, and its FIN on EOF
+func (c *captureConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	if n > 0 {
+		c.stream.data(false, b[:n])
+	}
+	if errors.Is(err, io.EOF) {
+		c.stream.finish(false)
+	}
+	return n, err
+}
+
+// Write records the data sent to the server
+func (c *captureConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	if n > 0 {
+		c.stream.data(true, b[:n])
+	}
+	return n, err
+}
+
+// Close records the FIN from the client
+func (c *captureConn) Close() error {
+	c.stream.finish(true)
+	return c.Conn.Close()
+}
+
+// handshake writes the SYN, SYN-ACK and ACK packets
+func (s *captureStream) handshake() {
+	s.packet(true, tcpSYN, nil)
+	s.clientSeq++
+	s.packet(false, tcpSYN|tcpACK, nil)
+	s.serverSeq++
+	s.packet(true, tcpACK, nil)
+}
+
+// data writes the payload in segments, from the client or from the server
+func (s *captureStream) data(fromClient bool, payload []byte) {
+	for len(payload) > 0 {
+		size := len(payload)
+		if size > captureSegmentSize {
+			size = captureSegmentSize
+		}
+		s.packet(fromClient, tcpPSH|tcpACK, payload[:size])
+		if fromClient {
+			s.clientSeq += uint32(size)
+		} else {
+			s.serverSeq += uint32(size)
+		}
+		payload = payload[size:]
+	}
+}
+
+// finish writes the FIN from one side, once
+func (s *captureStream) finish(fromClient bool) {
+	s.capture.mu.Lock()
+	done := (fromClient && s.clientDone) || (!fromClient && s.serverDone)
+	if fromClient {
+		s.clientDone = true
+	} else {
+		s.serverDone = true
+	}
+	s.capture.mu.Unlock()
+	if done {
+		return
+	}
+
+	s.packet(fromClient, tcpFIN|tcpACK, nil)
+	if fromClient {
+		s.clientSeq++
+	} else {
+		s.serverSeq++
+	}
+	s.packet(!fromClient, tcpACK, nil)
+}
+
+// packet writes one packet, using the current sequence numbers
+func (s *captureStream) packet(fromClient bool, flags byte, payload []byte) {
+	src, dst := s.server, s.client
+	seq, ack := s.serverSeq, s.clientSeq
+	if fromClient {
+		src, dst = s.client, s.server
+		seq, ack = s.clientSeq, s.serverSeq
+	}
+	if flags&tcpACK == 0 {
+		ack = 0
+	}
+
+	s.capture.mu.Lock()
+	defer s.capture.mu.Unlock()
+	s.capture.write(pcapngPacketBlock(time.Now(), ipPacket(src, dst, seq, ack, flags, payload), s.comment))
+	// only the first packet of the stream has the comment
+	s.comment = ""
+}
+
+// ipPacket builds an IPv4 or IPv6 packet with a TCP segment
+func ipPacket(src *net.TCPAddr, dst *net.TCPAddr, seq uint32, ack uint32, flags byte, payload []byte) []byte {
+	segment := make([]byte, 20+len(payload))
+	binary.BigEndian.PutUint16(segment[0:], uint16(src.Port))
+	binary.BigEndian.PutUint16(segment[2:], uint16(dst.Port))
+	binary.BigEndian.PutUint32(segment[4:], seq)
+	binary.BigEndian.PutUint32(segment[8:], ack)
+	segment[12] = 5 << 4
+	segment[13] = flags
+	binary.BigEndian.PutUint16(segment[14:], 65535)
+	copy(segment[20:], payload)
+
+	if src4, dst4 := src.IP.To4(), dst.IP.To4(); src4 != nil && dst4 != nil {
+		pseudo := make([]byte, 12)
+		copy(pseudo[0:], src4)
+		copy(pseudo[4:], dst4)
+		pseudo[9] = 6
+		binary.BigEndian.PutUint16(pseudo[10:], uint16(len(segment)))
+		binary.BigEndian.PutUint16(segment[16:], checksum(pseudo, segment))
+
+		header := make([]byte, 20)
+		header[0] = 0x45
+		binary.BigEndian.PutUint16(header[2:], uint16(len(header)+len(segment)))
+		// don't fragment
+		header[6] = 0x40
+		header[8] = 64
+		header[9] = 6
+		copy(header[12:], src4)
+		copy(header[16:], dst4)
+		binary.BigEndian.PutUint16(header[10:], checksum(header, nil))
+		return append(header, segment...)
+	}
+
+	pseudo := make([]byte, 40)
+	copy(pseudo[0:], src.IP.To16())
+	copy(pseudo[16:], dst.IP.To16())
+	binary.BigEndian.PutUint32(pseudo[32:], uint32(len(segment)))
+	pseudo[39] = 6
+	binary.BigEndian.PutUint16(segment[16:], checksum(pseudo, segment))
+
+	header := make([]byte, 40)
+	header[0] = 0x60
+	binary.BigEndian.PutUint16(header[4:], uint16(len(segment)))
+	header[6] = 6
+	header[7] = 64
+	copy(header[8:], src.IP.To16())
+	copy(header[24:], dst.IP.To16())
+	return append(header, segment...)
+}
+
+// checksum computes the internet checksum of both parts, see RFC 1071.
+// The first part must have an even length.
+func checksum(first []byte, second []byte) uint16 {
+	var sum uint32
+	data := append(append([]byte(nil), first...), second...)
+	for i := 0; i+1 < len(data); i += 2 {
+		sum += uint32(binary.BigEndian.Uint16(data[i:]))
+	}
+	if len(data)%2 == 1 {
+		sum += uint32(data[len(data)-1]) << 8
+	}
+	for sum > 0xffff {
+		sum = (sum >> 16) + (sum & 0xffff)
+	}
+	return ^uint16(sum)
+}
@@ -0,0 +1,174 @@
+package ftwhttp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// capturedPacket is an enhanced packet block read back from the capture
+type capturedPacket struct {
+	data    []byte
+	comment string
+}
+
+// readCapture checks the blocks in the pcapng file, and returns the packets
+func readCapture(t *testing.T, data []byte) []capturedPacket {
+	var packets []capturedPacket
+	first := true
+	for len(data) > 0 {
+		if len(data) < 12 {
+			t.Fatalf("Failed ! truncated block")
+		}
+		blockType := binary.LittleEndian.Uint32(data)
+		length := binary.LittleEndian.Uint32(data[4:])
+		if length%4 != 0 || int(length) > len(data) || binary.LittleEndian.Uint32(data[length-4:]) != length {
+			t.Fatalf("Failed ! bad block length %d", length)
+		}
+		if first && blockType != pcapngSectionHeader {
+			t.Fatalf("Failed ! the file must start with a section header")
+		}
+		first = false
+
+		if blockType == pcapngEnhancedPacket {
+			body := data[8 : length-4]
+			size := binary.LittleEndian.Uint32(body[12:])
+			p := capturedPacket{data: body[20 : 20+size]}
+			options := body[20+len(pad32(p.data)):]
+			for len(options) >= 4 {
+				code := binary.LittleEndian.Uint16(options)
+				l := binary.LittleEndian.Uint16(options[2:])
+				if code == pcapngOptionComment {
+					p.comment = string(options[4 : 4+l])
+				}
+				options = options[4+len(pad32(options[4:4+l])):]
+			}
+			packets = append(packets, p)
+		}
+		data = data[length:]
+	}
+	return packets
+}
+
+// tcpPayload returns the TCP flags and payload of an IPv4 packet, after checking the checksums
+func tcpPayload(t *testing.T, packet []byte) (uint16, uint16, byte, []byte) {
+	if packet[0] != 0x45 || checksum(packet[:20], nil) != 0 {
+		t.Fatalf("Failed ! bad IPv4 header %x", packet[:20])
+	}
+	segment := packet[20:]
+	pseudo := make([]byte, 12)
+	copy(pseudo, packet[12:20])
+	pseudo[9] = 6
+	binary.BigEndian.PutUint16(pseudo[10:], uint16(len(segment)))
+	if checksum(pseudo, segment) != 0 {
+		t.Fatalf("Failed ! bad TCP checksum")
+	}
+	return binary.BigEndian.Uint16(segment), binary.BigEndian.Uint16(segment[2:]), segment[13], segment[20:]
+}
+
+func TestCaptureHandlerTraffic(t *testing.T) {
+	var file bytes.Buffer
+	capture, err := NewCapture(&file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := NewClient()
+	c.Capture = capture
+	c.CaptureComment = "test 001, stage 1"
+	c.Dial = HandlerDialer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(strings.Repeat("a", 2000)))
+	}))
+	if err = c.NewConnection(Destination{DestAddr: "waf.example.com", Port: 8080, Protocol: "http", Network: TCPNetwork}); err != nil {
+		t.Fatal(err)
+	}
+	request := generateRequestForLocalTesting()
+	response, err := c.Do(*request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = capture.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	packets := readCapture(t, file.Bytes())
+	if len(packets) < 7 {
+		t.Fatalf("Failed ! got only %d packets", len(packets))
+	}
+	if packets[0].comment != "test 001, stage 1" || packets[1].comment != "" {
+		t.Errorf("Failed ! comment is %q", packets[0].comment)
+	}
+
+	var sent, received []byte
+	for i, p := range packets {
+		src, dst, flags, payload := tcpPayload(t, p.data)
+		switch {
+		case i == 0 && flags != tcpSYN, i == 1 && flags != tcpSYN|tcpACK:
+			t.Errorf("Failed ! packet %d has flags %x", i, flags)
+		case dst == 8080:
+			sent = append(sent, payload...)
+		case src == 8080:
+			received = append(received, payload...)
+		default:
+			t.Errorf("Failed ! packet %d goes from port %d to %d", i, src, dst)
+		}
+		if len(payload) > captureSegmentSize {
+			t.Errorf("Failed ! packet %d is too big", i)
+		}
+	}
+
+	data, _ := buildRequest(request)
+	if !bytes.Equal(sent, data) {
+		t.Errorf("Failed ! sent %q", sent)
+	}
+	if !bytes.Equal(received, response.RAW) {
+		t.Errorf("Failed ! received %q", received)
+	}
+	if _, _, flags, _ := tcpPayload(t, packets[len(packets)-2].data); flags&tcpFIN == 0 {
+		t.Errorf("Failed ! the stream should be finished")
+	}
+}
+
+func TestCaptureAddresses(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	var file bytes.Buffer
+	capture, _ := NewCapture(&file)
+	c := NewClient()
+	c.Capture = capture
+	if err = c.NewConnection(destinationFromListener(t, listener)); err != nil {
+		t.Fatal(err)
+	}
+	_ = capture.Close()
+
+	packets := readCapture(t, file.Bytes())
+	local := c.Transport.transport.LocalAddr().(*net.TCPAddr)
+	src, dst, _, _ := tcpPayload(t, packets[0].data)
+	if int(src) != local.Port || int(dst) != listener.Addr().(*net.TCPAddr).Port {
+		t.Errorf("Failed ! got ports %d -> %d", src, dst)
+	}
+}
+
+func TestCaptureIPv6Packet(t *testing.T) {
+	src := &net.TCPAddr{IP: net.ParseIP("::1"), Port: 50000}
+	dst := &net.TCPAddr{IP: net.ParseIP("::1"), Port: 80}
+	packet := ipPacket(src, dst, 1, 2, tcpPSH|tcpACK, []byte("GET"))
+
+	if packet[0]>>4 != 6 || binary.BigEndian.Uint16(packet[4:]) != 23 || packet[6] != 6 {
+		t.Errorf("Failed ! bad IPv6 header %x", packet[:40])
+	}
+	pseudo := make([]byte, 40)
+	copy(pseudo, packet[8:40])
+	binary.BigEndian.PutUint32(pseudo[32:], 23)
+	pseudo[39] = 6
+	if checksum(pseudo, packet[40:]) != 0 {
+		t.Errorf("Failed ! bad TCP checksum")
+	}
+}
@@ -0,0 +1,67 @@
+package ftwhttp
+
+import (
+	"bytes"
+	"fmt"
+)
+
+const (
+	// TransferEncodingHeader gives you the string for transfer encoding
+	TransferEncodingHeader string = "Transfer-Encoding"
+)
+
+// Chunk is one chunk of a chunked body
+type Chunk struct {
+	// Data is the content of the chunk
+	Data string `yaml:"data"`
+	// Size, when set, is sent instead of the real size of the data, so you can send wrong sizes.
+	// It is sent as-is, so it must be in hex.
+	Size *string `yaml:"size,omitempty"`
+	// Extensions are sent after the size, e.g. `name=value` is sent as `;name=value`
+	Extensions string `yaml:"extensions,omitempty"`
+}
+
+// ChunkedBody describes a body sent using `Transfer-Encoding: chunked`
+type ChunkedBody struct {
+	Chunks []Chunk `yaml:"chunks"`
+	// Trailers are sent after the last chunk
+	Trailers Header `yaml:"trailers,omitempty"`
+	// NoLastChunk leaves out the last (empty) chunk and the trailers, so the body never ends
+	NoLastChunk bool `yaml:"no_last_chunk,omitempty"`
+}
+
+// Bytes serializes the chunked body, as sent in the wire
+func (c *ChunkedBody) Bytes() []byte {
+	var b bytes.Buffer
+
+	for _, chunk := range c.Chunks {
+		writeChunkHeader(&b, chunk)
+		b.WriteString(chunk.Data)
+		b.WriteString("\r\n")
+	}
+
+	if c.NoLastChunk {
+		return b.Bytes()
+	}
+
+	b.WriteString("0\r\n")
+	// errors writing to a bytes.Buffer are always nil
+	_ = c.Trailers.WriteBytes(&b)
+	b.WriteString("\r\n")
+
+	return b.Bytes()
+}
+
+// writeChunkHeader writes the size line for the chunk
+func writeChunkHeader(b *bytes.Buffer, chunk Chunk) {
+	if chunk.Size != nil {
+		b.WriteString(*chunk.Size)
+	} else {
+		fmt.Fprintf(b, "%x", len(chunk.Data))
+	}
+	if chunk.Extensions != "" {
+		b.WriteString(";")
+		b.WriteString(chunk.Extensions)
+	}
+	b.WriteString("\r\n")
+}
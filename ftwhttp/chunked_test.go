@@ -0,0 +1,71 @@
+package ftwhttp
+
+import (
+	"bytes"
+	"testing"
+)
+
+func stringPointer(s string) *string {
+	return &s
+}
+
+var chunkedTests = []struct {
+	body     ChunkedBody
+	expected string
+}{
+	{ChunkedBody{}, "0\r\n\r\n"},
+	{ChunkedBody{Chunks: []Chunk{{Data: "hello"}, {Data: " world, with more"}}}, "5\r\nhello\r\n11\r\n world, with more\r\n0\r\n\r\n"},
+	{ChunkedBody{Chunks: []Chunk{{Data: "hello", Size: stringPointer("ff")}}}, "ff\r\nhello\r\n0\r\n\r\n"},
+	{ChunkedBody{Chunks: []Chunk{{Data: "hello", Extensions: "a=b"}}}, "5;a=b\r\nhello\r\n0\r\n\r\n"},
+	{ChunkedBody{Chunks: []Chunk{{Data: "hello"}}, Trailers: Header{"X-Trailer": "value"}}, "5\r\nhello\r\n0\r\nX-Trailer: value\r\n\r\n"},
+	{ChunkedBody{Chunks: []Chunk{{Data: "hello"}}, NoLastChunk: true}, "5\r\nhello\r\n"},
+}
+
+func TestChunkedBodyBytes(t *testing.T) {
+	for _, c := range chunkedTests {
+		c := c
+		if got := string(c.body.Bytes()); got != c.expected {
+			t.Errorf("Failed ! got %q, expected %q", got, c.expected)
+		}
+	}
+}
+
+func TestChunkedRequest(t *testing.T) {
+	rl := &RequestLine{
+		Method:  "POST",
+		URI:     "/",
+		Version: "HTTP/1.1",
+	}
+	req := NewRequest(rl, Header{"Host": "localhost"}, nil, true)
+	req.SetChunkedBody(&ChunkedBody{Chunks: []Chunk{{Data: "a=1"}}})
+
+	data, err := buildRequest(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "POST / HTTP/1.1\r\nConnection: close\r\nHost: localhost\r\nTransfer-Encoding: chunked\r\n\r\n3\r\na=1\r\n0\r\n\r\n"
+	if !bytes.Equal(data, []byte(expected)) {
+		t.Errorf("Failed ! got %q", data)
+	}
+}
+
+func TestChunkedRequestWithoutAutocomplete(t *testing.T) {
+	rl := &RequestLine{
+		Method:  "POST",
+		URI:     "/",
+		Version: "HTTP/1.1",
+	}
+	req := NewRequest(rl, Header{"Host": "localhost", "Transfer-Encoding": "chunked, identity"}, nil, false)
+	req.SetChunkedBody(&ChunkedBody{Chunks: []Chunk{{Data: "a=1"}}})
+
+	data, err := buildRequest(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "POST / HTTP/1.1\r\nHost: localhost\r\nTransfer-Encoding: chunked, identity\r\n\r\n3\r\na=1\r\n0\r\n\r\n"
+	if !bytes.Equal(data, []byte(expected)) {
+		t.Errorf("Failed ! got %q", data)
+	}
+}
@@ -2,9 +2,9 @@ package ftwhttp
 
 import (
 	"crypto/tls"
-	"fmt"
 	"net"
 	"net/http/cookiejar"
+	"net/url"
 	"strings"
 	"time"
 
@@ -23,35 +23,103 @@ func NewClient() *Client {
 		Jar: jar,
 		// default Timeout
 		Timeout: 3 * time.Second,
+		// use the proxy from the environment, if any
+		Proxy: ProxyFromEnvironment,
 	}
 	return c
 }
 
 // NewConnection creates a new Connection based on a Destination
 func (c *Client) NewConnection(d Destination) error {
+	duration := NewRoundTripTime()
+	netConn, transport, err := c.dial(d, duration)
+
+	if err == nil {
+		if c.Capture != nil {
+			if c.Transport != nil && c.Transport.connection != nil {
+				// the previous connection is not used anymore
+				_ = c.Transport.connection.Close()
+			}
+			netConn = c.Capture.wrap(netConn, transport, d, c.CaptureComment)
+		}
+		c.Transport = &Connection{
+			connection: netConn,
+			transport:  transport,
+			protocol:   d.Protocol,
+			duration:   duration,
+		}
+	}
+
+	return classifyDialError(err)
+}
+
+// dial connects to the destination, directly or through the proxy, and
+// performs the TLS handshake when the protocol is https. The time spent is stored in duration.
+// It returns the connection to use, and the underlying transport connection.
+func (c *Client) dial(d Destination, duration *RoundTripTime) (net.Conn, net.Conn, error) {
 	var err error
 	var netConn net.Conn
 
-	hostPort := fmt.Sprintf("%s:%d", d.DestAddr, d.Port)
+	begin := time.Now()
+	if d.Network == UnixNetwork {
+		// proxies make no sense for local sockets
+		netConn, err = c.dialNetwork(UnixNetwork, d.DestAddr)
+		duration.connect = time.Since(begin)
+		if err != nil {
+			return nil, nil, err
+		}
+		return netConn, netConn, nil
+	}
+
+	hostPort := c.Resolve.Address(d.DestAddr, d.Port)
+
+	var proxyURL *url.URL
+	if c.Proxy != nil {
+		proxyURL, err = c.Proxy(d)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
 
 	// Fatal error: dial tcp 127.0.0.1:80: connect: connection refused
 	// strings.HasSuffix(err.String(), "connection refused") {
-	if strings.ToLower(d.Protocol) == "https" {
-		// Commenting InsecureSkipVerify: true.
-		netConn, err = tls.DialWithDialer(&net.Dialer{Timeout: c.Timeout}, "tcp", hostPort, &tls.Config{MinVersion: tls.VersionTLS12})
+	if proxyURL != nil {
+		netConn, err = dialProxy(c.dialNetwork, proxyURL, hostPort, c.Timeout)
 	} else {
-		netConn, err = net.DialTimeout("tcp", hostPort, c.Timeout)
+		netConn, err = c.dialNetwork(TCPNetwork, hostPort)
+	}
+	duration.connect = time.Since(begin)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	if err == nil {
-		c.Transport = &Connection{
-			connection: netConn,
-			protocol:   d.Protocol,
-			duration:   NewRoundTripTime(),
+	if strings.ToLower(d.Protocol) == "https" {
+		// Commenting InsecureSkipVerify: true.
+		tlsConn := tls.Client(netConn, &tls.Config{ServerName: d.serverName(), MinVersion: tls.VersionTLS12, NextProtos: d.NextProtos})
+		begin = time.Now()
+		if err = tlsConn.SetDeadline(time.Now().Add(c.Timeout)); err == nil {
+			err = tlsConn.Handshake()
 		}
+		duration.tlsHandshake = time.Since(begin)
+		if err == nil {
+			err = tlsConn.SetDeadline(time.Time{})
+		}
+		if err != nil {
+			netConn.Close()
+			return nil, nil, err
+		}
+		return tlsConn, netConn, nil
 	}
 
-	return err
+	return netConn, netConn, nil
+}
+
+// dialNetwork opens the connection using the Dial hook, if set, or the net package
+func (c *Client) dialNetwork(network string, address string) (net.Conn, error) {
+	if c.Dial != nil {
+		return c.Dial(network, address)
+	}
+	return net.DialTimeout(network, address, c.Timeout)
 }
 
 // Do performs the http request roundtrip
@@ -73,6 +141,25 @@ func (c *Client) Do(req Request) (*Response, error) {
 	return response, err
 }
 
+// DoWebSocket performs the websocket handshake, and sends the frames if the server switches protocols.
+// The response is the handshake response, with what happened afterwards in its WebSocket field.
+func (c *Client) DoWebSocket(req Request, ws *WebSocket) (*Response, error) {
+	response, err := c.Transport.WebSocket(&req, ws)
+	if err != nil {
+		log.Debug().Msgf("ftw/http: error in websocket: %s\n", err.Error())
+	}
+	return response, err
+}
+
+// DoGRPC makes the gRPC call over HTTP/2. Use a Destination with NextProtos set to GRPCProto for TLS connections.
+func (c *Client) DoGRPC(authority string, call *GRPC) (*Response, error) {
+	response, err := c.Transport.GRPC(authority, call)
+	if err != nil {
+		log.Debug().Msgf("ftw/http: error in grpc call: %s\n", err.Error())
+	}
+	return response, err
+}
+
 // GetRoundTripTime returns the time taken from the initial send till receiving the full response
 func (c *Client) GetRoundTripTime() *RoundTripTime {
 	return c.Transport.GetTrackedTime()
@@ -1,6 +1,13 @@
 package ftwhttp
 
-import "testing"
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
 
 func TestNewClient(t *testing.T) {
 	c := NewClient()
@@ -151,3 +158,90 @@ Some-file-test-here
 	}
 
 }
+
+func TestConnectUnixSocket(t *testing.T) {
+	dir, err := os.MkdirTemp("", "go-ftw-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	socket := filepath.Join(dir, "waf.sock")
+	listener, err := net.Listen(UnixNetwork, socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("Hello, unix"))
+	})}
+	go func() { _ = server.Serve(listener) }()
+	defer server.Close()
+
+	d, err := DestinationFromString("unix://" + socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := NewClient()
+	if err = c.NewConnection(*d); err != nil {
+		t.Fatalf("Error connecting to unix socket: %s", err.Error())
+	}
+
+	resp, err := c.Do(*generateRequestForLocalTesting())
+	if err != nil {
+		t.Fatalf("Error sending request: %s", err.Error())
+	}
+
+	if body := resp.GetBodyAsString(); body != "Hello, unix" {
+		t.Errorf("Unexpected body %q", body)
+	}
+}
+
+func TestConnectCustomDialer(t *testing.T) {
+	var dialedNetwork, dialedAddress string
+
+	c := NewClient()
+	c.Dial = func(network string, address string) (net.Conn, error) {
+		dialedNetwork, dialedAddress = network, address
+		client, server := net.Pipe()
+		go func() {
+			defer server.Close()
+			req, err := http.ReadRequest(bufio.NewReader(server))
+			if err != nil {
+				return
+			}
+			_, _ = server.Write([]byte("HTTP/1.1 200 OK\r\nContent-Length: 8\r\nConnection: close\r\n\r\n" + req.Method + " pipe"))
+		}()
+		return client, nil
+	}
+
+	err := c.NewConnection(Destination{DestAddr: "waf.example.com", Port: 8080, Protocol: "http", Network: TCPNetwork})
+	if err != nil {
+		t.Fatalf("Error connecting with custom dialer: %s", err.Error())
+	}
+
+	if dialedNetwork != TCPNetwork || dialedAddress != "waf.example.com:8080" {
+		t.Errorf("Custom dialer called with %s %s", dialedNetwork, dialedAddress)
+	}
+
+	resp, err := c.Do(*generateRequestForLocalTesting())
+	if err != nil {
+		t.Fatalf("Error sending request: %s", err.Error())
+	}
+
+	if body := resp.GetBodyAsString(); body != "GET pipe" {
+		t.Errorf("Unexpected body %q", body)
+	}
+}
+
+func generateRequestForLocalTesting() *Request {
+	rl := &RequestLine{
+		Method:  "GET",
+		URI:     "/",
+		Version: "HTTP/1.1",
+	}
+
+	h := Header{"Accept": "*/*", "User-Agent": "go-ftw test agent", "Host": "localhost"}
+
+	return NewRequest(rl, h, nil, true)
+}
@@ -0,0 +1,94 @@
+package ftwhttp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+const (
+	// ContentEncodingHeader gives you the string for content encoding
+	ContentEncodingHeader string = "Content-Encoding"
+)
+
+// Supported content encodings
+const (
+	GzipEncoding     = "gzip"
+	DeflateEncoding  = "deflate"
+	BrotliEncoding   = "br"
+	IdentityEncoding = "identity"
+)
+
+// ValidateEncoding returns an error if the content encoding is not supported
+func ValidateEncoding(encoding string) error {
+	switch encoding {
+	case GzipEncoding, DeflateEncoding, BrotliEncoding, IdentityEncoding:
+		return nil
+	default:
+		return fmt.Errorf("ftw/http: unsupported content encoding %q, use one of gzip, deflate or br", encoding)
+	}
+}
+
+// Compress compresses data using the content encoding passed.
+// As in HTTP, `deflate` means the zlib format.
+func Compress(encoding string, data []byte) ([]byte, error) {
+	var b bytes.Buffer
+	var w io.WriteCloser
+
+	switch encoding {
+	case GzipEncoding:
+		w = gzip.NewWriter(&b)
+	case DeflateEncoding:
+		w = zlib.NewWriter(&b)
+	case BrotliEncoding:
+		w = brotli.NewWriter(&b)
+	case IdentityEncoding:
+		return data, nil
+	default:
+		return nil, ValidateEncoding(encoding)
+	}
+
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// Decompress decodes data using the value of a Content-Encoding header. When there are many
+// encodings, they are decoded in reverse order, as they were applied in order.
+func Decompress(contentEncoding string, data []byte) ([]byte, error) {
+	encodings := strings.Split(contentEncoding, ",")
+	for i := len(encodings) - 1; i >= 0; i-- {
+		encoding := strings.ToLower(strings.TrimSpace(encodings[i]))
+		var r io.Reader
+		var err error
+
+		switch encoding {
+		case GzipEncoding, "x-gzip":
+			r, err = gzip.NewReader(bytes.NewReader(data))
+		case DeflateEncoding:
+			r, err = zlib.NewReader(bytes.NewReader(data))
+		case BrotliEncoding:
+			r = brotli.NewReader(bytes.NewReader(data))
+		case IdentityEncoding, "":
+			continue
+		default:
+			return nil, ValidateEncoding(encoding)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if data, err = io.ReadAll(r); err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
@@ -0,0 +1,70 @@
+package ftwhttp
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCompressRoundTrip(t *testing.T) {
+	data := bytes.Repeat([]byte("<script>alert(1)</script>\x00\xff"), 20)
+	for _, encoding := range []string{GzipEncoding, DeflateEncoding, BrotliEncoding, IdentityEncoding} {
+		compressed, err := Compress(encoding, data)
+		if err != nil {
+			t.Fatalf("Failed ! %s: %s", encoding, err.Error())
+		}
+		if encoding != IdentityEncoding && len(compressed) >= len(data) {
+			t.Errorf("Failed ! %s: data was not compressed", encoding)
+		}
+		decompressed, err := Decompress(encoding, compressed)
+		if err != nil || !bytes.Equal(decompressed, data) {
+			t.Errorf("Failed ! %s: got %q, %v", encoding, decompressed, err)
+		}
+	}
+}
+
+func TestDecompressManyEncodings(t *testing.T) {
+	data := []byte("hello")
+	gzipped, _ := Compress(GzipEncoding, data)
+	both, _ := Compress(BrotliEncoding, gzipped)
+
+	decompressed, err := Decompress("gzip, br", both)
+	if err != nil || !bytes.Equal(decompressed, data) {
+		t.Errorf("Failed ! got %q, %v", decompressed, err)
+	}
+}
+
+func TestUnsupportedEncoding(t *testing.T) {
+	if _, err := Compress("zstd", []byte("a")); err == nil {
+		t.Errorf("Failed !")
+	}
+	if _, err := Decompress("zstd", []byte("a")); err == nil {
+		t.Errorf("Failed !")
+	}
+	if err := ValidateEncoding("gzip"); err != nil {
+		t.Errorf("Failed !")
+	}
+}
+
+func TestCompressedRequest(t *testing.T) {
+	rl := &RequestLine{
+		Method:  "POST",
+		URI:     "/",
+		Version: "HTTP/1.1",
+	}
+	req := NewRequest(rl, Header{"Host": "localhost", "Content-Type": "text/plain"}, []byte("hello"), true)
+	req.SetCompression(GzipEncoding)
+
+	data, err := buildRequest(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.Headers().Get(ContentEncodingHeader) != GzipEncoding {
+		t.Errorf("Failed ! no Content-Encoding header")
+	}
+
+	body := data[bytes.Index(data, []byte("\r\n\r\n"))+4:]
+	decompressed, err := Decompress(GzipEncoding, body)
+	if err != nil || string(decompressed) != "hello" {
+		t.Errorf("Failed ! got %q, %v", decompressed, err)
+	}
+}
@@ -10,29 +10,51 @@ import (
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/rs/zerolog/log"
 )
 
 // DestinationFromString create a Destination from String
+// Unix domain sockets use the `unix` scheme, like in `unix:///run/waf.sock`, and talk plain http.
 func DestinationFromString(urlString string) (*Destination, error) {
 	u, err := url.Parse(urlString)
 	if err != nil {
 		return nil, err
 	}
-	host, port, _ := net.SplitHostPort(u.Host)
-	p, _ := strconv.Atoi(port)
+	if u.Scheme == UnixNetwork {
+		d := &Destination{
+			DestAddr: u.Host + u.Path,
+			Protocol: "http",
+			Network:  UnixNetwork,
+		}
+		return d, nil
+	}
+	// Hostname and Port handle IPv6 literals like `[::1]:8080`
+	p, _ := strconv.Atoi(u.Port())
+	if p == 0 {
+		p = defaultPort(u.Scheme)
+	}
 
 	d := &Destination{
 		Port:     p,
-		DestAddr: host,
+		DestAddr: u.Hostname(),
 		Protocol: u.Scheme,
+		Network:  TCPNetwork,
 	}
 
 	return d, nil
 }
 
+// defaultPort returns the port used when the URL has none
+func defaultPort(scheme string) int {
+	if strings.ToLower(scheme) == "https" {
+		return 443
+	}
+	return 80
+}
+
 // StartTrackingTime initializes timer
 func (c *Connection) StartTrackingTime() {
 	c.duration.StartTracking()
@@ -48,7 +70,8 @@ func (c *Connection) GetTrackedTime() *RoundTripTime {
 	return c.duration
 }
 
-func (c *Connection) send(data []byte) (int, error) {
+// send writes data to the connection, using the transmission options when not nil
+func (c *Connection) send(data []byte, transmission *Transmission) (int, error) {
 	var err error
 	var sent int
 
@@ -56,7 +79,13 @@ func (c *Connection) send(data []byte) (int, error) {
 	// Store times for searching in logs, if necessary
 
 	if c.connection != nil {
-		sent, err = c.connection.Write(data)
+		begin := time.Now()
+		if transmission != nil {
+			sent, err = transmission.transmit(c.connection, c.transport, data)
+		} else {
+			sent, err = c.connection.Write(data)
+		}
+		c.duration.write = time.Since(begin)
 	} else {
 		err = errors.New("ftw/http/send: not connected to server")
 	}
@@ -75,35 +104,76 @@ func (c *Connection) receive() ([]byte, error) {
 	timeoutDuration := 1000 * time.Millisecond
 
 	// We assume the response body can be handled in memory without problems
-	// That's why we use io.ReadAll
 	if err = c.connection.SetReadDeadline(time.Now().Add(timeoutDuration)); err == nil {
-		buf, err = io.ReadAll(c.connection)
+		buf, err = c.readAll()
 	}
 
-	if neterr, ok := err.(net.Error); ok && !neterr.Timeout() {
+	var neterr net.Error
+	switch {
+	case err == nil:
+	case errors.As(err, &neterr) && neterr.Timeout():
+		if len(buf) == 0 {
+			// nothing was received before the deadline
+			err = &ClassifiedError{Class: ReadTimeout, Err: err}
+		} else {
+			// the response was read until the deadline
+			err = nil
+		}
+	default:
 		log.Error().Msgf("ftw/http: %s\n", err.Error())
-	} else {
-		err = nil
+		err = classifyTransportError(err)
 	}
 	log.Trace().Msgf("ftw/http: received data - %q", buf)
 
 	return buf, err
 }
 
-// Request will use all the inputs and send a raw http request to the destination
+// readAll reads until EOF or error, like io.ReadAll, tracking the time the first
+// and the last bytes were received
+func (c *Connection) readAll() ([]byte, error) {
+	var buf bytes.Buffer
+	var first, last time.Time
+	chunk := make([]byte, 4096)
+	begin := time.Now()
+
+	for {
+		n, err := c.connection.Read(chunk)
+		if n > 0 {
+			last = time.Now()
+			if first.IsZero() {
+				first = last
+			}
+			buf.Write(chunk[:n])
+		}
+		if err != nil {
+			if !first.IsZero() {
+				c.duration.firstByte = first.Sub(begin)
+				c.duration.read = last.Sub(first)
+			}
+			if err == io.EOF {
+				err = nil
+			}
+			return buf.Bytes(), err
+		}
+	}
+}
+
+// Request will use all the inputs and send a raw http request to the destination.
+// Errors building the request are returned as a BuildError.
 func (c *Connection) Request(request *Request) error {
 	// Build request first, then connect and send, so timers are accurate
 	data, err := buildRequest(request)
 	if err != nil {
-		log.Fatal().Msgf("ftw/http: fatal error building request: %s", err.Error())
+		return &BuildError{Err: err}
 	}
 
 	log.Debug().Msgf("ftw/http: sending data:\n%s\n", data)
 
-	_, err = c.send(data)
+	_, err = c.send(data, request.transmission)
 
 	if err != nil {
 		log.Error().Msgf("ftw/http: error writing data: %s", err.Error())
+		err = classifyTransportError(err)
 	}
 
 	return err
@@ -123,7 +193,7 @@ func (c *Connection) Response() (*Response, error) {
 
 	httpResponse, err := http.ReadResponse(&reader, nil)
 	if err != nil {
-		return nil, err
+		return nil, classifyResponseError(err)
 	}
 	response := Response{
 		RAW:    data,
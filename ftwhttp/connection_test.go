@@ -1,10 +1,22 @@
 package ftwhttp
 
-import "testing"
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
 
 func TestDestinationFromString(t *testing.T) {
+	d, err := DestinationFromString("https://example.com:8443")
+	if err != nil {
+		t.Fatalf("Error parsing destination: %s", err.Error())
+	}
 
+	if d.DestAddr != "example.com" || d.Port != 8443 || d.Protocol != "https" || d.Network != TCPNetwork {
+		t.Errorf("Wrong destination: %+v", d)
+	}
 }
+
 func TestMultipleRequestTypes(t *testing.T) {
 	var req *Request
 
@@ -23,3 +35,45 @@ func TestMultipleRequestTypes(t *testing.T) {
 		t.Error("Set Autocomplete headers error ")
 	}
 }
+
+func TestDestinationFromStringUnix(t *testing.T) {
+	d, err := DestinationFromString("unix:///run/waf.sock")
+	if err != nil {
+		t.Fatalf("Error parsing destination: %s", err.Error())
+	}
+
+	if d.DestAddr != "/run/waf.sock" || d.Protocol != "http" || d.Network != UnixNetwork {
+		t.Errorf("Wrong unix destination: %+v", d)
+	}
+}
+
+func TestDestinationFromStringIPv6(t *testing.T) {
+	d, err := DestinationFromString("http://[::1]:8080")
+	if err != nil {
+		t.Fatalf("Error parsing destination: %s", err.Error())
+	}
+	if d.DestAddr != "::1" || d.Port != 8080 {
+		t.Errorf("Wrong IPv6 destination: %+v", d)
+	}
+
+	d, _ = DestinationFromString("https://[2001:db8::1]")
+	if d.DestAddr != "2001:db8::1" || d.Port != 443 {
+		t.Errorf("Wrong IPv6 destination without port: %+v", d)
+	}
+}
+
+func TestRequestBuildError(t *testing.T) {
+	rl := &RequestLine{
+		Method:  "POST",
+		URI:     "/",
+		Version: "HTTP/1.1",
+	}
+	req := NewRequest(rl, Header{"Host": "localhost"}, nil, true)
+	req.SetMultipartBody(&MultipartBody{Parts: []Part{{Name: "f", File: filepath.Join(t.TempDir(), "missing.txt")}}})
+
+	var c Connection
+	var buildErr *BuildError
+	if err := c.Request(req); !errors.As(err, &buildErr) {
+		t.Errorf("Failed ! expected a build error, got %v", err)
+	}
+}
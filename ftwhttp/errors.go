@@ -0,0 +1,150 @@
+package ftwhttp
+
+import (
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"syscall"
+)
+
+// ErrorClass classifies the errors found while sending requests or receiving responses,
+// so tests can expect a particular kind of failure
+type ErrorClass string
+
+const (
+	// ConnectionRefused means the destination refused the connection
+	ConnectionRefused ErrorClass = "connection_refused"
+	// ConnectionReset means the connection was reset by the peer, or the pipe was broken while writing
+	ConnectionReset ErrorClass = "connection_reset"
+	// ConnectTimeout means the connection could not be established in time
+	ConnectTimeout ErrorClass = "connect_timeout"
+	// ReadTimeout means no response was received in time
+	ReadTimeout ErrorClass = "read_timeout"
+	// DNSFailure means the destination name could not be resolved
+	DNSFailure ErrorClass = "dns_failure"
+	// TLSAlert means the TLS handshake failed, or the peer sent a TLS alert
+	TLSAlert ErrorClass = "tls_alert"
+	// MalformedResponse means a response was received, but it is not valid HTTP
+	MalformedResponse ErrorClass = "malformed_response"
+	// PrematureEOF means the connection was closed before receiving a complete response
+	PrematureEOF ErrorClass = "premature_eof"
+	// UnknownError is used for any other error
+	UnknownError ErrorClass = "unknown"
+)
+
+// ErrorClasses has all the known error classes
+var ErrorClasses = []ErrorClass{
+	ConnectionRefused,
+	ConnectionReset,
+	ConnectTimeout,
+	ReadTimeout,
+	DNSFailure,
+	TLSAlert,
+	MalformedResponse,
+	PrematureEOF,
+	UnknownError,
+}
+
+// ClassifiedError is an error with its ErrorClass
+type ClassifiedError struct {
+	Class ErrorClass
+	Err   error
+}
+
+// Error returns the text of the original error
+func (e *ClassifiedError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the original error
+func (e *ClassifiedError) Unwrap() error {
+	return e.Err
+}
+
+// BuildError is returned when the request cannot be built, like when a file in the body cannot be read.
+// The request was not sent, so it is not classified.
+type BuildError struct {
+	Err error
+}
+
+// Error returns the text of the original error
+func (e *BuildError) Error() string {
+	return "ftw/http: error building request: " + e.Err.Error()
+}
+
+// Unwrap returns the original error
+func (e *BuildError) Unwrap() error {
+	return e.Err
+}
+
+// ClassifyError returns the ErrorClass for the error passed. Errors returned by the Client are
+// already classified; other errors are classified by looking at their type.
+func ClassifyError(err error) ErrorClass {
+	var classified *ClassifiedError
+	if errors.As(err, &classified) {
+		return classified.Class
+	}
+	return classifyError(err)
+}
+
+// classifyError looks at the error type and returns its class.
+// Timeouts are classified as ReadTimeout: use classifyDialError when connecting.
+func classifyError(err error) ErrorClass {
+	var dnsErr *net.DNSError
+	var recordErr tls.RecordHeaderError
+	var netErr net.Error
+
+	switch {
+	case err == nil:
+		return ""
+	case errors.As(err, &dnsErr):
+		return DNSFailure
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return ConnectionRefused
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.EPIPE), errors.Is(err, syscall.ECONNABORTED):
+		return ConnectionReset
+	case errors.As(err, &recordErr), strings.Contains(err.Error(), "tls: "):
+		return TLSAlert
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return PrematureEOF
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return ReadTimeout
+	default:
+		return UnknownError
+	}
+}
+
+// classifyDialError classifies errors found while connecting
+func classifyDialError(err error) error {
+	if err == nil {
+		return nil
+	}
+	class := classifyError(err)
+	if class == ReadTimeout {
+		class = ConnectTimeout
+	}
+	return &ClassifiedError{Class: class, Err: err}
+}
+
+// classifyResponseError classifies errors found while parsing the response
+func classifyResponseError(err error) error {
+	if err == nil {
+		return nil
+	}
+	class := classifyError(err)
+	if class == UnknownError {
+		// the stdlib doesn't have types for parsing errors
+		class = MalformedResponse
+	}
+	return &ClassifiedError{Class: class, Err: err}
+}
+
+// classifyTransportError classifies errors found while writing or reading
+func classifyTransportError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &ClassifiedError{Class: classifyError(err), Err: err}
+}
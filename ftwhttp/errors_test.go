@@ -0,0 +1,143 @@
+package ftwhttp
+
+import (
+	"errors"
+	"io"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+)
+
+var classifyErrorTests = []struct {
+	err      error
+	expected ErrorClass
+}{
+	{&net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}, ConnectionRefused},
+	{&net.OpError{Op: "read", Err: syscall.ECONNRESET}, ConnectionReset},
+	{&net.OpError{Op: "write", Err: syscall.EPIPE}, ConnectionReset},
+	{&net.DNSError{Err: "no such host", Name: "wafx.example.com"}, DNSFailure},
+	{errors.New("remote error: tls: handshake failure"), TLSAlert},
+	{io.ErrUnexpectedEOF, PrematureEOF},
+	{errors.New("a"), UnknownError},
+	{&ClassifiedError{Class: MalformedResponse, Err: errors.New("a")}, MalformedResponse},
+}
+
+func TestClassifyError(t *testing.T) {
+	for _, e := range classifyErrorTests {
+		if class := ClassifyError(e.err); class != e.expected {
+			t.Errorf("Failed ! %v classified as %s, expected %s", e.err, class, e.expected)
+		}
+	}
+}
+
+// newActionServer accepts one connection, reads the request and then calls action with the connection
+func newActionServer(t *testing.T, action func(conn *net.TCPConn)) net.Listener {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		buf := make([]byte, 4096)
+		_, _ = conn.Read(buf)
+		action(conn.(*net.TCPConn))
+	}()
+
+	return listener
+}
+
+func requestErrorClass(t *testing.T, action func(conn *net.TCPConn)) ErrorClass {
+	server := newActionServer(t, action)
+	defer server.Close()
+
+	c := NewClient()
+	if err := c.NewConnection(destinationFromListener(t, server)); err != nil {
+		t.Fatalf("Error connecting: %s", err.Error())
+	}
+
+	_, err := c.Do(*generateRequestForLocalTesting())
+	if err == nil {
+		return ""
+	}
+	return ClassifyError(err)
+}
+
+func TestResponseErrorClasses(t *testing.T) {
+	actions := []struct {
+		name     string
+		action   func(conn *net.TCPConn)
+		expected ErrorClass
+	}{
+		{"reset", func(conn *net.TCPConn) {
+			_ = conn.SetLinger(0)
+			conn.Close()
+		}, ConnectionReset},
+		{"close", func(conn *net.TCPConn) {
+			conn.Close()
+		}, PrematureEOF},
+		{"partial", func(conn *net.TCPConn) {
+			_, _ = conn.Write([]byte("HTTP/1.1 200 OK\r\nContent-Length: 10\r\n"))
+			conn.Close()
+		}, PrematureEOF},
+		{"malformed", func(conn *net.TCPConn) {
+			_, _ = conn.Write([]byte("this is not http\r\n\r\n"))
+			conn.Close()
+		}, MalformedResponse},
+		{"timeout", func(conn *net.TCPConn) {
+			time.Sleep(1500 * time.Millisecond)
+			conn.Close()
+		}, ReadTimeout},
+	}
+
+	for _, a := range actions {
+		if class := requestErrorClass(t, a.action); class != a.expected {
+			t.Errorf("Failed ! %s: got error class %q, expected %s", a.name, class, a.expected)
+		}
+	}
+}
+
+func TestConnectionRefusedClass(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := destinationFromListener(t, listener)
+	listener.Close()
+
+	c := NewClient()
+	err = c.NewConnection(d)
+	if ClassifyError(err) != ConnectionRefused {
+		t.Errorf("Failed ! got %v", err)
+	}
+}
+
+// failingConn is a connection whose reads fail with err
+type failingConn struct {
+	net.Conn
+	err error
+}
+
+func (c *failingConn) SetReadDeadline(time.Time) error { return nil }
+
+func (c *failingConn) Read([]byte) (int, error) { return 0, c.err }
+
+func TestReceiveErrorClasses(t *testing.T) {
+	for _, e := range []struct {
+		err      error
+		expected ErrorClass
+	}{
+		{errors.New("a"), UnknownError},
+		{io.ErrUnexpectedEOF, PrematureEOF},
+		{&net.OpError{Op: "read", Err: syscall.ECONNRESET}, ConnectionReset},
+	} {
+		c := Connection{connection: &failingConn{err: e.err}}
+		if _, err := c.receive(); ClassifyError(err) != e.expected {
+			t.Errorf("Failed ! %v received as %q, expected %s", e.err, ClassifyError(err), e.expected)
+		}
+	}
+}
@@ -0,0 +1,462 @@
+package ftwhttp
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/hpack"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// GRPCProto is the protocol offered using ALPN for gRPC calls over TLS
+const GRPCProto = "h2"
+
+// grpcStream is the HTTP/2 stream used for the call. We only do one call per connection.
+const grpcStream = 1
+
+// GRPC describes a gRPC unary call
+type GRPC struct {
+	// Service is the full name of the service, like `helloworld.Greeter`
+	Service string `yaml:"service"`
+	// Method is the name of the method in the service, like `SayHello`
+	Method string `yaml:"method"`
+	// MessageBase64 is the request message, as protobuf bytes in base64
+	MessageBase64 string `yaml:"message_base64,omitempty"`
+	// MessageJSON is the request message as JSON, encoded using the descriptor set
+	MessageJSON string `yaml:"message_json,omitempty"`
+	// DescriptorSet is the path of a protobuf FileDescriptorSet file, like those made by `protoc --descriptor_set_out`.
+	// When set, response messages are decoded to JSON. Relative paths in test files are relative to the test file.
+	DescriptorSet string `yaml:"descriptor_set,omitempty"`
+	// Metadata is sent as headers
+	Metadata Header `yaml:"metadata,omitempty"`
+}
+
+// Path returns the HTTP/2 path for the call
+func (g *GRPC) Path() string {
+	return "/" + g.Service + "/" + g.Method
+}
+
+// Validate checks the call can be made
+func (g *GRPC) Validate() error {
+	if g.Service == "" || g.Method == "" {
+		return errors.New("ftw/http: grpc needs service and method")
+	}
+	if g.MessageBase64 != "" && g.MessageJSON != "" {
+		return errors.New("ftw/http: grpc: choose between message_base64 or message_json")
+	}
+	if g.MessageJSON != "" && g.DescriptorSet == "" {
+		return errors.New("ftw/http: grpc: message_json needs a descriptor_set")
+	}
+	_, err := g.Message()
+	return err
+}
+
+// method returns the descriptor for the method, from the descriptor set
+func (g *GRPC) method() (protoreflect.MethodDescriptor, error) {
+	data, err := os.ReadFile(g.DescriptorSet)
+	if err != nil {
+		return nil, err
+	}
+	var set descriptorpb.FileDescriptorSet
+	if err = proto.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("ftw/http: bad descriptor set %s: %w", g.DescriptorSet, err)
+	}
+	files, err := protodesc.NewFiles(&set)
+	if err != nil {
+		return nil, fmt.Errorf("ftw/http: bad descriptor set %s: %w", g.DescriptorSet, err)
+	}
+	descriptor, err := files.FindDescriptorByName(protoreflect.FullName(g.Service + "." + g.Method))
+	if err != nil {
+		return nil, fmt.Errorf("ftw/http: cannot find %s.%s in %s: %w", g.Service, g.Method, g.DescriptorSet, err)
+	}
+	method, ok := descriptor.(protoreflect.MethodDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("ftw/http: %s.%s is not a method", g.Service, g.Method)
+	}
+	return method, nil
+}
+
+// Message returns the request message as protobuf bytes
+func (g *GRPC) Message() ([]byte, error) {
+	if g.MessageJSON == "" {
+		return base64.StdEncoding.DecodeString(g.MessageBase64)
+	}
+	method, err := g.method()
+	if err != nil {
+		return nil, err
+	}
+	message := dynamicpb.NewMessage(method.Input())
+	if err = protojson.Unmarshal([]byte(g.MessageJSON), message); err != nil {
+		return nil, fmt.Errorf("ftw/http: bad message_json: %w", err)
+	}
+	return proto.Marshal(message)
+}
+
+// decodeResponse returns the response messages, as JSON if there is a descriptor set
+func (g *GRPC) decodeResponse(messages [][]byte) []byte {
+	if g.DescriptorSet == "" {
+		return bytes.Join(messages, nil)
+	}
+	method, err := g.method()
+	if err != nil {
+		return bytes.Join(messages, nil)
+	}
+	var decoded [][]byte
+	for _, m := range messages {
+		message := dynamicpb.NewMessage(method.Output())
+		if err = proto.Unmarshal(m, message); err != nil {
+			log.Debug().Msgf("ftw/http/grpc: cannot decode response message: %s", err.Error())
+			decoded = append(decoded, m)
+			continue
+		}
+		json, err := protojson.Marshal(message)
+		if err != nil {
+			decoded = append(decoded, m)
+			continue
+		}
+		decoded = append(decoded, json)
+	}
+	return bytes.Join(decoded, []byte("\n"))
+}
+
+// GRPCStatus returns the grpc-status from the trailers, or from the headers in trailers-only responses
+func (r *Response) GRPCStatus() (int, bool) {
+	value := r.Parsed.Trailer.Get("Grpc-Status")
+	if value == "" {
+		value = r.Parsed.Header.Get("Grpc-Status")
+	}
+	status, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, false
+	}
+	return status, true
+}
+
+// grpcFrame adds the gRPC length prefix to the message
+func grpcFrame(message []byte) []byte {
+	frame := make([]byte, 5, 5+len(message))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(message)))
+	return append(frame, message...)
+}
+
+// grpcMessages splits the data in messages, decompressing them using encoding if needed
+func grpcMessages(data []byte, encoding string) ([][]byte, error) {
+	var messages [][]byte
+	for len(data) > 0 {
+		if len(data) < 5 {
+			return messages, errors.New("ftw/http/grpc: truncated message prefix")
+		}
+		compressed := data[0] == 1
+		length := binary.BigEndian.Uint32(data[1:5])
+		if uint64(len(data)-5) < uint64(length) {
+			return messages, errors.New("ftw/http/grpc: truncated message")
+		}
+		message := data[5 : 5+length]
+		if compressed {
+			decompressed, err := Decompress(encoding, message)
+			if err != nil {
+				return messages, err
+			}
+			message = decompressed
+		}
+		messages = append(messages, message)
+		data = data[5+length:]
+	}
+	return messages, nil
+}
+
+// GRPC makes the gRPC call using HTTP/2 over the connection, with prior knowledge for cleartext connections.
+// The response has the HTTP status, headers and trailers, and the response messages as body.
+func (c *Connection) GRPC(authority string, call *GRPC) (*Response, error) {
+	if c.connection == nil {
+		return nil, errors.New("ftw/http/grpc: not connected to server")
+	}
+	message, err := call.Message()
+	if err != nil {
+		return nil, err
+	}
+
+	var raw bytes.Buffer
+	framer := http2.NewFramer(c.connection, io.TeeReader(c.connection, &raw))
+	framer.ReadMetaHeaders = hpack.NewDecoder(4096, nil)
+	exchange := newGRPCExchange(framer)
+
+	begin := time.Now()
+	if err = c.writeGRPCRequest(exchange, authority, call, message); err != nil {
+		return nil, err
+	}
+	c.duration.write = time.Since(begin)
+
+	return c.readGRPCResponse(exchange, &raw, call)
+}
+
+// grpcExchange is the state of the call: the HTTP/2 send windows, and what was received
+type grpcExchange struct {
+	framer *http2.Framer
+	// initialWindow is the send window of new streams, from the SETTINGS_INITIAL_WINDOW_SIZE of the server
+	initialWindow int64
+	// connectionWindow and streamWindow are the bytes we can send before the server sends WINDOW_UPDATE
+	connectionWindow int64
+	streamWindow     int64
+	// settings is true once the settings of the server were received
+	settings bool
+	headers  []hpack.HeaderField
+	trailers []hpack.HeaderField
+	data     bytes.Buffer
+	ended    bool
+}
+
+// newGRPCExchange starts with the windows every HTTP/2 connection has before the settings arrive
+func newGRPCExchange(framer *http2.Framer) *grpcExchange {
+	return &grpcExchange{
+		framer:           framer,
+		initialWindow:    65535,
+		connectionWindow: 65535,
+		streamWindow:     65535,
+	}
+}
+
+// sendWindow is the number of bytes of data that can be sent now
+func (e *grpcExchange) sendWindow() int64 {
+	if e.connectionWindow < e.streamWindow {
+		return e.connectionWindow
+	}
+	return e.streamWindow
+}
+
+// handle updates the exchange with the frame, answering settings and pings,
+// and giving back the window used by received data
+func (e *grpcExchange) handle(frame http2.Frame) error {
+	switch f := frame.(type) {
+	case *http2.SettingsFrame:
+		if f.IsAck() {
+			return nil
+		}
+		if size, ok := f.Value(http2.SettingInitialWindowSize); ok {
+			e.streamWindow += int64(size) - e.initialWindow
+			e.initialWindow = int64(size)
+		}
+		e.settings = true
+		return e.framer.WriteSettingsAck()
+	case *http2.WindowUpdateFrame:
+		if f.StreamID == 0 {
+			e.connectionWindow += int64(f.Increment)
+		} else if f.StreamID == grpcStream {
+			e.streamWindow += int64(f.Increment)
+		}
+	case *http2.PingFrame:
+		if !f.IsAck() {
+			return e.framer.WritePing(true, f.Data)
+		}
+	case *http2.MetaHeadersFrame:
+		if f.StreamID != grpcStream {
+			return nil
+		}
+		if e.headers == nil {
+			e.headers = f.Fields
+		} else {
+			e.trailers = f.Fields
+		}
+		e.ended = f.StreamEnded()
+	case *http2.DataFrame:
+		if f.StreamID != grpcStream {
+			return nil
+		}
+		e.data.Write(f.Data())
+		e.ended = f.StreamEnded()
+		if size := f.Length; size > 0 {
+			if err := e.framer.WriteWindowUpdate(0, size); err != nil {
+				return err
+			}
+			if !e.ended {
+				return e.framer.WriteWindowUpdate(grpcStream, size)
+			}
+		}
+	case *http2.RSTStreamFrame:
+		return &ClassifiedError{Class: ConnectionReset, Err: fmt.Errorf("ftw/http/grpc: stream reset by server: %s", f.ErrCode)}
+	case *http2.GoAwayFrame:
+		if e.headers == nil {
+			return &ClassifiedError{Class: ConnectionReset, Err: fmt.Errorf("ftw/http/grpc: connection closed by server: %s", f.ErrCode)}
+		}
+		e.ended = true
+	}
+	return nil
+}
+
+// readFrame reads the next frame and handles it, classifying errors
+func (c *Connection) readFrame(e *grpcExchange, timeout time.Duration) error {
+	if err := c.connection.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+		return classifyTransportError(err)
+	}
+	frame, err := e.framer.ReadFrame()
+	if err != nil {
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() && e.headers == nil {
+			return &ClassifiedError{Class: ReadTimeout, Err: err}
+		}
+		if errors.Is(err, io.EOF) {
+			return &ClassifiedError{Class: PrematureEOF, Err: err}
+		}
+		return classifyTransportError(err)
+	}
+	if err = e.handle(frame); err != nil {
+		var classified *ClassifiedError
+		if errors.As(err, &classified) {
+			return err
+		}
+		return classifyTransportError(err)
+	}
+	return nil
+}
+
+// writeGRPCRequest writes the connection preface, the settings, and the request.
+// Data is sent as the send windows of the server allow, reading frames while waiting for WINDOW_UPDATE.
+func (c *Connection) writeGRPCRequest(e *grpcExchange, authority string, call *GRPC, message []byte) error {
+	if _, err := c.connection.Write([]byte(http2.ClientPreface)); err != nil {
+		return classifyTransportError(err)
+	}
+	if err := e.framer.WriteSettings(); err != nil {
+		return classifyTransportError(err)
+	}
+
+	scheme := "http"
+	if strings.ToLower(c.protocol) == "https" {
+		scheme = "https"
+	}
+
+	var block bytes.Buffer
+	encoder := hpack.NewEncoder(&block)
+	fields := []hpack.HeaderField{
+		{Name: ":method", Value: "POST"},
+		{Name: ":scheme", Value: scheme},
+		{Name: ":path", Value: call.Path()},
+		{Name: ":authority", Value: authority},
+		{Name: "content-type", Value: "application/grpc"},
+		{Name: "te", Value: "trailers"},
+	}
+	for _, name := range call.Metadata.getSortedHeadersByName() {
+		fields = append(fields, hpack.HeaderField{Name: strings.ToLower(name), Value: call.Metadata[name]})
+	}
+	for _, f := range fields {
+		if err := encoder.WriteField(f); err != nil {
+			return err
+		}
+	}
+	log.Debug().Msgf("ftw/http/grpc: sending call %s with headers %v", call.Path(), fields)
+
+	if err := e.framer.WriteHeaders(http2.HeadersFrameParam{
+		StreamID:      grpcStream,
+		BlockFragment: block.Bytes(),
+		EndHeaders:    true,
+	}); err != nil {
+		return classifyTransportError(err)
+	}
+
+	// the server starts with its settings, which can make the windows smaller
+	for !e.settings {
+		if err := c.readFrame(e, 1000*time.Millisecond); err != nil {
+			return err
+		}
+	}
+
+	// the default max frame size is 16KB
+	body := grpcFrame(message)
+	for len(body) > 0 {
+		if e.ended {
+			// the server answered without reading the whole message
+			log.Debug().Msgf("ftw/http/grpc: response received with %d bytes of the request not sent", len(body))
+			return nil
+		}
+		window := e.sendWindow()
+		if window <= 0 {
+			if err := c.readFrame(e, 1000*time.Millisecond); err != nil {
+				return err
+			}
+			continue
+		}
+		size := len(body)
+		if size > 16384 {
+			size = 16384
+		}
+		if int64(size) > window {
+			size = int(window)
+		}
+		if err := e.framer.WriteData(grpcStream, size == len(body), body[:size]); err != nil {
+			return classifyTransportError(err)
+		}
+		e.connectionWindow -= int64(size)
+		e.streamWindow -= int64(size)
+		body = body[size:]
+	}
+	return nil
+}
+
+// readGRPCResponse reads frames until the stream ends
+func (c *Connection) readGRPCResponse(e *grpcExchange, raw *bytes.Buffer, call *GRPC) (*Response, error) {
+	timeoutDuration := 1000 * time.Millisecond
+	begin := time.Now()
+
+	for !e.ended {
+		if err := c.readFrame(e, timeoutDuration); err != nil {
+			return nil, err
+		}
+		if c.duration.firstByte == 0 {
+			c.duration.firstByte = time.Since(begin)
+		}
+	}
+	c.duration.read = time.Since(begin) - c.duration.firstByte
+
+	return grpcResponse(raw.Bytes(), e.headers, e.trailers, e.data.Bytes(), call)
+}
+
+// grpcResponse builds the response from what was received
+func grpcResponse(raw []byte, headers []hpack.HeaderField, trailers []hpack.HeaderField, data []byte, call *GRPC) (*Response, error) {
+	parsed := http.Response{
+		Proto:      "HTTP/2.0",
+		ProtoMajor: 2,
+		Header:     make(http.Header),
+		Trailer:    make(http.Header),
+	}
+	for _, f := range headers {
+		if f.Name == ":status" {
+			status, err := strconv.Atoi(f.Value)
+			if err != nil {
+				return nil, &ClassifiedError{Class: MalformedResponse, Err: fmt.Errorf("ftw/http/grpc: bad status %q", f.Value)}
+			}
+			parsed.StatusCode = status
+			parsed.Status = fmt.Sprintf("%d %s", status, http.StatusText(status))
+			continue
+		}
+		parsed.Header.Add(f.Name, f.Value)
+	}
+	for _, f := range trailers {
+		parsed.Trailer.Add(f.Name, f.Value)
+	}
+
+	messages, err := grpcMessages(data, parsed.Header.Get("Grpc-Encoding"))
+	if err != nil {
+		log.Debug().Msgf("ftw/http/grpc: %s", err.Error())
+	}
+	body := call.decodeResponse(messages)
+	parsed.Body = io.NopCloser(bytes.NewReader(body))
+	parsed.ContentLength = int64(len(body))
+
+	return &Response{RAW: raw, Parsed: parsed}, nil
+}
@@ -0,0 +1,232 @@
+package ftwhttp
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/http2"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// helloMessage encodes a message with a string in field 1, like HelloRequest and HelloReply
+func helloMessage(s string) []byte {
+	return append([]byte{0x0a, byte(len(s))}, s...)
+}
+
+// newGRPCServer serves a Greeter service over cleartext HTTP/2. Names containing "attack" are
+// refused with grpc-status 7 (permission denied).
+func newGRPCServer(t *testing.T) net.Listener {
+	return serveGRPC(t, &http2.Server{})
+}
+
+// serveGRPC serves the Greeter service using the HTTP/2 server
+func serveGRPC(t *testing.T, server *http2.Server) net.Listener {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/grpc")
+		w.Header().Set("Trailer", "Grpc-Status")
+		if r.URL.Path != "/helloworld.Greeter/SayHello" {
+			w.Header().Set("Grpc-Status", "12")
+			return
+		}
+		var name string
+		if len(body) > 7 {
+			name = string(body[7:])
+		}
+		if strings.Contains(name, "attack") || r.Header.Get("X-Attack") != "" {
+			w.Header().Set("Grpc-Status", "7")
+			return
+		}
+		_, _ = w.Write(grpcFrame(helloMessage("Hello " + name)))
+		w.Header().Set("Grpc-Status", "0")
+	})
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.ServeConn(conn, &http2.ServeConnOpts{Handler: handler})
+		}
+	}()
+
+	return listener
+}
+
+// writeGreeterDescriptorSet writes a descriptor set for the helloworld.Greeter service
+func writeGreeterDescriptorSet(t *testing.T) string {
+	stringField := func(name string) *descriptorpb.FieldDescriptorProto {
+		return &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(name),
+			JsonName: proto.String(name),
+			Number:   proto.Int32(1),
+			Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+			Type:     descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+		}
+	}
+	set := &descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{{
+		Name:    proto.String("helloworld.proto"),
+		Package: proto.String("helloworld"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{Name: proto.String("HelloRequest"), Field: []*descriptorpb.FieldDescriptorProto{stringField("name")}},
+			{Name: proto.String("HelloReply"), Field: []*descriptorpb.FieldDescriptorProto{stringField("message")}},
+		},
+		Service: []*descriptorpb.ServiceDescriptorProto{{
+			Name: proto.String("Greeter"),
+			Method: []*descriptorpb.MethodDescriptorProto{{
+				Name:       proto.String("SayHello"),
+				InputType:  proto.String(".helloworld.HelloRequest"),
+				OutputType: proto.String(".helloworld.HelloReply"),
+			}},
+		}},
+	}}}
+
+	data, err := proto.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(t.TempDir(), "helloworld.protoset")
+	if err = os.WriteFile(file, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func doGRPC(t *testing.T, server net.Listener, call *GRPC) *Response {
+	c := NewClient()
+	if err := c.NewConnection(destinationFromListener(t, server)); err != nil {
+		t.Fatal(err)
+	}
+	response, err := c.DoGRPC(server.Addr().String(), call)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return response
+}
+
+func TestGRPCRawMessage(t *testing.T) {
+	server := newGRPCServer(t)
+	defer server.Close()
+
+	response := doGRPC(t, server, &GRPC{
+		Service:       "helloworld.Greeter",
+		Method:        "SayHello",
+		MessageBase64: base64.StdEncoding.EncodeToString(helloMessage("ftw")),
+	})
+
+	if response.Parsed.StatusCode != http.StatusOK {
+		t.Errorf("Failed ! got status %d", response.Parsed.StatusCode)
+	}
+	if status, ok := response.GRPCStatus(); !ok || status != 0 {
+		t.Errorf("Failed ! got grpc-status %d", status)
+	}
+	if body := response.GetBodyAsString(); body != string(helloMessage("Hello ftw")) {
+		t.Errorf("Failed ! got body %q", body)
+	}
+}
+
+func TestGRPCLargeMessage(t *testing.T) {
+	// the server lets us send 16KB before updating the window
+	server := serveGRPC(t, &http2.Server{MaxUploadBufferPerStream: 16384})
+	defer server.Close()
+
+	name := strings.Repeat("a", 100000)
+	response := doGRPC(t, server, &GRPC{
+		Service:       "helloworld.Greeter",
+		Method:        "SayHello",
+		MessageBase64: base64.StdEncoding.EncodeToString(helloMessage(name)),
+	})
+
+	if status, ok := response.GRPCStatus(); !ok || status != 0 {
+		t.Errorf("Failed ! got grpc-status %d", status)
+	}
+	// the reply is also larger than the initial window
+	if body := response.GetBodyAsString(); body != string(helloMessage("Hello "+name)) {
+		t.Errorf("Failed ! got body of %d bytes", len(body))
+	}
+}
+
+func TestGRPCJSONMessage(t *testing.T) {
+	server := newGRPCServer(t)
+	defer server.Close()
+
+	call := &GRPC{
+		Service:       "helloworld.Greeter",
+		Method:        "SayHello",
+		MessageJSON:   `{"name": "json"}`,
+		DescriptorSet: writeGreeterDescriptorSet(t),
+	}
+	if err := call.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	message, err := call.Message()
+	if err != nil || !bytes.Equal(message, helloMessage("json")) {
+		t.Fatalf("Failed ! got %q, %v", message, err)
+	}
+
+	response := doGRPC(t, server, call)
+	if body := response.GetBodyAsString(); !strings.Contains(body, `"message":"Hello json"`) {
+		t.Errorf("Failed ! got body %q", body)
+	}
+}
+
+func TestGRPCStatusRefused(t *testing.T) {
+	server := newGRPCServer(t)
+	defer server.Close()
+
+	for _, call := range []*GRPC{
+		{Service: "helloworld.Greeter", Method: "SayHello", MessageBase64: base64.StdEncoding.EncodeToString(helloMessage("an attack"))},
+		{Service: "helloworld.Greeter", Method: "SayHello", Metadata: Header{"X-Attack": "yes"}},
+	} {
+		response := doGRPC(t, server, call)
+		if status, ok := response.GRPCStatus(); !ok || status != 7 {
+			t.Errorf("Failed ! got grpc-status %d", status)
+		}
+	}
+}
+
+func TestGRPCValidate(t *testing.T) {
+	bad := []GRPC{
+		{Method: "SayHello"},
+		{Service: "helloworld.Greeter", Method: "SayHello", MessageBase64: "%%%"},
+		{Service: "helloworld.Greeter", Method: "SayHello", MessageJSON: "{}"},
+		{Service: "helloworld.Greeter", Method: "SayHello", MessageJSON: "{}", MessageBase64: "AA=="},
+		{Service: "helloworld.Greeter", Method: "SayHello", MessageJSON: "{}", DescriptorSet: "/does/not/exist"},
+	}
+	for _, g := range bad {
+		g := g
+		if g.Validate() == nil {
+			t.Errorf("Failed ! %+v should not be valid", g)
+		}
+	}
+}
+
+func TestGRPCMessages(t *testing.T) {
+	compressed, _ := Compress(GzipEncoding, []byte("two"))
+	data := append(grpcFrame([]byte("one")), append([]byte{1, 0, 0, 0, byte(len(compressed))}, compressed...)...)
+
+	messages, err := grpcMessages(data, GzipEncoding)
+	if err != nil || len(messages) != 2 || string(messages[0]) != "one" || string(messages[1]) != "two" {
+		t.Errorf("Failed ! got %q, %v", messages, err)
+	}
+
+	if _, err = grpcMessages([]byte{0, 0, 0, 0, 9, 'a'}, ""); err == nil {
+		t.Errorf("Failed ! truncated message should fail")
+	}
+}
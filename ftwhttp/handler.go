@@ -0,0 +1,148 @@
+package ftwhttp
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// badRequestResponse is what the go http server answers when it cannot parse the request
+const badRequestResponse = "HTTP/1.1 400 Bad Request\r\nContent-Type: text/plain; charset=utf-8\r\nConnection: close\r\n\r\n400 Bad Request"
+
+// HandlerDialer returns a DialFunc whose connections are served in-process by the http.Handler passed,
+// without using the network. The raw request bytes are parsed using the go stdlib, the handler
+// is called, and its response is serialized back so it can be read as usual.
+// Use it as the Dial hook in Client, with the http protocol.
+func HandlerDialer(handler http.Handler) DialFunc {
+	return func(network string, address string) (net.Conn, error) {
+		return &handlerConn{
+			handler: handler,
+			local:   handlerAddr{network: network, address: "127.0.0.1:0"},
+			remote:  handlerAddr{network: network, address: address},
+		}, nil
+	}
+}
+
+// handlerConn is a net.Conn that buffers everything written, and on the first read
+// passes the request to the handler and returns the response
+type handlerConn struct {
+	mu       sync.Mutex
+	handler  http.Handler
+	local    handlerAddr
+	remote   handlerAddr
+	request  bytes.Buffer
+	response *bytes.Reader
+	closed   bool
+}
+
+// Read returns the response from the handler. The request is considered complete on the first read.
+func (c *handlerConn) Read(b []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return 0, net.ErrClosed
+	}
+	if c.response == nil {
+		c.response = bytes.NewReader(c.serve())
+	}
+	return c.response.Read(b)
+}
+
+// Write stores the request bytes until the response is read
+func (c *handlerConn) Write(b []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return 0, net.ErrClosed
+	}
+	if c.response != nil {
+		return 0, errors.New("ftw/http: in-process connection only supports one request")
+	}
+	return c.request.Write(b)
+}
+
+// Close closes the connection
+func (c *handlerConn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.closed = true
+	return nil
+}
+
+// LocalAddr returns the local address
+func (c *handlerConn) LocalAddr() net.Addr {
+	return c.local
+}
+
+// RemoteAddr returns the remote address, which is the address dialed
+func (c *handlerConn) RemoteAddr() net.Addr {
+	return c.remote
+}
+
+// SetDeadline does nothing, as the handler is called synchronously
+func (c *handlerConn) SetDeadline(t time.Time) error {
+	return nil
+}
+
+// SetReadDeadline does nothing, as the handler is called synchronously
+func (c *handlerConn) SetReadDeadline(t time.Time) error {
+	return nil
+}
+
+// SetWriteDeadline does nothing, as the handler is called synchronously
+func (c *handlerConn) SetWriteDeadline(t time.Time) error {
+	return nil
+}
+
+// serve parses the request, calls the handler and returns the raw response
+func (c *handlerConn) serve() []byte {
+	req, err := http.ReadRequest(bufio.NewReader(bytes.NewReader(c.request.Bytes())))
+	if err != nil {
+		return []byte(badRequestResponse)
+	}
+	req.RemoteAddr = c.local.String()
+
+	recorder := httptest.NewRecorder()
+	c.handler.ServeHTTP(recorder, req)
+	// drain the body, as a real server would
+	_, _ = io.Copy(io.Discard, req.Body)
+
+	result := recorder.Result()
+	if result.Header.Get("Content-Length") == "" {
+		result.Header.Set("Content-Length", strconv.Itoa(recorder.Body.Len()))
+		result.ContentLength = int64(recorder.Body.Len())
+	}
+	result.Close = true
+
+	var b bytes.Buffer
+	if err = result.Write(&b); err != nil {
+		return []byte(badRequestResponse)
+	}
+	return b.Bytes()
+}
+
+// handlerAddr is the net.Addr used for in-process connections
+type handlerAddr struct {
+	network string
+	address string
+}
+
+// Network returns the network name
+func (a handlerAddr) Network() string {
+	return a.network
+}
+
+// String returns the address
+func (a handlerAddr) String() string {
+	return a.address
+}
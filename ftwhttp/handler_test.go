@@ -0,0 +1,94 @@
+package ftwhttp
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func echoHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("X-Method", r.Method)
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(r.URL.Path + ":" + string(body)))
+	})
+}
+
+func TestHandlerDialer(t *testing.T) {
+	c := NewClient()
+	c.Dial = HandlerDialer(echoHandler())
+
+	if err := c.NewConnection(Destination{DestAddr: "localhost", Port: 80, Protocol: "http"}); err != nil {
+		t.Fatalf("Error connecting in-process: %s", err.Error())
+	}
+
+	rl := &RequestLine{
+		Method:  "POST",
+		URI:     "/echo",
+		Version: "HTTP/1.1",
+	}
+	h := Header{"Accept": "*/*", "Host": "localhost"}
+	req := NewRequest(rl, h, []byte("a=b"), true)
+
+	resp, err := c.Do(*req)
+	if err != nil {
+		t.Fatalf("Error calling handler: %s", err.Error())
+	}
+
+	if resp.Parsed.StatusCode != http.StatusAccepted {
+		t.Errorf("Unexpected status %d", resp.Parsed.StatusCode)
+	}
+	if resp.Parsed.Header.Get("X-Method") != "POST" {
+		t.Errorf("Missing header in response: %q", resp.RAW)
+	}
+	if body := resp.GetBodyAsString(); body != "/echo:a=b" {
+		t.Errorf("Unexpected body %q", body)
+	}
+}
+
+func TestHandlerDialerBadRequest(t *testing.T) {
+	called := false
+	c := NewClient()
+	c.Dial = HandlerDialer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+
+	if err := c.NewConnection(Destination{DestAddr: "localhost", Port: 80, Protocol: "http"}); err != nil {
+		t.Fatalf("Error connecting in-process: %s", err.Error())
+	}
+
+	req := NewRawRequest([]byte("THIS IS NOT HTTP\r\n\r\n"), false)
+	resp, err := c.Do(*req)
+	if err != nil {
+		t.Fatalf("Error calling handler: %s", err.Error())
+	}
+
+	if called {
+		t.Error("Handler should not be called with a malformed request")
+	}
+	if resp.Parsed.StatusCode != http.StatusBadRequest {
+		t.Errorf("Unexpected status %d", resp.Parsed.StatusCode)
+	}
+}
+
+func TestHandlerConnSingleRequest(t *testing.T) {
+	conn, _ := HandlerDialer(echoHandler())(TCPNetwork, "localhost:80")
+
+	if _, err := conn.Write([]byte("GET / HTTP/1.1\r\nHost: localhost\r\n\r\n")); err != nil {
+		t.Fatal(err)
+	}
+	response, err := io.ReadAll(conn)
+	if err != nil || !strings.HasPrefix(string(response), "HTTP/1.1 202 Accepted") {
+		t.Fatalf("Unexpected response %q", response)
+	}
+
+	if _, err = conn.Write([]byte("GET / HTTP/1.1\r\n\r\n")); err == nil {
+		t.Error("Writing after the response was read should fail")
+	}
+	conn.Close()
+	if _, err = conn.Read(make([]byte, 1)); err == nil {
+		t.Error("Reading from a closed connection should fail")
+	}
+}
@@ -0,0 +1,135 @@
+package ftwhttp
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Part is one part of a multipart/form-data body
+type Part struct {
+	Name string `yaml:"name"`
+	// Filename, when set, is added to the Content-Disposition header, even if empty
+	Filename    *string `yaml:"filename,omitempty"`
+	ContentType string  `yaml:"content_type,omitempty"`
+	// Use only one of Content, ContentBase64 or File
+	Content       string `yaml:"content,omitempty"`
+	ContentBase64 string `yaml:"content_base64,omitempty"`
+	// File is the path of the file with the content. Relative paths in test files are relative to the test file.
+	File string `yaml:"file,omitempty"`
+	// Headers are added to the part headers, replacing Content-Disposition and Content-Type if present
+	Headers Header `yaml:"headers,omitempty"`
+}
+
+// MultipartBody describes a multipart/form-data body. Parts are sent in order, so you can repeat names.
+type MultipartBody struct {
+	// Boundary is generated when empty
+	Boundary string `yaml:"boundary,omitempty"`
+	Parts    []Part `yaml:"parts"`
+	// NoFinalBoundary leaves out the closing boundary
+	NoFinalBoundary bool `yaml:"no_final_boundary,omitempty"`
+	// QuotedBoundary puts the boundary between quotes in the Content-Type header
+	QuotedBoundary bool `yaml:"quoted_boundary,omitempty"`
+}
+
+// Validate checks every part has at most one content source, and that files exist
+func (m *MultipartBody) Validate() error {
+	for i, p := range m.Parts {
+		sources := 0
+		for _, s := range []string{p.Content, p.ContentBase64, p.File} {
+			if s != "" {
+				sources++
+			}
+		}
+		if sources > 1 {
+			return fmt.Errorf("ftw/http: multipart part %d: choose between content, content_base64 or file", i+1)
+		}
+		if p.ContentBase64 != "" {
+			if _, err := base64.StdEncoding.DecodeString(p.ContentBase64); err != nil {
+				return fmt.Errorf("ftw/http: multipart part %d: bad content_base64: %w", i+1, err)
+			}
+		}
+		if p.File != "" {
+			if _, err := os.Stat(p.File); err != nil {
+				return fmt.Errorf("ftw/http: multipart part %d: bad file: %w", i+1, err)
+			}
+		}
+	}
+	return nil
+}
+
+// ContentType returns the value for the Content-Type header, using the boundary passed
+func (m *MultipartBody) ContentType(boundary string) string {
+	if m.QuotedBoundary {
+		return fmt.Sprintf("multipart/form-data; boundary=\"%s\"", boundary)
+	}
+	return fmt.Sprintf("multipart/form-data; boundary=%s", boundary)
+}
+
+// Bytes serializes the multipart body using the boundary passed
+func (m *MultipartBody) Bytes(boundary string) ([]byte, error) {
+	var b bytes.Buffer
+
+	for i, p := range m.Parts {
+		content, err := p.content()
+		if err != nil {
+			return nil, fmt.Errorf("ftw/http: multipart part %d: %w", i+1, err)
+		}
+		fmt.Fprintf(&b, "--%s\r\n", boundary)
+		_ = p.headers().WriteBytes(&b)
+		b.WriteString("\r\n")
+		b.Write(content)
+		b.WriteString("\r\n")
+	}
+
+	if !m.NoFinalBoundary {
+		fmt.Fprintf(&b, "--%s--\r\n", boundary)
+	}
+
+	return b.Bytes(), nil
+}
+
+// headers returns the headers for the part
+func (p Part) headers() Header {
+	disposition := "form-data"
+	if p.Name != "" {
+		disposition += fmt.Sprintf("; name=\"%s\"", p.Name)
+	}
+	if p.Filename != nil {
+		disposition += fmt.Sprintf("; filename=\"%s\"", *p.Filename)
+	}
+
+	h := Header{"Content-Disposition": disposition}
+	if p.ContentType != "" {
+		h.Set(ContentTypeHeader, p.ContentType)
+	}
+	for name, value := range p.Headers {
+		h.Set(name, value)
+	}
+	return h
+}
+
+// content returns the content of the part, from the source used
+func (p Part) content() ([]byte, error) {
+	switch {
+	case p.ContentBase64 != "":
+		return base64.StdEncoding.DecodeString(p.ContentBase64)
+	case p.File != "":
+		return os.ReadFile(p.File)
+	default:
+		return []byte(p.Content), nil
+	}
+}
+
+// randomBoundary generates a boundary like the go stdlib does
+func randomBoundary() string {
+	var buf [15]byte
+	if _, err := rand.Read(buf[:]); err != nil {
+		panic(errors.New("ftw/http: cannot generate multipart boundary: " + err.Error()))
+	}
+	return strings.Repeat("-", 10) + fmt.Sprintf("%x", buf[:])
+}
@@ -0,0 +1,117 @@
+package ftwhttp
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMultipartBodyBytes(t *testing.T) {
+	filename := "shell.php"
+	m := MultipartBody{
+		Parts: []Part{
+			{Name: "a", Content: "1"},
+			{Name: "a", Content: "2"},
+			{Name: "file", Filename: &filename, ContentType: "image/png", ContentBase64: "PD9waHA="},
+			{Name: "b", Content: "3", Headers: Header{"Content-Disposition": "form-data; name=\"c\"", "X-Part": "yes"}},
+		},
+	}
+
+	data, err := m.Bytes("XyZ")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "--XyZ\r\nContent-Disposition: form-data; name=\"a\"\r\n\r\n1\r\n" +
+		"--XyZ\r\nContent-Disposition: form-data; name=\"a\"\r\n\r\n2\r\n" +
+		"--XyZ\r\nContent-Disposition: form-data; name=\"file\"; filename=\"shell.php\"\r\nContent-Type: image/png\r\n\r\n<?php\r\n" +
+		"--XyZ\r\nContent-Disposition: form-data; name=\"c\"\r\nX-Part: yes\r\n\r\n3\r\n" +
+		"--XyZ--\r\n"
+	if string(data) != expected {
+		t.Errorf("Failed ! got %q", data)
+	}
+}
+
+func TestMultipartMalformed(t *testing.T) {
+	m := MultipartBody{
+		Parts:           []Part{{Name: "a", Content: "1"}},
+		NoFinalBoundary: true,
+		QuotedBoundary:  true,
+	}
+
+	data, err := m.Bytes("XyZ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("--XyZ--")) {
+		t.Errorf("Failed ! final boundary found in %q", data)
+	}
+	if ct := m.ContentType("XyZ"); ct != "multipart/form-data; boundary=\"XyZ\"" {
+		t.Errorf("Failed ! got %s", ct)
+	}
+}
+
+func TestMultipartFromFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "upload.txt")
+	if err := os.WriteFile(file, []byte("file content"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	m := MultipartBody{Parts: []Part{{Name: "f", File: file}}}
+
+	data, err := m.Bytes("XyZ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(data, []byte("\r\n\r\nfile content\r\n")) {
+		t.Errorf("Failed ! got %q", data)
+	}
+
+	m.Parts[0].File = file + ".missing"
+	if _, err = m.Bytes("XyZ"); err == nil {
+		t.Errorf("Failed !")
+	}
+}
+
+func TestMultipartValidate(t *testing.T) {
+	bad := []MultipartBody{
+		{Parts: []Part{{Content: "a", File: "b"}}},
+		{Parts: []Part{{ContentBase64: "%%%"}}},
+		{Parts: []Part{{Name: "f", File: filepath.Join(t.TempDir(), "missing.txt")}}},
+	}
+	for _, m := range bad {
+		m := m
+		if m.Validate() == nil {
+			t.Errorf("Failed ! %+v should not be valid", m)
+		}
+	}
+}
+
+func TestMultipartRequest(t *testing.T) {
+	rl := &RequestLine{
+		Method:  "POST",
+		URI:     "/",
+		Version: "HTTP/1.1",
+	}
+	req := NewRequest(rl, Header{"Host": "localhost"}, nil, true)
+	req.SetMultipartBody(&MultipartBody{Parts: []Part{{Name: "a", Content: "1\n2"}}})
+
+	data, err := buildRequest(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ct := req.Headers().Get(ContentTypeHeader)
+	if !strings.HasPrefix(ct, "multipart/form-data; boundary=") {
+		t.Fatalf("Failed ! got content type %q", ct)
+	}
+	boundary := strings.TrimPrefix(ct, "multipart/form-data; boundary=")
+	body := "--" + boundary + "\r\nContent-Disposition: form-data; name=\"a\"\r\n\r\n1\n2\r\n--" + boundary + "--\r\n"
+	if !bytes.HasSuffix(data, []byte("\r\n\r\n"+body)) {
+		t.Errorf("Failed ! got %q", data)
+	}
+	if req.Headers().Get("Content-Length") == "" {
+		t.Errorf("Failed ! no Content-Length")
+	}
+}
@@ -0,0 +1,104 @@
+package ftwhttp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"time"
+)
+
+// pcapng block types and options, see https://www.ietf.org/archive/id/draft-tuexen-opsawg-pcapng-05.html
+const (
+	pcapngSectionHeader    uint32 = 0x0a0d0d0a
+	pcapngInterface        uint32 = 0x00000001
+	pcapngEnhancedPacket   uint32 = 0x00000006
+	pcapngByteOrderMagic   uint32 = 0x1a2b3c4d
+	pcapngOptionEnd        uint16 = 0
+	pcapngOptionComment    uint16 = 1
+	pcapngOptionUserAppl   uint16 = 4
+	pcapngOptionIfName     uint16 = 2
+	pcapngOptionIfTSResol  uint16 = 9
+	pcapngLinkTypeRaw      uint16 = 101
+	pcapngTimestampDivisor        = 1000 // timestamps are in microseconds
+)
+
+// pcapngOption is an option in a block
+type pcapngOption struct {
+	code  uint16
+	value []byte
+}
+
+// pcapngBlock serializes a block with its body and options
+func pcapngBlock(blockType uint32, body []byte, options []pcapngOption) []byte {
+	var opts bytes.Buffer
+	for _, o := range options {
+		_ = binary.Write(&opts, binary.LittleEndian, o.code)
+		_ = binary.Write(&opts, binary.LittleEndian, uint16(len(o.value)))
+		opts.Write(pad32(o.value))
+	}
+	if len(options) > 0 {
+		_ = binary.Write(&opts, binary.LittleEndian, pcapngOptionEnd)
+		_ = binary.Write(&opts, binary.LittleEndian, uint16(0))
+	}
+
+	body = pad32(body)
+	length := uint32(12 + len(body) + opts.Len())
+
+	var b bytes.Buffer
+	_ = binary.Write(&b, binary.LittleEndian, blockType)
+	_ = binary.Write(&b, binary.LittleEndian, length)
+	b.Write(body)
+	b.Write(opts.Bytes())
+	_ = binary.Write(&b, binary.LittleEndian, length)
+	return b.Bytes()
+}
+
+// pcapngSectionHeaderBlock starts the file
+func pcapngSectionHeaderBlock() []byte {
+	var body bytes.Buffer
+	_ = binary.Write(&body, binary.LittleEndian, pcapngByteOrderMagic)
+	_ = binary.Write(&body, binary.LittleEndian, uint16(1))
+	_ = binary.Write(&body, binary.LittleEndian, uint16(0))
+	// the section length is not known
+	_ = binary.Write(&body, binary.LittleEndian, int64(-1))
+	return pcapngBlock(pcapngSectionHeader, body.Bytes(), []pcapngOption{{pcapngOptionUserAppl, []byte("go-ftw")}})
+}
+
+// pcapngInterfaceBlock describes the only interface, which has raw IP packets
+func pcapngInterfaceBlock() []byte {
+	var body bytes.Buffer
+	_ = binary.Write(&body, binary.LittleEndian, pcapngLinkTypeRaw)
+	_ = binary.Write(&body, binary.LittleEndian, uint16(0))
+	// no snapshot length limit
+	_ = binary.Write(&body, binary.LittleEndian, uint32(0))
+	return pcapngBlock(pcapngInterface, body.Bytes(), []pcapngOption{
+		{pcapngOptionIfName, []byte("ftw")},
+		{pcapngOptionIfTSResol, []byte{6}},
+	})
+}
+
+// pcapngPacketBlock has one packet, with an optional comment
+func pcapngPacketBlock(ts time.Time, packet []byte, comment string) []byte {
+	micros := uint64(ts.UnixNano() / pcapngTimestampDivisor)
+
+	var body bytes.Buffer
+	_ = binary.Write(&body, binary.LittleEndian, uint32(0))
+	_ = binary.Write(&body, binary.LittleEndian, uint32(micros>>32))
+	_ = binary.Write(&body, binary.LittleEndian, uint32(micros))
+	_ = binary.Write(&body, binary.LittleEndian, uint32(len(packet)))
+	_ = binary.Write(&body, binary.LittleEndian, uint32(len(packet)))
+	body.Write(packet)
+
+	var options []pcapngOption
+	if comment != "" {
+		options = append(options, pcapngOption{pcapngOptionComment, []byte(comment)})
+	}
+	return pcapngBlock(pcapngEnhancedPacket, body.Bytes(), options)
+}
+
+// pad32 pads data with zeros to a multiple of 32 bits
+func pad32(data []byte) []byte {
+	if len(data)%4 == 0 {
+		return data
+	}
+	return append(append([]byte(nil), data...), make([]byte, 4-len(data)%4)...)
+}
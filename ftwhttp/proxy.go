@@ -0,0 +1,173 @@
+package ftwhttp
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"golang.org/x/net/http/httpproxy"
+	"golang.org/x/net/proxy"
+)
+
+// ProxyFunc returns the proxy to use for reaching the destination passed.
+// A nil URL and nil error means the destination is dialed directly.
+type ProxyFunc func(d Destination) (*url.URL, error)
+
+// ProxyURL returns a ProxyFunc that always uses the proxy passed
+func ProxyURL(u *url.URL) ProxyFunc {
+	return func(Destination) (*url.URL, error) {
+		return u, nil
+	}
+}
+
+// ProxyFromEnvironment uses the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables
+// (or their lowercase versions) to choose the proxy for the destination, like the go stdlib does.
+// Requests to localhost and loopback addresses are never proxied.
+func ProxyFromEnvironment(d Destination) (*url.URL, error) {
+	u := &url.URL{
+		Scheme: strings.ToLower(d.Protocol),
+		Host:   net.JoinHostPort(trimBrackets(d.DestAddr), strconv.Itoa(d.Port)),
+	}
+	return httpproxy.FromEnvironment().ProxyFunc()(u)
+}
+
+// dialProxy connects to hostPort tunneling through the proxy passed.
+// Supported schemes are `http` (using CONNECT), `socks5` and `socks5h`. Credentials in the
+// proxy URL are used for authenticating against the proxy.
+func dialProxy(dial DialFunc, proxyURL *url.URL, hostPort string, timeout time.Duration) (net.Conn, error) {
+	log.Trace().Msgf("ftw/http: connecting to %s using proxy %s", hostPort, proxyURL.Redacted())
+
+	switch strings.ToLower(proxyURL.Scheme) {
+	case "http", "":
+		return dialHTTPConnect(dial, proxyURL, hostPort, timeout)
+	case "socks5", "socks5h":
+		return dialSOCKS5(dial, proxyURL, hostPort, timeout)
+	default:
+		return nil, fmt.Errorf("ftw/http: unsupported proxy scheme %q", proxyURL.Scheme)
+	}
+}
+
+// dialHTTPConnect opens a tunnel to hostPort using the HTTP CONNECT method
+func dialHTTPConnect(dial DialFunc, proxyURL *url.URL, hostPort string, timeout time.Duration) (net.Conn, error) {
+	conn, err := dial(TCPNetwork, proxyHostPort(proxyURL, "8080"))
+	if err != nil {
+		return nil, err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "CONNECT %s HTTP/1.1\r\nHost: %s\r\n", hostPort, hostPort)
+	if user := proxyURL.User; user != nil {
+		password, _ := user.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(user.Username() + ":" + password))
+		fmt.Fprintf(&b, "Proxy-Authorization: Basic %s\r\n", credentials)
+	}
+	b.WriteString("\r\n")
+
+	if err = conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if _, err = conn.Write([]byte(b.String())); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	reader := bufio.NewReader(conn)
+	response, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("ftw/http: proxy refused to connect to %s: %s", hostPort, response.Status)
+	}
+
+	if err = conn.SetDeadline(time.Time{}); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	// The proxy might have sent bytes from the destination along with its own response
+	if reader.Buffered() > 0 {
+		return &bufferedConn{Conn: conn, reader: reader}, nil
+	}
+
+	return conn, nil
+}
+
+// dialSOCKS5 connects to hostPort using a SOCKS5 proxy
+func dialSOCKS5(dial DialFunc, proxyURL *url.URL, hostPort string, timeout time.Duration) (net.Conn, error) {
+	var auth *proxy.Auth
+	if user := proxyURL.User; user != nil {
+		password, _ := user.Password()
+		auth = &proxy.Auth{
+			User:     user.Username(),
+			Password: password,
+		}
+	}
+
+	// the handshake happens on the connection to the proxy, so the deadline is set as soon as it is dialed
+	dialWithDeadline := DialFunc(func(network string, address string) (net.Conn, error) {
+		conn, err := dial(network, address)
+		if err != nil {
+			return nil, err
+		}
+		if err = conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		return conn, nil
+	})
+
+	dialer, err := proxy.SOCKS5(TCPNetwork, proxyHostPort(proxyURL, "1080"), auth, dialWithDeadline)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := dialer.Dial(TCPNetwork, hostPort)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = conn.SetDeadline(time.Time{}); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+// proxyHostPort returns the host and port for the proxy, using defaultPort when the URL has none
+func proxyHostPort(proxyURL *url.URL, defaultPort string) string {
+	port := proxyURL.Port()
+	if port == "" {
+		port = defaultPort
+	}
+	return net.JoinHostPort(proxyURL.Hostname(), port)
+}
+
+// Dial implements proxy.Dialer, so the SOCKS5 dialer can use our DialFunc
+func (f DialFunc) Dial(network string, address string) (net.Conn, error) {
+	return f(network, address)
+}
+
+// bufferedConn is a net.Conn that first returns the data already buffered when reading
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+// Read reads from the buffer first, then from the connection
+func (b *bufferedConn) Read(p []byte) (int, error) {
+	return b.reader.Read(p)
+}
@@ -0,0 +1,346 @@
+package ftwhttp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// newRawServer starts a server that stores the raw bytes of the first request received
+// and answers with a fixed response
+func newRawServer(t *testing.T) (net.Listener, chan []byte) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	received := make(chan []byte, 1)
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		var data []byte
+		buf := make([]byte, 1024)
+		for !bytes.Contains(data, []byte("\r\n\r\n")) {
+			n, err := conn.Read(buf)
+			data = append(data, buf[:n]...)
+			if err != nil {
+				break
+			}
+		}
+		received <- data
+		_, _ = conn.Write([]byte("HTTP/1.1 200 OK\r\nContent-Length: 13\r\nConnection: close\r\n\r\nHello, client"))
+	}()
+
+	return listener, received
+}
+
+// pipe copies data both ways between the connections until one of them is closed
+func pipe(a net.Conn, b net.Conn) {
+	done := make(chan struct{}, 2)
+	go func() {
+		_, _ = io.Copy(a, b)
+		done <- struct{}{}
+	}()
+	go func() {
+		_, _ = io.Copy(b, a)
+		done <- struct{}{}
+	}()
+	<-done
+	a.Close()
+	b.Close()
+}
+
+// newConnectProxy starts a stand-in HTTP proxy that only supports CONNECT
+func newConnectProxy(t *testing.T, credentials string) net.Listener {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				reader := bufio.NewReader(conn)
+				req, err := http.ReadRequest(reader)
+				if err != nil || req.Method != http.MethodConnect {
+					conn.Close()
+					return
+				}
+				if credentials != "" && req.Header.Get("Proxy-Authorization") != "Basic "+base64.StdEncoding.EncodeToString([]byte(credentials)) {
+					_, _ = conn.Write([]byte("HTTP/1.1 407 Proxy Authentication Required\r\n\r\n"))
+					conn.Close()
+					return
+				}
+				target, err := net.Dial("tcp", req.Host)
+				if err != nil {
+					_, _ = conn.Write([]byte("HTTP/1.1 502 Bad Gateway\r\n\r\n"))
+					conn.Close()
+					return
+				}
+				_, _ = conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n"))
+				pipe(conn, target)
+			}(conn)
+		}
+	}()
+
+	return listener
+}
+
+// newSOCKS5Proxy starts a stand-in SOCKS5 proxy, supporting only the CONNECT command
+func newSOCKS5Proxy(t *testing.T, user string, password string) net.Listener {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go handleSOCKS5(conn, user, password)
+		}
+	}()
+
+	return listener
+}
+
+func handleSOCKS5(conn net.Conn, user string, password string) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		conn.Close()
+		return
+	}
+	methods := make([]byte, header[1])
+	if _, err := io.ReadFull(conn, methods); err != nil {
+		conn.Close()
+		return
+	}
+	if user == "" {
+		_, _ = conn.Write([]byte{0x05, 0x00})
+	} else {
+		_, _ = conn.Write([]byte{0x05, 0x02})
+		// RFC 1929: version, user length, user, password length, password
+		authVersion := make([]byte, 2)
+		_, _ = io.ReadFull(conn, authVersion)
+		gotUser := make([]byte, authVersion[1])
+		_, _ = io.ReadFull(conn, gotUser)
+		passwordLen := make([]byte, 1)
+		_, _ = io.ReadFull(conn, passwordLen)
+		gotPassword := make([]byte, passwordLen[0])
+		_, _ = io.ReadFull(conn, gotPassword)
+		if string(gotUser) != user || string(gotPassword) != password {
+			_, _ = conn.Write([]byte{0x01, 0x01})
+			conn.Close()
+			return
+		}
+		_, _ = conn.Write([]byte{0x01, 0x00})
+	}
+
+	request := make([]byte, 4)
+	if _, err := io.ReadFull(conn, request); err != nil {
+		conn.Close()
+		return
+	}
+	var host string
+	switch request[3] {
+	case 0x01:
+		ip := make([]byte, 4)
+		_, _ = io.ReadFull(conn, ip)
+		host = net.IP(ip).String()
+	case 0x03:
+		length := make([]byte, 1)
+		_, _ = io.ReadFull(conn, length)
+		name := make([]byte, length[0])
+		_, _ = io.ReadFull(conn, name)
+		host = string(name)
+	default:
+		conn.Close()
+		return
+	}
+	port := make([]byte, 2)
+	_, _ = io.ReadFull(conn, port)
+
+	target, err := net.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port)))))
+	if err != nil {
+		_, _ = conn.Write([]byte{0x05, 0x05, 0x00, 0x01, 0, 0, 0, 0, 0, 0})
+		conn.Close()
+		return
+	}
+	_, _ = conn.Write([]byte{0x05, 0x00, 0x00, 0x01, 0, 0, 0, 0, 0, 0})
+	pipe(conn, target)
+}
+
+func destinationFromListener(t *testing.T, l net.Listener) Destination {
+	d, err := DestinationFromString("http://" + l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return *d
+}
+
+func sendThroughProxy(t *testing.T, proxy string) {
+	server, received := newRawServer(t)
+	defer server.Close()
+
+	proxyURL, err := url.Parse(proxy)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := NewClient()
+	c.Proxy = ProxyURL(proxyURL)
+
+	raw := []byte("GET /raw HTTP/1.1\r\nHost: waf.example.com\r\nX-Weird:\tvalue \r\n\r\n")
+	req := NewRawRequest(raw, false)
+
+	if err = c.NewConnection(destinationFromListener(t, server)); err != nil {
+		t.Fatalf("Error connecting through proxy: %s", err.Error())
+	}
+
+	resp, err := c.Do(*req)
+	if err != nil {
+		t.Fatalf("Error sending request through proxy: %s", err.Error())
+	}
+
+	if got := <-received; !bytes.Equal(got, raw) {
+		t.Errorf("Request was modified by the proxy: %q", got)
+	}
+
+	if resp.GetBodyAsString() != "Hello, client" {
+		t.Errorf("Unexpected response: %q", resp.RAW)
+	}
+}
+
+func TestHTTPConnectProxy(t *testing.T) {
+	proxy := newConnectProxy(t, "")
+	defer proxy.Close()
+
+	sendThroughProxy(t, "http://"+proxy.Addr().String())
+}
+
+func TestHTTPConnectProxyWithAuth(t *testing.T) {
+	proxy := newConnectProxy(t, "user:secret")
+	defer proxy.Close()
+
+	sendThroughProxy(t, "http://user:secret@"+proxy.Addr().String())
+}
+
+func TestHTTPConnectProxyBadAuth(t *testing.T) {
+	server, _ := newRawServer(t)
+	defer server.Close()
+	proxy := newConnectProxy(t, "user:secret")
+	defer proxy.Close()
+
+	proxyURL, _ := url.Parse("http://user:wrong@" + proxy.Addr().String())
+	c := NewClient()
+	c.Proxy = ProxyURL(proxyURL)
+
+	if err := c.NewConnection(destinationFromListener(t, server)); err == nil {
+		t.Error("Connection should fail with bad proxy credentials")
+	}
+}
+
+func TestSOCKS5Proxy(t *testing.T) {
+	proxy := newSOCKS5Proxy(t, "", "")
+	defer proxy.Close()
+
+	sendThroughProxy(t, "socks5://"+proxy.Addr().String())
+}
+
+func TestSOCKS5ProxyWithAuth(t *testing.T) {
+	proxy := newSOCKS5Proxy(t, "user", "secret")
+	defer proxy.Close()
+
+	sendThroughProxy(t, "socks5://user:secret@"+proxy.Addr().String())
+}
+
+func TestSOCKS5ProxyTimeout(t *testing.T) {
+	// the proxy accepts connections, but never answers the handshake
+	proxy, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.Close()
+	go func() {
+		for {
+			conn, err := proxy.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	proxyURL, _ := url.Parse("socks5://" + proxy.Addr().String())
+	c := NewClient()
+	c.Proxy = ProxyURL(proxyURL)
+	c.Timeout = 200 * time.Millisecond
+
+	done := make(chan error, 1)
+	go func() {
+		done <- c.NewConnection(Destination{DestAddr: "127.0.0.1", Port: 80, Protocol: "http"})
+	}()
+	select {
+	case err = <-done:
+		if err == nil {
+			t.Error("Connection should fail when the proxy does not answer")
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("Connection should time out when the proxy does not answer")
+	}
+}
+
+func TestUnsupportedProxyScheme(t *testing.T) {
+	proxyURL, _ := url.Parse("ftp://127.0.0.1:21")
+	c := NewClient()
+	c.Proxy = ProxyURL(proxyURL)
+
+	if err := c.NewConnection(Destination{DestAddr: "127.0.0.1", Port: 80, Protocol: "http"}); err == nil {
+		t.Error("Unsupported proxy scheme should error")
+	}
+}
+
+func TestProxyFromEnvironment(t *testing.T) {
+	for name, value := range map[string]string{"HTTP_PROXY": "http://proxy.example.com:3128", "NO_PROXY": "internal.example.com"} {
+		previous, found := os.LookupEnv(name)
+		os.Setenv(name, value)
+		if found {
+			defer os.Setenv(name, previous)
+		} else {
+			defer os.Unsetenv(name)
+		}
+	}
+
+	u, err := ProxyFromEnvironment(Destination{DestAddr: "waf.example.com", Port: 80, Protocol: "http"})
+	if err != nil || u == nil || u.Host != "proxy.example.com:3128" {
+		t.Errorf("Expected proxy from environment, got %v", u)
+	}
+
+	u, err = ProxyFromEnvironment(Destination{DestAddr: "internal.example.com", Port: 80, Protocol: "http"})
+	if err != nil || u != nil {
+		t.Errorf("NO_PROXY destination should not use a proxy, got %v", u)
+	}
+
+	u, err = ProxyFromEnvironment(Destination{DestAddr: "localhost", Port: 80, Protocol: "http"})
+	if err != nil || u != nil {
+		t.Errorf("localhost should never use a proxy, got %v", u)
+	}
+}
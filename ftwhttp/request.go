@@ -27,6 +27,10 @@ func NewRequest(reqLine *RequestLine, h Header, data []byte, b bool) *Request {
 		raw:                 nil,
 		autoCompleteHeaders: b,
 	}
+	// tests without headers still get the standard ones
+	if r.headers == nil {
+		r.headers = make(Header)
+	}
 	return r
 }
 
@@ -49,6 +53,41 @@ func (r Request) WithAutoCompleteHeaders() bool {
 	return r.autoCompleteHeaders
 }
 
+// SetTransmission sets how the request is written to the connection. Use nil for writing it at once.
+func (r *Request) SetTransmission(t *Transmission) {
+	r.transmission = t
+}
+
+// Transmission returns the transmission options for the request
+func (r Request) Transmission() *Transmission {
+	return r.transmission
+}
+
+// SetChunkedBody sets a body sent using chunked transfer encoding, instead of data.
+// The Transfer-Encoding header is added unless the request was created without auto complete headers.
+func (r *Request) SetChunkedBody(c *ChunkedBody) {
+	r.chunked = c
+}
+
+// SetMultipartBody sets a multipart/form-data body, instead of data.
+// The Content-Type header is added unless already present, or the request was created
+// without auto complete headers.
+func (r *Request) SetMultipartBody(m *MultipartBody) {
+	r.multipart = m
+}
+
+// SetExactData sets data that is sent byte-exact: it is not url-encoded, and no Content-Type is added
+func (r *Request) SetExactData(data []byte) {
+	r.data = data
+	r.exactData = true
+}
+
+// SetCompression sets the content encoding used for compressing the body. The Content-Encoding header
+// is added unless the request was created without auto complete headers. Chunked bodies are not compressed.
+func (r *Request) SetCompression(encoding string) {
+	r.compression = encoding
+}
+
 // SetData sets the data
 // You can use only one of raw, encoded or data.
 func (r *Request) SetData(data []byte) error {
@@ -126,8 +165,17 @@ func buildRequest(r *Request) ([]byte, error) {
 			return nil, err
 		}
 
+		if r.multipart != nil {
+			if err = buildMultipartBody(r); err != nil {
+				return nil, err
+			}
+		}
+		if r.chunked != nil {
+			buildChunkedBody(r)
+		}
+
 		// We need to add the remaining headers, unless "NoDefaults"
-		if utils.IsNotEmpty(r.data) && r.WithAutoCompleteHeaders() {
+		if !r.hasBodyBuilder() && utils.IsNotEmpty(r.data) && r.WithAutoCompleteHeaders() {
 			// If there is no Content-Type, then we add one
 			r.AddHeader(ContentTypeHeader, "application/x-www-form-urlencoded")
 			data, err = encodeDataParameters(r.headers, r.data)
@@ -143,7 +191,7 @@ func buildRequest(r *Request) ([]byte, error) {
 		}
 
 		// Multipart form data needs to end in \r\n, per RFC (and modsecurity make a scene if not)
-		if ct := r.headers.Value(ContentTypeHeader); strings.HasPrefix(ct, "multipart/form-data;") {
+		if ct := r.headers.Value(ContentTypeHeader); !r.hasBodyBuilder() && strings.HasPrefix(ct, "multipart/form-data;") {
 			crlf := []byte("\r\n")
 			lf := []byte("\n")
 			log.Debug().Msgf("ftw/http: with LF only - %d bytes:\n%x\n", len(r.data), r.data)
@@ -152,7 +200,13 @@ func buildRequest(r *Request) ([]byte, error) {
 			r.data = data
 		}
 
-		if r.WithAutoCompleteHeaders() {
+		if r.compression != "" && r.chunked == nil {
+			if err = compressBody(r); err != nil {
+				return nil, err
+			}
+		}
+
+		if r.chunked == nil && r.WithAutoCompleteHeaders() {
 			r.AddStandardHeaders(len(r.data))
 		}
 
@@ -183,6 +237,52 @@ func buildRequest(r *Request) ([]byte, error) {
 	return b.Bytes(), err
 }
 
+// hasBodyBuilder returns true when the body is built from a chunked or multipart description,
+// or is exact data, so data is sent as it is
+func (r Request) hasBodyBuilder() bool {
+	return r.chunked != nil || r.multipart != nil || r.exactData
+}
+
+// buildMultipartBody uses the multipart body as data
+func buildMultipartBody(r *Request) error {
+	boundary := r.multipart.Boundary
+	if boundary == "" {
+		boundary = randomBoundary()
+	}
+	data, err := r.multipart.Bytes(boundary)
+	if err != nil {
+		return err
+	}
+	r.data = data
+	if r.WithAutoCompleteHeaders() {
+		r.AddHeader(ContentTypeHeader, r.multipart.ContentType(boundary))
+	}
+	return nil
+}
+
+// compressBody compresses the data, once it has its final form
+func compressBody(r *Request) error {
+	data, err := Compress(r.compression, r.data)
+	if err != nil {
+		return err
+	}
+	r.data = data
+	if r.WithAutoCompleteHeaders() {
+		r.AddHeader(ContentEncodingHeader, r.compression)
+	}
+	return nil
+}
+
+// buildChunkedBody uses the chunked body as data. The body length is given by the chunks,
+// so there is no Content-Length.
+func buildChunkedBody(r *Request) {
+	r.data = r.chunked.Bytes()
+	if r.WithAutoCompleteHeaders() {
+		r.AddHeader(TransferEncodingHeader, "chunked")
+		r.AddStandardHeaders(0)
+	}
+}
+
 // If the values are empty in the map, then don't encode anythin
 // This keeps the compatibility with the python implementation
 func emptyQueryValues(values url.Values) bool {
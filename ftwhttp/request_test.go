@@ -266,3 +266,43 @@ func TestRequestURLParseFail(t *testing.T) {
 		t.Errorf("Failed !")
 	}
 }
+
+func TestRequestExactData(t *testing.T) {
+	rl := &RequestLine{
+		Method:  "POST",
+		URI:     "/",
+		Version: "HTTP/1.1",
+	}
+	body := []byte("a=1 2\x00\xff\n")
+	req := NewRequest(rl, Header{"Host": "localhost", "Content-Type": "multipart/form-data; boundary=x"}, nil, true)
+	req.SetExactData(body)
+
+	data, err := buildRequest(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.HasSuffix(data, append([]byte("\r\n\r\n"), body...)) {
+		t.Errorf("Failed ! body was modified: %q", data)
+	}
+	if req.Headers().Get("Content-Length") != "8" {
+		t.Errorf("Failed ! got Content-Length %q", req.Headers().Get("Content-Length"))
+	}
+}
+
+func TestRequestExactDataNoContentType(t *testing.T) {
+	rl := &RequestLine{
+		Method:  "POST",
+		URI:     "/",
+		Version: "HTTP/1.1",
+	}
+	req := NewRequest(rl, Header{"Host": "localhost"}, nil, true)
+	req.SetExactData([]byte("a=1 2"))
+
+	if _, err := buildRequest(req); err != nil {
+		t.Fatal(err)
+	}
+	if req.Headers().Get(ContentTypeHeader) != "" {
+		t.Errorf("Failed ! Content-Type was added")
+	}
+}
@@ -0,0 +1,68 @@
+package ftwhttp
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// Resolve maps a `host:port` or a `host` to the address used for connecting to it,
+// like the --resolve option in curl. The address can have a port, like `10.0.0.1:8080` or `[::1]:8080`.
+type Resolve map[string]string
+
+// ParseResolve reads entries in the curl format `host:port:address`, e.g. `app.example.com:443:127.0.0.1`
+// or `app.example.com:443:[::1]`. Use `*` as port for matching any port.
+func ParseResolve(entries []string) (Resolve, error) {
+	r := make(Resolve)
+	for _, entry := range entries {
+		parts := strings.SplitN(entry, ":", 3)
+		if len(parts) != 3 || parts[0] == "" || parts[2] == "" {
+			return nil, fmt.Errorf("ftw/http: bad resolve entry %q, use host:port:address", entry)
+		}
+		host, port, address := parts[0], parts[1], trimBrackets(parts[2])
+		if port == "*" {
+			r[strings.ToLower(host)] = address
+			continue
+		}
+		if _, err := strconv.Atoi(port); err != nil {
+			return nil, fmt.Errorf("ftw/http: bad port in resolve entry %q", entry)
+		}
+		r[net.JoinHostPort(strings.ToLower(host), port)] = address
+	}
+	return r, nil
+}
+
+// Address returns the address used for connecting to host and port
+func (r Resolve) Address(host string, port int) string {
+	host = strings.ToLower(trimBrackets(host))
+	hostPort := net.JoinHostPort(host, strconv.Itoa(port))
+	address, ok := r[hostPort]
+	if !ok {
+		address, ok = r[host]
+	}
+	if !ok {
+		return hostPort
+	}
+	if _, _, err := net.SplitHostPort(address); err == nil {
+		return address
+	}
+	return net.JoinHostPort(trimBrackets(address), strconv.Itoa(port))
+}
+
+// serverName returns the name used for SNI and for verifying the certificate.
+// Go does not send SNI for IP addresses, but still verifies them.
+func (d Destination) serverName() string {
+	if d.ServerName != "" {
+		return d.ServerName
+	}
+	return trimBrackets(d.DestAddr)
+}
+
+// trimBrackets removes the brackets from IPv6 literals like `[::1]`
+func trimBrackets(host string) string {
+	if strings.HasPrefix(host, "[") && strings.HasSuffix(host, "]") {
+		return host[1 : len(host)-1]
+	}
+	return host
+}
@@ -0,0 +1,63 @@
+package ftwhttp
+
+import (
+	"net"
+	"strconv"
+	"testing"
+)
+
+func TestParseResolve(t *testing.T) {
+	r, err := ParseResolve([]string{"App.example.com:443:127.0.0.1", "v6.example.com:80:[::1]", "any.example.com:*:10.0.0.1:8080"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		host     string
+		port     int
+		expected string
+	}{
+		{"app.example.com", 443, "127.0.0.1:443"},
+		{"app.example.com", 80, "app.example.com:80"},
+		{"v6.example.com", 80, "[::1]:80"},
+		{"any.example.com", 9000, "10.0.0.1:8080"},
+		{"[::1]", 80, "[::1]:80"},
+		{"::1", 80, "[::1]:80"},
+	}
+	for _, tt := range tests {
+		if address := r.Address(tt.host, tt.port); address != tt.expected {
+			t.Errorf("Failed ! %s:%d resolved to %s", tt.host, tt.port, address)
+		}
+	}
+
+	for _, bad := range []string{"app.example.com", "app.example.com:443:", "app.example.com:https:127.0.0.1", ":443:127.0.0.1"} {
+		if _, err = ParseResolve([]string{bad}); err == nil {
+			t.Errorf("Failed ! %q should not be valid", bad)
+		}
+	}
+}
+
+func TestConnectResolved(t *testing.T) {
+	for _, network := range []string{"127.0.0.1:0", "[::1]:0"} {
+		listener, err := net.Listen("tcp", network)
+		if err != nil {
+			t.Logf("skipping %s: %s", network, err.Error())
+			continue
+		}
+		host, port, _ := net.SplitHostPort(listener.Addr().String())
+
+		c := NewClient()
+		c.Proxy = nil
+		c.Resolve, _ = ParseResolve([]string{"app.example.com:" + port + ":" + host})
+
+		p, _ := strconv.Atoi(port)
+		if err = c.NewConnection(Destination{DestAddr: "app.example.com", Port: p, Protocol: "http", Network: TCPNetwork}); err != nil {
+			t.Errorf("Failed ! resolved connection to %s: %s", host, err.Error())
+		}
+		// IPv6 literals work with or without brackets
+		if err = c.NewConnection(Destination{DestAddr: "[" + host + "]", Port: p, Protocol: "http", Network: TCPNetwork}); err != nil {
+			t.Errorf("Failed ! connection to [%s]: %s", host, err.Error())
+		}
+		listener.Close()
+	}
+}
@@ -2,13 +2,24 @@ package ftwhttp
 
 import (
 	"io"
+
+	"github.com/rs/zerolog/log"
 )
 
-// GetBodyAsString gives the response body as string, or nil if there was some error
+// GetBodyAsString gives the response body as string, or nil if there was some error.
+// Compressed bodies are decoded using the Content-Encoding header; the bytes received are still in RAW.
 func (r *Response) GetBodyAsString() string {
 	body, err := io.ReadAll(r.Parsed.Body)
 	if err != nil {
 		return ""
 	}
+	if encoding := r.Parsed.Header.Get(ContentEncodingHeader); encoding != "" {
+		decoded, err := Decompress(encoding, body)
+		if err != nil {
+			log.Debug().Msgf("ftw/http: cannot decode %s response body, using it as received: %s", encoding, err.Error())
+			return string(body)
+		}
+		return string(decoded)
+	}
 	return string(body)
 }
@@ -1,9 +1,11 @@
 package ftwhttp
 
 import (
+	"bytes"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 )
@@ -143,3 +145,34 @@ func TestResponseWithCookies(t *testing.T) {
 		t.Logf("Failed !")
 	}
 }
+
+func TestResponseCompressed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := Compress(GzipEncoding, bytes.Repeat([]byte("Hello, compressed client"), 10))
+		w.Header().Set(ContentEncodingHeader, GzipEncoding)
+		_, _ = w.Write(body)
+	}))
+	defer server.Close()
+
+	d, err := DestinationFromString(server.URL)
+	if err != nil {
+		t.Fatalf("Error! %s", err.Error())
+	}
+
+	client := NewClient()
+	if err = client.NewConnection(*d); err != nil {
+		t.Fatalf("Error! %s", err.Error())
+	}
+
+	response, err := client.Do(*generateRequestForTesting(false))
+	if err != nil {
+		t.Fatalf("Error! %s", err.Error())
+	}
+
+	if response.GetBodyAsString() != strings.Repeat("Hello, compressed client", 10) {
+		t.Errorf("Error!")
+	}
+	if bytes.Contains(response.RAW, []byte("Hello")) {
+		t.Errorf("Error! RAW should keep the compressed body")
+	}
+}
@@ -1,6 +1,9 @@
 package ftwhttp
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
 
 // NewRoundTripTime initializes a roundtriptime struct
 func NewRoundTripTime() *RoundTripTime {
@@ -19,8 +22,7 @@ func (rtt *RoundTripTime) StartTracking() {
 
 // StopTracking sets the finish time to Now
 func (rtt *RoundTripTime) StopTracking() {
-	now := time.Now()
-	rtt.end = now.Add(50 * time.Millisecond)
+	rtt.end = time.Now()
 }
 
 // StartTime returns the time when this round trip started
@@ -37,3 +39,35 @@ func (rtt *RoundTripTime) StopTime() time.Time {
 func (rtt *RoundTripTime) RoundTripDuration() time.Duration {
 	return rtt.end.Sub(rtt.begin)
 }
+
+// ConnectDuration gives the time spent resolving the name and connecting, including the proxy if any
+func (rtt *RoundTripTime) ConnectDuration() time.Duration {
+	return rtt.connect
+}
+
+// TLSHandshakeDuration gives the time spent in the TLS handshake, zero when not using TLS
+func (rtt *RoundTripTime) TLSHandshakeDuration() time.Duration {
+	return rtt.tlsHandshake
+}
+
+// WriteDuration gives the time spent writing the request
+func (rtt *RoundTripTime) WriteDuration() time.Duration {
+	return rtt.write
+}
+
+// TimeToFirstByte gives the time since the request was written until the first byte of the response arrived.
+// This is the best approximation to the time the WAF spent processing the request.
+func (rtt *RoundTripTime) TimeToFirstByte() time.Duration {
+	return rtt.firstByte
+}
+
+// ReadDuration gives the time since the first byte of the response arrived until the last one did
+func (rtt *RoundTripTime) ReadDuration() time.Duration {
+	return rtt.read
+}
+
+// Phases returns all the phase durations in a readable way
+func (rtt *RoundTripTime) Phases() string {
+	return fmt.Sprintf("connect %s, tls %s, write %s, ttfb %s, read %s",
+		rtt.connect, rtt.tlsHandshake, rtt.write, rtt.firstByte, rtt.read)
+}
@@ -0,0 +1,60 @@
+package ftwhttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRoundTripTimeNoMargin(t *testing.T) {
+	rtt := NewRoundTripTime()
+	rtt.StartTracking()
+	rtt.StopTracking()
+
+	if rtt.RoundTripDuration() >= 50*time.Millisecond {
+		t.Errorf("Duration should not include any margin, got %s", rtt.RoundTripDuration())
+	}
+}
+
+func TestRoundTripTimePhases(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+		_, _ = w.Write([]byte("Hello, client"))
+	}))
+	defer server.Close()
+
+	d, err := DestinationFromString(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := NewClient()
+	if err = c.NewConnection(*d); err != nil {
+		t.Fatal(err)
+	}
+
+	c.StartTrackingTime()
+	if _, err = c.Do(*generateRequestForLocalTesting()); err != nil {
+		t.Fatal(err)
+	}
+	c.StopTrackingTime()
+
+	rtt := c.GetRoundTripTime()
+	if rtt.ConnectDuration() <= 0 {
+		t.Errorf("Connect duration not tracked: %s", rtt.Phases())
+	}
+	if rtt.TLSHandshakeDuration() != 0 {
+		t.Errorf("There should be no TLS handshake: %s", rtt.Phases())
+	}
+	if rtt.TimeToFirstByte() < 20*time.Millisecond {
+		t.Errorf("Time to first byte should include the server processing time: %s", rtt.Phases())
+	}
+	if rtt.RoundTripDuration() < rtt.WriteDuration()+rtt.TimeToFirstByte()+rtt.ReadDuration() {
+		t.Errorf("Round trip %s should include all phases: %s", rtt.RoundTripDuration(), rtt.Phases())
+	}
+	if !strings.HasPrefix(rtt.Phases(), "connect ") {
+		t.Errorf("Unexpected phases text %s", rtt.Phases())
+	}
+}
@@ -0,0 +1,107 @@
+package ftwhttp
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Transmission controls how the request bytes are written to the connection.
+// Without it, the whole request is written at once.
+type Transmission struct {
+	// SplitAt are the offsets in the request where it is split, in ascending order
+	SplitAt []int `yaml:"split_at,flow,omitempty"`
+	// ChunkSize splits the request (or each part from SplitAt) in chunks of this size
+	ChunkSize int `yaml:"chunk_size,omitempty"`
+	// DelayMS is the time to wait between writes, in milliseconds
+	DelayMS int `yaml:"delay_ms,omitempty"`
+	// NoDelay sets TCP_NODELAY in the connection. Go sets it by default, so use `false`
+	// for letting the kernel coalesce writes.
+	NoDelay *bool `yaml:"nodelay,omitempty"`
+}
+
+// Validate checks the transmission options make sense
+func (t *Transmission) Validate() error {
+	if t.ChunkSize < 0 {
+		return fmt.Errorf("ftw/http: chunk_size must be positive, got %d", t.ChunkSize)
+	}
+	if t.DelayMS < 0 {
+		return fmt.Errorf("ftw/http: delay_ms must be positive, got %d", t.DelayMS)
+	}
+	last := 0
+	for _, offset := range t.SplitAt {
+		if offset <= last {
+			return errors.New("ftw/http: split_at offsets must be positive and in ascending order")
+		}
+		last = offset
+	}
+	return nil
+}
+
+// Delay returns the time to wait between writes
+func (t *Transmission) Delay() time.Duration {
+	return time.Duration(t.DelayMS) * time.Millisecond
+}
+
+// Split returns the pieces of data to write, one per write. Offsets past the end of data are ignored.
+func (t *Transmission) Split(data []byte) [][]byte {
+	var parts [][]byte
+	start := 0
+	for _, offset := range t.SplitAt {
+		if offset >= len(data) {
+			break
+		}
+		parts = append(parts, data[start:offset])
+		start = offset
+	}
+	parts = append(parts, data[start:])
+
+	if t.ChunkSize <= 0 {
+		return parts
+	}
+
+	var chunks [][]byte
+	for _, part := range parts {
+		for len(part) > t.ChunkSize {
+			chunks = append(chunks, part[:t.ChunkSize])
+			part = part[t.ChunkSize:]
+		}
+		chunks = append(chunks, part)
+	}
+	return chunks
+}
+
+// noDelaySetter is implemented by connections supporting TCP_NODELAY, like *net.TCPConn
+type noDelaySetter interface {
+	SetNoDelay(noDelay bool) error
+}
+
+// transmit writes data to conn using the transmission options
+func (t *Transmission) transmit(conn net.Conn, transport net.Conn, data []byte) (int, error) {
+	if t.NoDelay != nil {
+		if setter, ok := transport.(noDelaySetter); ok {
+			if err := setter.SetNoDelay(*t.NoDelay); err != nil {
+				return 0, err
+			}
+		} else {
+			log.Info().Msgf("ftw/http: nodelay is not supported by this connection, ignoring it")
+		}
+	}
+
+	sent := 0
+	for i, part := range t.Split(data) {
+		if i > 0 && t.DelayMS > 0 {
+			time.Sleep(t.Delay())
+		}
+		log.Trace().Msgf("ftw/http: sending part %d - %q", i, part)
+		n, err := conn.Write(part)
+		sent += n
+		if err != nil {
+			return sent, err
+		}
+	}
+	return sent, nil
+}
@@ -0,0 +1,108 @@
+package ftwhttp
+
+import (
+	"bytes"
+	"net"
+	"reflect"
+	"testing"
+)
+
+var splitTests = []struct {
+	transmission Transmission
+	expected     []string
+}{
+	{Transmission{}, []string{"GET / HTTP/1.1"}},
+	{Transmission{ChunkSize: 5}, []string{"GET /", " HTTP", "/1.1"}},
+	{Transmission{SplitAt: []int{3, 6}}, []string{"GET", " / ", "HTTP/1.1"}},
+	{Transmission{SplitAt: []int{6}, ChunkSize: 4}, []string{"GET ", "/ ", "HTTP", "/1.1"}},
+	{Transmission{SplitAt: []int{3, 100}}, []string{"GET", " / HTTP/1.1"}},
+}
+
+func TestTransmissionSplit(t *testing.T) {
+	for _, s := range splitTests {
+		var got []string
+		for _, part := range s.transmission.Split([]byte("GET / HTTP/1.1")) {
+			got = append(got, string(part))
+		}
+		if !reflect.DeepEqual(got, s.expected) {
+			t.Errorf("Failed ! got %q, expected %q", got, s.expected)
+		}
+	}
+}
+
+func TestTransmissionValidate(t *testing.T) {
+	bad := []Transmission{
+		{ChunkSize: -1},
+		{DelayMS: -1},
+		{SplitAt: []int{5, 3}},
+		{SplitAt: []int{0}},
+	}
+	for _, b := range bad {
+		b := b
+		if b.Validate() == nil {
+			t.Errorf("Failed ! %+v should not be valid", b)
+		}
+	}
+
+	good := Transmission{SplitAt: []int{3, 5}, ChunkSize: 1, DelayMS: 10}
+	if err := good.Validate(); err != nil {
+		t.Errorf("Failed ! %s", err.Error())
+	}
+}
+
+func TestSendWithTransmission(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	reads := make(chan [][]byte, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		var received [][]byte
+		var all []byte
+		buf := make([]byte, 4096)
+		for !bytes.HasSuffix(all, []byte("\r\n\r\n")) {
+			n, err := conn.Read(buf)
+			if err != nil {
+				break
+			}
+			received = append(received, append([]byte(nil), buf[:n]...))
+			all = append(all, buf[:n]...)
+		}
+		_, _ = conn.Write([]byte("HTTP/1.1 200 OK\r\nContent-Length: 0\r\nConnection: close\r\n\r\n"))
+		reads <- received
+	}()
+
+	c := NewClient()
+	if err = c.NewConnection(destinationFromListener(t, listener)); err != nil {
+		t.Fatal(err)
+	}
+
+	raw := []byte("GET / HTTP/1.1\r\nHost: localhost\r\n\r\n")
+	req := NewRawRequest(raw, false)
+	noDelay := true
+	req.SetTransmission(&Transmission{SplitAt: []int{10, 20}, DelayMS: 50, NoDelay: &noDelay})
+
+	resp, err := c.Do(*req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Parsed.StatusCode != 200 {
+		t.Errorf("Failed ! got status %d", resp.Parsed.StatusCode)
+	}
+
+	received := <-reads
+	if !bytes.Equal(bytes.Join(received, nil), raw) {
+		t.Errorf("Failed ! request was modified: %q", received)
+	}
+	if len(received) != 3 {
+		t.Errorf("Failed ! expected 3 reads, got %q", received)
+	}
+}
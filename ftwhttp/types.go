@@ -6,24 +6,52 @@ import (
 	"time"
 )
 
+const (
+	// TCPNetwork is the network used by default for connecting to destinations
+	TCPNetwork string = "tcp"
+	// UnixNetwork is the network used for connecting to unix domain sockets
+	UnixNetwork string = "unix"
+)
+
 // Client is the top level abstraction in http
 type Client struct {
 	Transport *Connection
 	Jar       http.CookieJar
 	Timeout   time.Duration
+	// Resolve overrides the address used for connecting to destinations, like curl --resolve
+	Resolve Resolve
+	// Proxy selects the proxy used for each destination. Direct connections are used when nil.
+	Proxy ProxyFunc
+	// Dial, when set, is used instead of the net package for opening connections.
+	// It allows using in-memory connections like net.Pipe, or listeners in your own test suites.
+	Dial DialFunc
+	// Capture, when set, records the traffic of all connections in a pcapng file
+	Capture *Capture
+	// CaptureComment is added to the first packet of the next connections in the capture, e.g. the test title
+	CaptureComment string
 }
 
+// DialFunc opens a connection to address on the named network, with the same semantics as net.Dial
+type DialFunc func(network string, address string) (net.Conn, error)
+
 // Connection is the type used for sending/receiving data
 type Connection struct {
 	connection net.Conn
-	protocol   string
-	duration   *RoundTripTime
+	// transport is the connection below TLS, or the same connection when not using TLS
+	transport net.Conn
+	protocol  string
+	duration  *RoundTripTime
 }
 
-// RoundTripTime abstracts the time a transaction takes
+// RoundTripTime abstracts the time a transaction takes, and the time spent in each phase
 type RoundTripTime struct {
-	begin time.Time
-	end   time.Time
+	begin        time.Time
+	end          time.Time
+	connect      time.Duration
+	tlsHandshake time.Duration
+	write        time.Duration
+	firstByte    time.Duration
+	read         time.Duration
 }
 
 // FTWConnection is the interface method implement to send and receive data
@@ -36,10 +64,17 @@ type FTWConnection interface {
 }
 
 // Destination is the host, port and protocol to be used when connecting to a remote host
+// When Network is "unix", DestAddr is the path to the socket and Port is not used.
+// IPv6 addresses can be written with or without brackets.
 type Destination struct {
 	DestAddr string `default:"localhost"`
 	Port     int    `default:"80"`
 	Protocol string `default:"http"`
+	Network  string `default:"tcp"`
+	// NextProtos are the protocols offered using ALPN in TLS connections, like `h2`
+	NextProtos []string
+	// ServerName, when set, is sent using SNI instead of DestAddr
+	ServerName string
 }
 
 // RequestLine is the first line in the HTTP request dialog
@@ -58,10 +93,17 @@ type Request struct {
 	data                []byte
 	raw                 []byte
 	autoCompleteHeaders bool
+	transmission        *Transmission
+	chunked             *ChunkedBody
+	multipart           *MultipartBody
+	exactData           bool
+	compression         string
 }
 
 // Response represents the http response received from the server/waf
 type Response struct {
 	RAW    []byte
 	Parsed http.Response
+	// WebSocket is set when the request was a websocket handshake
+	WebSocket *WebSocketResult
 }
@@ -0,0 +1,368 @@
+package ftwhttp
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// websocketGUID is used for computing Sec-WebSocket-Accept, see RFC 6455
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// WebSocket opcodes, see RFC 6455
+const (
+	ContinuationFrame byte = 0x0
+	TextFrame         byte = 0x1
+	BinaryFrame       byte = 0x2
+	CloseFrame        byte = 0x8
+	PingFrame         byte = 0x9
+	PongFrame         byte = 0xa
+)
+
+// Mask values for WebSocketFrame
+const (
+	// NoMask sends the frame unmasked, which servers must reject
+	NoMask = "none"
+	// WrongMask announces a masking key, but masks the payload with a different one
+	WrongMask = "wrong"
+)
+
+var frameTypes = map[string]byte{
+	"continuation": ContinuationFrame,
+	"text":         TextFrame,
+	"binary":       BinaryFrame,
+	"close":        CloseFrame,
+	"ping":         PingFrame,
+	"pong":         PongFrame,
+}
+
+// WebSocket has the frames sent after the handshake
+type WebSocket struct {
+	Frames []WebSocketFrame `yaml:"frames"`
+}
+
+// WebSocketFrame describes a frame sent to the server
+type WebSocketFrame struct {
+	// Type is one of text (the default), binary, ping, pong, close or continuation
+	Type string `yaml:"type,omitempty"`
+	// Use only one of Data or DataBase64
+	Data       string `yaml:"data,omitempty"`
+	DataBase64 string `yaml:"data_base64,omitempty"`
+	// Fragments splits the payload in this number of frames
+	Fragments int `yaml:"fragments,omitempty"`
+	// Mask can be `none` or `wrong` for sending frames masked incorrectly
+	Mask string `yaml:"mask,omitempty"`
+}
+
+// WebSocketMessage is a message received from the server. Fragmented messages are joined.
+type WebSocketMessage struct {
+	Opcode byte
+	Data   []byte
+}
+
+// WebSocketResult has what happened after the handshake
+type WebSocketResult struct {
+	// Upgraded is true when the server switched protocols
+	Upgraded bool
+	// Messages are the messages received from the server
+	Messages []WebSocketMessage
+	// Closed is true when the server closed the connection, or sent a close frame
+	Closed bool
+}
+
+// Validate checks the frames can be sent
+func (w *WebSocket) Validate() error {
+	for i, f := range w.Frames {
+		if _, ok := frameTypes[f.frameType()]; !ok {
+			return fmt.Errorf("ftw/http: websocket frame %d: unknown type %q", i+1, f.Type)
+		}
+		if f.Data != "" && f.DataBase64 != "" {
+			return fmt.Errorf("ftw/http: websocket frame %d: choose between data or data_base64", i+1)
+		}
+		if _, err := f.payload(); err != nil {
+			return fmt.Errorf("ftw/http: websocket frame %d: bad data_base64: %w", i+1, err)
+		}
+		if f.Fragments < 0 {
+			return fmt.Errorf("ftw/http: websocket frame %d: fragments must be positive", i+1)
+		}
+		if f.Mask != "" && f.Mask != NoMask && f.Mask != WrongMask {
+			return fmt.Errorf("ftw/http: websocket frame %d: mask must be %q or %q", i+1, NoMask, WrongMask)
+		}
+	}
+	return nil
+}
+
+func (f WebSocketFrame) frameType() string {
+	if f.Type == "" {
+		return "text"
+	}
+	return f.Type
+}
+
+func (f WebSocketFrame) payload() ([]byte, error) {
+	if f.DataBase64 != "" {
+		return base64.StdEncoding.DecodeString(f.DataBase64)
+	}
+	return []byte(f.Data), nil
+}
+
+// Bytes serializes the frame, as sent in the wire. Fragmented frames return many frames.
+func (f WebSocketFrame) Bytes() ([]byte, error) {
+	opcode, ok := frameTypes[f.frameType()]
+	if !ok {
+		return nil, fmt.Errorf("ftw/http: unknown websocket frame type %q", f.Type)
+	}
+	payload, err := f.payload()
+	if err != nil {
+		return nil, err
+	}
+
+	fragments := f.Fragments
+	if fragments < 1 {
+		fragments = 1
+	}
+	size := (len(payload) + fragments - 1) / fragments
+
+	var b bytes.Buffer
+	for i := 0; i < fragments; i++ {
+		start := i * size
+		end := start + size
+		if start > len(payload) {
+			start = len(payload)
+		}
+		if end > len(payload) {
+			end = len(payload)
+		}
+		if i > 0 {
+			opcode = ContinuationFrame
+		}
+		if err = writeFrame(&b, i == fragments-1, opcode, payload[start:end], f.Mask); err != nil {
+			return nil, err
+		}
+	}
+	return b.Bytes(), nil
+}
+
+// writeFrame writes one frame. Clients must mask frames, unless mask is NoMask.
+func writeFrame(b *bytes.Buffer, fin bool, opcode byte, payload []byte, mask string) error {
+	first := opcode
+	if fin {
+		first |= 0x80
+	}
+	b.WriteByte(first)
+
+	var maskBit byte
+	if mask != NoMask {
+		maskBit = 0x80
+	}
+	switch {
+	case len(payload) < 126:
+		b.WriteByte(maskBit | byte(len(payload)))
+	case len(payload) <= 0xffff:
+		b.WriteByte(maskBit | 126)
+		_ = binary.Write(b, binary.BigEndian, uint16(len(payload)))
+	default:
+		b.WriteByte(maskBit | 127)
+		_ = binary.Write(b, binary.BigEndian, uint64(len(payload)))
+	}
+
+	if mask == NoMask {
+		b.Write(payload)
+		return nil
+	}
+
+	var key [4]byte
+	if _, err := rand.Read(key[:]); err != nil {
+		return err
+	}
+	b.Write(key[:])
+	if mask == WrongMask {
+		for i := range key {
+			key[i] = ^key[i]
+		}
+	}
+	for i, c := range payload {
+		b.WriteByte(c ^ key[i%4])
+	}
+	return nil
+}
+
+// readFrame reads one frame from the server
+func readFrame(r *bufio.Reader) (bool, byte, []byte, error) {
+	var header [2]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return false, 0, nil, err
+	}
+	fin := header[0]&0x80 != 0
+	opcode := header[0] & 0x0f
+	masked := header[1]&0x80 != 0
+	length := uint64(header[1] & 0x7f)
+
+	switch length {
+	case 126:
+		var l uint16
+		if err := binary.Read(r, binary.BigEndian, &l); err != nil {
+			return false, 0, nil, err
+		}
+		length = uint64(l)
+	case 127:
+		if err := binary.Read(r, binary.BigEndian, &length); err != nil {
+			return false, 0, nil, err
+		}
+	}
+
+	var key [4]byte
+	if masked {
+		if _, err := io.ReadFull(r, key[:]); err != nil {
+			return false, 0, nil, err
+		}
+	}
+
+	// we keep responses in memory, so don't trust huge lengths
+	if length > 1<<24 {
+		return false, 0, nil, fmt.Errorf("ftw/http: websocket frame too big: %d bytes", length)
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return false, 0, nil, err
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= key[i%4]
+		}
+	}
+	return fin, opcode, payload, nil
+}
+
+// addWebSocketHeaders adds the headers needed for the handshake
+func addWebSocketHeaders(r *Request) (string, error) {
+	var key [16]byte
+	if _, err := rand.Read(key[:]); err != nil {
+		return "", err
+	}
+	r.AddHeader("Upgrade", "websocket")
+	r.AddHeader("Connection", "Upgrade")
+	r.AddHeader("Sec-WebSocket-Version", "13")
+	r.AddHeader("Sec-WebSocket-Key", base64.StdEncoding.EncodeToString(key[:]))
+	return r.headers.Get("Sec-WebSocket-Key"), nil
+}
+
+// websocketAccept computes the Sec-WebSocket-Accept value for the key
+func websocketAccept(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + websocketGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// WebSocket sends the handshake request. If the server switches protocols, the frames are sent, and
+// frames are read until the server closes the connection, or nothing is received before the deadline.
+// When the handshake is refused, the response is returned as usual.
+func (c *Connection) WebSocket(request *Request, ws *WebSocket) (*Response, error) {
+	if c.connection == nil {
+		return nil, errors.New("ftw/http/websocket: not connected to server")
+	}
+
+	var key string
+	var err error
+	if !request.isRaw() && request.WithAutoCompleteHeaders() {
+		if key, err = addWebSocketHeaders(request); err != nil {
+			return nil, err
+		}
+	}
+	if err = c.Request(request); err != nil {
+		return nil, err
+	}
+
+	timeoutDuration := 1000 * time.Millisecond
+	var raw bytes.Buffer
+	reader := bufio.NewReader(io.TeeReader(c.connection, &raw))
+
+	if err = c.connection.SetReadDeadline(time.Now().Add(timeoutDuration)); err != nil {
+		return nil, classifyTransportError(err)
+	}
+	begin := time.Now()
+	httpResponse, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		return nil, classifyResponseError(err)
+	}
+	c.duration.firstByte = time.Since(begin)
+
+	result := &WebSocketResult{}
+	if httpResponse.StatusCode != http.StatusSwitchingProtocols {
+		// the handshake was refused, keep the response body like a normal response
+		body, _ := io.ReadAll(httpResponse.Body)
+		httpResponse.Body = io.NopCloser(bytes.NewReader(body))
+		return &Response{RAW: raw.Bytes(), Parsed: *httpResponse, WebSocket: result}, nil
+	}
+
+	result.Upgraded = true
+	if key != "" && httpResponse.Header.Get("Sec-WebSocket-Accept") != websocketAccept(key) {
+		log.Debug().Msgf("ftw/http/websocket: unexpected Sec-WebSocket-Accept %q", httpResponse.Header.Get("Sec-WebSocket-Accept"))
+	}
+	handshake := append([]byte(nil), raw.Bytes()[:raw.Len()-reader.Buffered()]...)
+
+	for _, frame := range ws.Frames {
+		data, err := frame.Bytes()
+		if err != nil {
+			return nil, err
+		}
+		log.Trace().Msgf("ftw/http/websocket: sending frame %q", data)
+		if _, err = c.connection.Write(data); err != nil {
+			// the server might close the connection while we are still sending
+			log.Debug().Msgf("ftw/http/websocket: error sending frame: %s", err.Error())
+			result.Closed = true
+			break
+		}
+	}
+
+	if !result.Closed {
+		c.readWebSocketMessages(reader, result, timeoutDuration)
+	}
+
+	return &Response{RAW: handshake, Parsed: *httpResponse, WebSocket: result}, nil
+}
+
+// readWebSocketMessages reads frames until the server closes the connection, or stops sending
+func (c *Connection) readWebSocketMessages(reader *bufio.Reader, result *WebSocketResult, timeout time.Duration) {
+	continuing := false
+	for {
+		if err := c.connection.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+			return
+		}
+		fin, opcode, payload, err := readFrame(reader)
+		if err != nil {
+			var netErr net.Error
+			if !errors.As(err, &netErr) || !netErr.Timeout() {
+				log.Debug().Msgf("ftw/http/websocket: connection closed: %s", err.Error())
+				result.Closed = true
+			}
+			return
+		}
+		log.Trace().Msgf("ftw/http/websocket: received frame %x - %q", opcode, payload)
+
+		if opcode == ContinuationFrame && continuing && len(result.Messages) > 0 {
+			last := &result.Messages[len(result.Messages)-1]
+			last.Data = append(last.Data, payload...)
+		} else {
+			result.Messages = append(result.Messages, WebSocketMessage{Opcode: opcode, Data: payload})
+		}
+		if opcode < CloseFrame {
+			continuing = !fin
+		}
+		if opcode == CloseFrame {
+			result.Closed = true
+			return
+		}
+	}
+}
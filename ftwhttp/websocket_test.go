@@ -0,0 +1,181 @@
+package ftwhttp
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"net/http"
+	"testing"
+)
+
+func TestWebSocketFrameBytes(t *testing.T) {
+	data, err := WebSocketFrame{Data: "hi", Mask: NoMask}.Bytes()
+	if err != nil || !bytes.Equal(data, []byte{0x81, 0x02, 'h', 'i'}) {
+		t.Errorf("Failed ! got %x, %v", data, err)
+	}
+
+	data, err = WebSocketFrame{Type: "binary", Data: "abcde", Fragments: 3, Mask: NoMask}.Bytes()
+	expected := []byte{0x02, 0x02, 'a', 'b', 0x00, 0x02, 'c', 'd', 0x80, 0x01, 'e'}
+	if err != nil || !bytes.Equal(data, expected) {
+		t.Errorf("Failed ! got %x, %v", data, err)
+	}
+}
+
+func TestWebSocketFrameMask(t *testing.T) {
+	payload := string(bytes.Repeat([]byte("a"), 300))
+
+	data, err := WebSocketFrame{Data: payload}.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fin, opcode, got, err := readFrame(bufio.NewReader(bytes.NewReader(data)))
+	if err != nil || !fin || opcode != TextFrame || string(got) != payload {
+		t.Errorf("Failed ! got %v %x %q, %v", fin, opcode, got, err)
+	}
+
+	data, err = WebSocketFrame{Data: payload, Mask: WrongMask}.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, got, err = readFrame(bufio.NewReader(bytes.NewReader(data)))
+	if err != nil || string(got) == payload {
+		t.Errorf("Failed ! payload should be masked with the wrong key")
+	}
+}
+
+func TestWebSocketValidate(t *testing.T) {
+	bad := []WebSocket{
+		{Frames: []WebSocketFrame{{Type: "text2"}}},
+		{Frames: []WebSocketFrame{{Data: "a", DataBase64: "YQ=="}}},
+		{Frames: []WebSocketFrame{{DataBase64: "%%%"}}},
+		{Frames: []WebSocketFrame{{Mask: "bad"}}},
+		{Frames: []WebSocketFrame{{Fragments: -1}}},
+	}
+	for _, w := range bad {
+		w := w
+		if w.Validate() == nil {
+			t.Errorf("Failed ! %+v should not be valid", w)
+		}
+	}
+}
+
+// newWebSocketServer accepts one connection, and behaves like a WAF in front of an echo server:
+// unmasked frames and frames containing "attack" close the connection.
+// When refuse is true, the handshake is refused.
+func newWebSocketServer(t *testing.T, refuse bool) net.Listener {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		reader := bufio.NewReader(conn)
+		req, err := http.ReadRequest(reader)
+		if err != nil {
+			return
+		}
+		if refuse {
+			_, _ = conn.Write([]byte("HTTP/1.1 403 Forbidden\r\nContent-Length: 7\r\nConnection: close\r\n\r\nblocked"))
+			return
+		}
+		accept := websocketAccept(req.Header.Get("Sec-WebSocket-Key"))
+		_, _ = conn.Write([]byte("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: " + accept + "\r\n\r\n"))
+
+		var message []byte
+		for {
+			peek, err := reader.Peek(2)
+			if err != nil {
+				return
+			}
+			if peek[1]&0x80 == 0 {
+				// unmasked frames are a protocol error
+				return
+			}
+			fin, _, payload, err := readFrame(reader)
+			if err != nil {
+				return
+			}
+			message = append(message, payload...)
+			if !fin {
+				continue
+			}
+			if bytes.Contains(message, []byte("attack")) {
+				return
+			}
+			var b bytes.Buffer
+			_ = writeFrame(&b, true, TextFrame, append([]byte("echo: "), message...), NoMask)
+			_, _ = conn.Write(b.Bytes())
+			message = nil
+		}
+	}()
+
+	return listener
+}
+
+func doWebSocket(t *testing.T, server net.Listener, ws *WebSocket) *Response {
+	c := NewClient()
+	if err := c.NewConnection(destinationFromListener(t, server)); err != nil {
+		t.Fatal(err)
+	}
+
+	response, err := c.DoWebSocket(*generateRequestForLocalTesting(), ws)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return response
+}
+
+func TestWebSocketEcho(t *testing.T) {
+	server := newWebSocketServer(t, false)
+	defer server.Close()
+
+	response := doWebSocket(t, server, &WebSocket{Frames: []WebSocketFrame{
+		{Data: "hello"},
+		{Data: "fragmented message", Fragments: 3},
+	}})
+
+	if response.Parsed.StatusCode != http.StatusSwitchingProtocols || !response.WebSocket.Upgraded {
+		t.Fatalf("Failed ! handshake status %d", response.Parsed.StatusCode)
+	}
+	messages := response.WebSocket.Messages
+	if len(messages) != 2 || string(messages[0].Data) != "echo: hello" || string(messages[1].Data) != "echo: fragmented message" {
+		t.Errorf("Failed ! got %+v", messages)
+	}
+	if response.WebSocket.Closed {
+		t.Errorf("Failed ! connection should stay open")
+	}
+}
+
+func TestWebSocketClosedByWAF(t *testing.T) {
+	for _, frame := range []WebSocketFrame{
+		{Data: "an attack"},
+		{Data: "hello", Mask: NoMask},
+	} {
+		server := newWebSocketServer(t, false)
+
+		response := doWebSocket(t, server, &WebSocket{Frames: []WebSocketFrame{frame}})
+		if !response.WebSocket.Closed {
+			t.Errorf("Failed ! connection should be closed after %+v", frame)
+		}
+		server.Close()
+	}
+}
+
+func TestWebSocketHandshakeRefused(t *testing.T) {
+	server := newWebSocketServer(t, true)
+	defer server.Close()
+
+	response := doWebSocket(t, server, &WebSocket{Frames: []WebSocketFrame{{Data: "hello"}}})
+	if response.Parsed.StatusCode != http.StatusForbidden || response.WebSocket.Upgraded {
+		t.Errorf("Failed ! got status %d", response.Parsed.StatusCode)
+	}
+	if response.GetBodyAsString() != "blocked" {
+		t.Errorf("Failed ! got body %q", response.GetBodyAsString())
+	}
+}
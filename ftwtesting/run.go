@@ -0,0 +1,147 @@
+// Package ftwtesting runs ftw test suites as Go subtests, so you can use the `go test` tooling
+// (`-run` filtering, `-v`, parallel tests) with your WAF tests
+package ftwtesting
+
+import (
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	ftwconfig "github.com/fzipi/go-ftw/config"
+	"github.com/fzipi/go-ftw/runner"
+	"github.com/fzipi/go-ftw/test"
+	"github.com/fzipi/go-ftw/waflog"
+)
+
+// Options are the options for running the tests
+type Options struct {
+	// Handler, when not nil, serves all requests in-process instead of sending them over the network
+	Handler http.Handler
+	// LogSink, when not nil, is used for checking logs instead of the log file from the config
+	LogSink *waflog.MemorySink
+	// Parallel runs the tests in parallel. Log checks use the time the request was sent, so only
+	// enable it when logs can be told apart, or when tests don't check logs.
+	Parallel bool
+}
+
+// Run runs every FTWTest as a subtest named after its file, and each test in it as a nested
+// subtest named after its test_title, with the tests expanded from parameters nested under it.
+// Failed stages are reported using t.Errorf, with an explanation of what was expected.
+func Run(t *testing.T, tests []test.FTWTest, opts Options) {
+	t.Helper()
+
+	// the default global config is set here, as the subtests only read it when running in parallel
+	if ftwconfig.FTWConfig == nil {
+		ftwconfig.FTWConfig = &ftwconfig.FTWConfiguration{}
+	}
+
+	config := runner.Config{
+		Quiet:   true,
+		Handler: opts.Handler,
+		LogSink: opts.LogSink,
+	}
+
+	for _, ftwTest := range tests {
+		ftwTest := ftwTest
+		t.Run(testFileName(ftwTest), func(t *testing.T) {
+			if opts.Parallel {
+				t.Parallel()
+			}
+			if !ftwTest.Meta.Enabled {
+				t.Skip("tests disabled in meta")
+			}
+
+			for _, group := range groupCases(ftwTest.Tests) {
+				group := group
+				if group[0].ParentTitle == "" {
+					runSubtest(t, config, opts, group[0].TestTitle, group[0])
+					continue
+				}
+				// cases expanded from parameters are run under their test, named like `[3]`
+				t.Run(group[0].ParentTitle, func(t *testing.T) {
+					if opts.Parallel {
+						t.Parallel()
+					}
+					for _, ftwTestCase := range group {
+						runSubtest(t, config, opts, strings.TrimPrefix(ftwTestCase.TestTitle, ftwTestCase.ParentTitle), ftwTestCase)
+					}
+				})
+			}
+		})
+	}
+}
+
+// runSubtest runs the test as a subtest with the name
+func runSubtest(t *testing.T, config runner.Config, opts Options, name string, ftwTestCase test.Test) {
+	t.Helper()
+
+	t.Run(name, func(t *testing.T) {
+		if opts.Parallel {
+			t.Parallel()
+		}
+		runTest(t, config, ftwTestCase)
+	})
+}
+
+// groupCases puts together the consecutive cases expanded from the same test. Other tests are alone in their group.
+func groupCases(tests []test.Test) [][]test.Test {
+	var groups [][]test.Test
+	for _, ftwTestCase := range tests {
+		last := len(groups) - 1
+		if last >= 0 && ftwTestCase.ParentTitle != "" && groups[last][0].ParentTitle == ftwTestCase.ParentTitle {
+			groups[last] = append(groups[last], ftwTestCase)
+			continue
+		}
+		groups = append(groups, []test.Test{ftwTestCase})
+	}
+	return groups
+}
+
+// runTest runs all the stages in the test, using a new client
+func runTest(t *testing.T, config runner.Config, ftwTestCase test.Test) {
+	t.Helper()
+
+	client, err := runner.NewClient(config)
+	if err != nil {
+		t.Fatalf("cannot create client: %s", err.Error())
+	}
+
+	for i, stage := range ftwTestCase.Stages {
+		name := stageName(i, stage.Stage)
+		result, err := runner.RunStage(client, config, ftwTestCase.TestTitle, stage.Stage)
+		if err != nil {
+			t.Fatalf("%s: %s", name, err.Error())
+		}
+
+		switch result.Result {
+		case runner.Failed:
+			t.Errorf("%s failed: %s", name, result.Explanation)
+		case runner.ForceFail:
+			t.Errorf("%s was forced to fail", name)
+		case runner.Ignored:
+			t.Logf("%s result ignored", name)
+		case runner.ForcePass:
+			t.Logf("%s was forced to pass", name)
+		default:
+			t.Logf("%s passed in %s", name, result.Duration)
+		}
+	}
+}
+
+// stageName names the stage in messages, with its position in the test file when known
+func stageName(i int, stage test.StageData) string {
+	if stage.Position.Line == 0 {
+		return fmt.Sprintf("stage %d", i+1)
+	}
+	return fmt.Sprintf("stage %d (%s)", i+1, stage.Position)
+}
+
+// testFileName returns the name used for the subtest of a file
+func testFileName(ftwTest test.FTWTest) string {
+	if ftwTest.FileName != "" {
+		return filepath.Base(ftwTest.FileName)
+	}
+	return ftwTest.Meta.Name
+}
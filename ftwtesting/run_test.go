@@ -0,0 +1,156 @@
+package ftwtesting
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/fzipi/go-ftw/config"
+	"github.com/fzipi/go-ftw/test"
+	"github.com/fzipi/go-ftw/utils"
+	"github.com/fzipi/go-ftw/waflog"
+)
+
+var yamlTest = `---
+meta:
+  author: "tester"
+  enabled: true
+  name: "911100.yaml"
+  description: "Example Test"
+tests:
+  - test_title: "911100-1"
+    stages:
+      - stage:
+          input:
+            dest_addr: "waf.example.com"
+            method: "OTHER"
+            headers:
+              User-Agent: "ModSecurity CRS 3 Tests"
+              Host: "localhost"
+          output:
+            log_contains: id \"911100\"
+            status: [405]
+  - test_title: "911100-2"
+    stages:
+      - stage:
+          input:
+            dest_addr: "waf.example.com"
+            method: "GET"
+            headers:
+              User-Agent: "ModSecurity CRS 3 Tests"
+              Host: "localhost"
+          output:
+            no_log_contains: id \"911100\"
+`
+
+var yamlDisabledTest = `---
+meta:
+  author: "tester"
+  enabled: false
+  name: "disabled.yaml"
+tests:
+  - test_title: "1"
+    stages:
+      - stage:
+          input:
+            dest_addr: "waf.example.com"
+          output:
+            status: [1234]
+`
+
+// methodWAF only allows GET and POST
+func methodWAF(sink *waflog.MemorySink) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodPost {
+			sink.WriteLine(`ModSecurity: Warning. Method is not allowed by policy [id "911100"]`)
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		_, _ = w.Write([]byte("Hello, client"))
+	})
+}
+
+func readTests(t *testing.T, contents ...string) []test.FTWTest {
+	var tests []test.FTWTest
+	for _, content := range contents {
+		filename, err := utils.CreateTempFileWithContent(content, "goftw-test-*.yaml")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.Remove(filename)
+
+		ftwTests, err := test.GetTestsFromFiles(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tests = append(tests, ftwTests...)
+	}
+	return tests
+}
+
+func TestRunSuite(t *testing.T) {
+	sink := waflog.NewMemorySink()
+	tests := readTests(t, yamlTest, yamlDisabledTest)
+
+	Run(t, tests, Options{
+		Handler: methodWAF(sink),
+		LogSink: sink,
+	})
+}
+
+func TestRunParallel(t *testing.T) {
+	// without a config, Run sets up the default one before the subtests run in parallel
+	config.FTWConfig = nil
+	sink := waflog.NewMemorySink()
+	// only status checks are safe in parallel
+	tests := readTests(t, strings.ReplaceAll(yamlTest, `no_log_contains: id \"911100\"`, "status: [200]"))
+
+	Run(t, tests, Options{
+		Handler:  methodWAF(sink),
+		LogSink:  sink,
+		Parallel: true,
+	})
+}
+
+func TestTestFileName(t *testing.T) {
+	ftwTest := test.FTWTest{FileName: "/tmp/tests/911100.yaml"}
+	if name := testFileName(ftwTest); name != "911100.yaml" {
+		t.Errorf("Wrong subtest name %s", name)
+	}
+
+	ftwTest = test.FTWTest{}
+	ftwTest.Meta.Name = "from-meta.yaml"
+	if name := testFileName(ftwTest); name != "from-meta.yaml" {
+		t.Errorf("Wrong subtest name %s", name)
+	}
+}
+
+func TestStageName(t *testing.T) {
+	if name := stageName(0, test.StageData{}); name != "stage 1" {
+		t.Errorf("Wrong stage name %s", name)
+	}
+	tests := readTests(t, yamlTest)
+	stage := tests[0].Tests[0].Stages[0].Stage
+	if name := stageName(0, stage); name != fmt.Sprintf("stage 1 (%s:%d:%d)", tests[0].FileName, stage.Position.Line, stage.Position.Column) || stage.Position.Line == 0 {
+		t.Errorf("Wrong stage name %s", name)
+	}
+}
+
+func TestGroupCases(t *testing.T) {
+	groups := groupCases([]test.Test{
+		{TestTitle: "1"},
+		{TestTitle: "2[1]", ParentTitle: "2"},
+		{TestTitle: "2[2]", ParentTitle: "2"},
+		{TestTitle: "3"},
+		{TestTitle: "4[1]", ParentTitle: "4"},
+	})
+	var sizes []int
+	for _, group := range groups {
+		sizes = append(sizes, len(group))
+	}
+	if fmt.Sprint(sizes) != "[1 2 1 1]" || groups[1][1].TestTitle != "2[2]" {
+		t.Errorf("Wrong groups %v", groups)
+	}
+}
@@ -0,0 +1,92 @@
+package lint
+
+import (
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/goccy/go-yaml/ast"
+
+	"github.com/fzipi/go-ftw/test"
+)
+
+// edit replaces a scalar value in a line, keeping everything else, like quotes and comments
+type edit struct {
+	line   int
+	column int
+	old    string
+	new    string
+}
+
+// replaceValue returns the edit replacing the value of the scalar node. Values spanning
+// several lines or written with escapes are not fixed, as the edit could change them.
+func replaceValue(n ast.Node, old string, new string) *edit {
+	line, column := test.NodePosition(n)
+	if line == 0 || strings.ContainsAny(old, "\\\n") || old == "" {
+		return nil
+	}
+	return &edit{line: line, column: column, old: old, new: new}
+}
+
+// apply applies the edit to the lines, returning false when the value is not where expected
+func (e *edit) apply(lines []string) bool {
+	if e.line > len(lines) {
+		return false
+	}
+	line := lines[e.line-1]
+	// the column may point to the opening quote
+	start := e.column - 1
+	if start < 0 || start > len(line) {
+		return false
+	}
+	i := strings.Index(line[start:], e.old)
+	if i < 0 || i > 1 {
+		return false
+	}
+	start += i
+	lines[e.line-1] = line[:start] + e.new + line[start+len(e.old):]
+	return true
+}
+
+// Fix applies the fixes of the problems found by the rules enabled, and writes the files changed.
+// It returns the number of problems fixed.
+func (l *Linter) Fix(files []string) (int, error) {
+	c := readCorpus(files)
+	fixed := 0
+	for _, f := range c.files {
+		var edits []*edit
+		for _, r := range l.findings(c, f) {
+			if r.finding.fix != nil {
+				edits = append(edits, r.finding.fix)
+			}
+		}
+		if len(edits) == 0 {
+			continue
+		}
+		// apply from the end, so columns are still right when there are several edits in a line
+		sort.SliceStable(edits, func(i, j int) bool {
+			if edits[i].line != edits[j].line {
+				return edits[i].line > edits[j].line
+			}
+			return edits[i].column > edits[j].column
+		})
+		changed := 0
+		for _, e := range edits {
+			if e.apply(f.lines) {
+				changed++
+			}
+		}
+		if changed == 0 {
+			continue
+		}
+		info, err := os.Stat(f.name)
+		if err != nil {
+			return fixed, err
+		}
+		if err = os.WriteFile(f.name, []byte(strings.Join(f.lines, "\n")), info.Mode()); err != nil {
+			return fixed, err
+		}
+		fixed += changed
+	}
+	return fixed, nil
+}
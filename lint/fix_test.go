@@ -0,0 +1,38 @@
+package lint
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestFix(t *testing.T) {
+	dir, files := writeTests(t, map[string]string{"920100.yaml": yamlUnorderedTests})
+	defer os.RemoveAll(dir)
+
+	l, _ := NewLinter(nil)
+	fixed, err := l.Fix(files)
+	if err != nil || fixed != 2 {
+		t.Errorf("Failed ! fixed %d problems: %v", fixed, err)
+	}
+	data, _ := os.ReadFile(files[0])
+	expected := strings.Replace(strings.Replace(yamlUnorderedTests, `"920100-3"`, `"920100-2"`, 1), `"920110"`, `"920100"`, 1)
+	if string(data) != expected {
+		t.Errorf("Failed ! fixed file is:\n%s", data)
+	}
+	if s := l.Lint(files).Summary(); s.Problems != 0 {
+		t.Errorf("Failed ! found %d problems after fixing", s.Problems)
+	}
+}
+
+func TestEditApply(t *testing.T) {
+	lines := []string{`  - test_title: '920100-7' # keep me`}
+	e := &edit{line: 1, column: 17, old: "920100-7", new: "920100-1"}
+	if !e.apply(lines) || lines[0] != `  - test_title: '920100-1' # keep me` {
+		t.Errorf("Failed ! edited line is %s", lines[0])
+	}
+	e = &edit{line: 1, column: 5, old: "920100-1", new: "x"}
+	if e.apply(lines) {
+		t.Errorf("Failed ! edit must not be applied far from its column")
+	}
+}
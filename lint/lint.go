@@ -0,0 +1,180 @@
+// Package lint checks the conventions of a test corpus, beyond the syntax checked by `ftw check`
+package lint
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/parser"
+
+	"github.com/fzipi/go-ftw/test"
+)
+
+// Level is how the problems found by a rule are reported
+type Level string
+
+const (
+	// Off disables the rule
+	Off Level = "off"
+	// Warning reports the problems without failing
+	Warning Level = "warning"
+	// Error reports the problems, and makes the lint fail
+	Error Level = "error"
+)
+
+// Linter checks test files using the rules enabled
+type Linter struct {
+	levels map[string]Level
+}
+
+// finding is a problem found by a rule, at the position of node
+type finding struct {
+	node    ast.Node
+	message string
+	// fix, when not nil, is the edit that solves the problem
+	fix *edit
+}
+
+// file is a test file being linted
+type file struct {
+	name string
+	// id is the file name without extension, like the rule id in `920100.yaml`
+	id    string
+	test  test.FTWTest
+	root  ast.Node
+	lines []string
+	// unreadable is set when the file cannot be parsed, run `ftw check` for the details
+	unreadable error
+}
+
+// corpus has all the files linted, for rules that look at all of them at once
+type corpus struct {
+	files []*file
+	// titles has the files using each test_title
+	titles map[string][]*file
+}
+
+// NewLinter creates a linter. Levels override the default level of the rules by name.
+func NewLinter(levels map[string]string) (*Linter, error) {
+	l := &Linter{levels: make(map[string]Level)}
+	for _, r := range Rules {
+		l.levels[r.Name] = r.Level
+	}
+	for name, level := range levels {
+		if _, ok := l.levels[name]; !ok {
+			return nil, fmt.Errorf("ftw/lint: unknown rule %q, use one of %s", name, strings.Join(RuleNames(), ", "))
+		}
+		switch Level(level) {
+		case Off, Warning, Error:
+			l.levels[name] = Level(level)
+		default:
+			return nil, fmt.Errorf("ftw/lint: bad level %q for rule %s, use off, warning or error", level, name)
+		}
+	}
+	return l, nil
+}
+
+// Level returns the level used for the rule
+func (l *Linter) Level(rule string) Level {
+	return l.levels[rule]
+}
+
+// Lint checks the files using the rules enabled, and returns the problems found in each of them
+func (l *Linter) Lint(files []string) test.Report {
+	c := readCorpus(files)
+	var report test.Report
+	for _, f := range c.files {
+		diagnostics := []test.Diagnostic{}
+		for _, r := range l.findings(c, f) {
+			line, column := test.NodePosition(r.finding.node)
+			level, ok := l.levels[r.rule]
+			if !ok {
+				level = Error
+			}
+			diagnostics = append(diagnostics, test.Diagnostic{
+				File:     f.name,
+				Line:     line,
+				Column:   column,
+				Message:  r.finding.message,
+				Rule:     r.rule,
+				Severity: string(level),
+			})
+		}
+		sort.SliceStable(diagnostics, func(i, j int) bool {
+			return diagnostics[i].Line < diagnostics[j].Line
+		})
+		report.Files = append(report.Files, test.FileReport{File: f.name, Diagnostics: diagnostics})
+	}
+	return report
+}
+
+// ruleFinding is a finding with the name of the rule
+type ruleFinding struct {
+	rule    string
+	finding finding
+}
+
+// findings runs the rules enabled in the file, skipping the findings suppressed by comments
+func (l *Linter) findings(c *corpus, f *file) []ruleFinding {
+	if f.unreadable != nil {
+		return []ruleFinding{{rule: readableRule, finding: finding{message: fmt.Sprintf("cannot read the file, run ftw check for details: %s", f.unreadable.Error())}}}
+	}
+	suppressions := parseSuppressions(f.lines)
+	var found []ruleFinding
+	for _, r := range Rules {
+		if l.levels[r.Name] == Off {
+			continue
+		}
+		for _, fi := range r.check(c, f) {
+			line, _ := test.NodePosition(fi.node)
+			if suppressions.suppressed(r.Name, line) {
+				continue
+			}
+			found = append(found, ruleFinding{rule: r.Name, finding: fi})
+		}
+	}
+	return found
+}
+
+// readCorpus reads all the files. Files that cannot be read are kept, so they are reported.
+func readCorpus(files []string) *corpus {
+	c := &corpus{titles: make(map[string][]*file)}
+	for _, name := range files {
+		f := readFile(name)
+		c.files = append(c.files, f)
+		for _, t := range f.test.Tests {
+			c.titles[t.TestTitle] = append(c.titles[t.TestTitle], f)
+		}
+	}
+	return c
+}
+
+func readFile(name string) *file {
+	base := filepath.Base(name)
+	f := &file{name: name, id: strings.TrimSuffix(base, filepath.Ext(base))}
+
+	data, err := os.ReadFile(name)
+	if err != nil {
+		f.unreadable = err
+		return f
+	}
+	f.lines = strings.Split(string(data), "\n")
+
+	parsed, err := parser.ParseBytes(data, 0)
+	if err != nil {
+		f.unreadable = err
+		return f
+	}
+	if len(parsed.Docs) > 0 {
+		f.root = parsed.Docs[0].Body
+	}
+	if err = yaml.Unmarshal(data, &f.test); err != nil {
+		f.unreadable = err
+	}
+	return f
+}
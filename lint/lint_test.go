@@ -0,0 +1,154 @@
+package lint
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fzipi/go-ftw/test"
+)
+
+var yamlGoodTests = `---
+meta:
+  author: "tester"
+  enabled: true
+  name: "920100.yaml"
+  description: "Description"
+tests:
+  - test_title: 920100-1
+    desc: "first test"
+    stages:
+      - stage:
+          input:
+            uri: "/"
+          output:
+            status: [200]
+  - test_title: 920100-2
+    desc: "second test"
+    stages:
+      - stage:
+          input:
+            uri: "/?a=b"
+          output:
+            log_contains: "id \"920100\""
+`
+
+var yamlBadTests = `---
+meta:
+  author: "tester"
+  name: "920100.yaml"
+tests:
+  - test_title: 920100-1
+    stages:
+      - stage:
+          input:
+            uri: "/"
+          output:
+            status: [200]
+  - test_title: 920200-2
+    desc: "copied test"
+    stages:
+      - stage:
+          input:
+            uri: "/"
+          output: {}
+`
+
+// writeTests writes the tests in a temporary directory, using the names passed
+func writeTests(t *testing.T, files map[string]string) (string, []string) {
+	dir, err := os.MkdirTemp("", "ftw-lint-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err = os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, path)
+	}
+	return dir, names
+}
+
+// diagnostics returns all the diagnostics in the report, by rule
+func diagnostics(r test.Report) map[string][]test.Diagnostic {
+	found := make(map[string][]test.Diagnostic)
+	for _, f := range r.Files {
+		for _, d := range f.Diagnostics {
+			found[d.Rule] = append(found[d.Rule], d)
+		}
+	}
+	return found
+}
+
+func TestLintGoodTests(t *testing.T) {
+	dir, files := writeTests(t, map[string]string{"920100.yaml": yamlGoodTests})
+	defer os.RemoveAll(dir)
+
+	l, _ := NewLinter(nil)
+	report := l.Lint(files)
+	if s := report.Summary(); s.Problems != 0 {
+		t.Errorf("Failed ! found problems in good tests: %v", report.Files)
+	}
+}
+
+func TestLintBadTests(t *testing.T) {
+	dir, files := writeTests(t, map[string]string{"920100.yaml": yamlBadTests})
+	defer os.RemoveAll(dir)
+
+	l, _ := NewLinter(nil)
+	report := l.Lint(files)
+	found := diagnostics(report)
+
+	if d := found["desc"]; len(d) != 1 || d[0].Line != 6 || d[0].Severity != test.SeverityWarning {
+		t.Errorf("Failed ! desc diagnostics are %v", d)
+	}
+	if d := found["title-prefix"]; len(d) != 1 || d[0].Line != 13 || d[0].Column != 17 {
+		t.Errorf("Failed ! title-prefix diagnostics are %v", d)
+	}
+	if d := found["assertions"]; len(d) != 1 || d[0].Line != 19 || d[0].Severity != test.SeverityError {
+		t.Errorf("Failed ! assertions diagnostics are %v", d)
+	}
+	if report.OK() {
+		t.Errorf("Failed ! stages without assertions must fail")
+	}
+}
+
+func TestLintUniqueTitles(t *testing.T) {
+	dir, files := writeTests(t, map[string]string{"920100.yaml": yamlGoodTests, "other.yaml": yamlGoodTests})
+	defer os.RemoveAll(dir)
+
+	l, _ := NewLinter(map[string]string{"title-prefix": "off", "meta-name": "off"})
+	found := diagnostics(l.Lint(files))
+	if d := found["unique-title"]; len(d) != 4 {
+		t.Errorf("Failed ! unique-title diagnostics are %v", d)
+	}
+	if len(found) != 1 {
+		t.Errorf("Failed ! disabled rules were used: %v", found)
+	}
+}
+
+func TestLintUnreadable(t *testing.T) {
+	dir, files := writeTests(t, map[string]string{"920100.yaml": "tests: [\n  - test_title: {"})
+	defer os.RemoveAll(dir)
+
+	l, _ := NewLinter(nil)
+	report := l.Lint(files)
+	if d := diagnostics(report)[readableRule]; len(d) != 1 || d[0].Severity != test.SeverityError {
+		t.Errorf("Failed ! unreadable file diagnostics are %v", report.Files)
+	}
+}
+
+func TestNewLinter(t *testing.T) {
+	l, err := NewLinter(map[string]string{"desc": "error"})
+	if err != nil || l.Level("desc") != Error || l.Level("title-prefix") != Warning {
+		t.Errorf("Failed ! levels were not set")
+	}
+	if _, err = NewLinter(map[string]string{"nope": "off"}); err == nil {
+		t.Errorf("Failed ! unknown rules must fail")
+	}
+	if _, err = NewLinter(map[string]string{"desc": "loud"}); err == nil {
+		t.Errorf("Failed ! unknown levels must fail")
+	}
+}
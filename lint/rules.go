@@ -0,0 +1,206 @@
+package lint
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/goccy/go-yaml/ast"
+
+	"github.com/fzipi/go-ftw/test"
+)
+
+// Rule is a convention checked in test files
+type Rule struct {
+	Name string
+	// Description says what the rule checks, shown by `ftw lint --list`
+	Description string
+	// Level is used when the rule is not configured
+	Level Level
+	check func(c *corpus, f *file) []finding
+}
+
+// readableRule is used for files that cannot be read. It cannot be disabled.
+const readableRule = "readable"
+
+// numberedTitle matches titles like `920100-3`
+var numberedTitle = regexp.MustCompile(`^(.+)-(\d+)$`)
+
+// Rules are all the rules, in the order they run
+var Rules = []Rule{
+	{
+		Name:        "unique-title",
+		Description: "test_title is unique across all the files",
+		Level:       Error,
+		check:       checkUniqueTitle,
+	},
+	{
+		Name:        "title-prefix",
+		Description: "test_title starts with the rule id from the file name, like 920100-1 in 920100.yaml",
+		Level:       Warning,
+		check:       checkTitlePrefix,
+	},
+	{
+		Name:        "sequential-titles",
+		Description: "tests are numbered in order starting at 1, fixed by --fix",
+		Level:       Warning,
+		check:       checkSequentialTitles,
+	},
+	{
+		Name:        "meta-name",
+		Description: "meta.name matches the file name, fixed by --fix",
+		Level:       Warning,
+		check:       checkMetaName,
+	},
+	{
+		Name:        "desc",
+		Description: "every test has a desc",
+		Level:       Warning,
+		check:       checkDesc,
+	},
+	{
+		Name:        "assertions",
+		Description: "every stage expects something in its output",
+		Level:       Error,
+		check:       checkAssertions,
+	},
+}
+
+// RuleNames returns the names of all the rules
+func RuleNames() []string {
+	var names []string
+	for _, r := range Rules {
+		names = append(names, r.Name)
+	}
+	return names
+}
+
+// testNode returns the node of the test at index i
+func (f *file) testNode(i int) ast.Node {
+	return test.ValueAt(f.root, "tests", i)
+}
+
+// titleNode returns the node with the title of the test at index i, or the test itself when it has no title
+func (f *file) titleNode(i int) ast.Node {
+	if n := test.ValueAt(f.testNode(i), "test_title"); n != nil {
+		return n
+	}
+	return f.testNode(i)
+}
+
+func checkUniqueTitle(c *corpus, f *file) []finding {
+	var found []finding
+	for i, t := range f.test.Tests {
+		if t.TestTitle == "" {
+			continue
+		}
+		var others []string
+		sameFile := 0
+		for _, other := range c.titles[t.TestTitle] {
+			if other == f {
+				sameFile++
+			} else if len(others) == 0 || others[len(others)-1] != other.name {
+				others = append(others, other.name)
+			}
+		}
+		switch {
+		case sameFile > 1:
+			found = append(found, finding{node: f.titleNode(i), message: fmt.Sprintf("test_title %q is used more than once in this file", t.TestTitle)})
+		case len(others) > 0:
+			found = append(found, finding{node: f.titleNode(i), message: fmt.Sprintf("test_title %q is also used in %s", t.TestTitle, strings.Join(others, ", "))})
+		}
+	}
+	return found
+}
+
+func checkTitlePrefix(c *corpus, f *file) []finding {
+	var found []finding
+	for i, t := range f.test.Tests {
+		if t.TestTitle != "" && !strings.HasPrefix(t.TestTitle, f.id+"-") {
+			found = append(found, finding{node: f.titleNode(i), message: fmt.Sprintf("test_title %q should start with %q", t.TestTitle, f.id+"-")})
+		}
+	}
+	return found
+}
+
+// checkSequentialTitles only looks at titles like `<id>-<n>`, others are reported by title-prefix
+func checkSequentialTitles(c *corpus, f *file) []finding {
+	var found []finding
+	for i, t := range f.test.Tests {
+		m := numberedTitle.FindStringSubmatch(t.TestTitle)
+		if m == nil || m[1] != f.id {
+			continue
+		}
+		expected := fmt.Sprintf("%s-%d", f.id, i+1)
+		if n, _ := strconv.Atoi(m[2]); n == i+1 {
+			continue
+		}
+		node := f.titleNode(i)
+		found = append(found, finding{
+			node:    node,
+			message: fmt.Sprintf("test %d should be numbered %q, got %q", i+1, expected, t.TestTitle),
+			fix:     replaceValue(node, t.TestTitle, expected),
+		})
+	}
+	return found
+}
+
+func checkMetaName(c *corpus, f *file) []finding {
+	name := f.test.Meta.Name
+	if name == f.id || name == filepath.Base(f.name) {
+		return nil
+	}
+	meta := test.ValueAt(f.root, "meta")
+	node := test.ValueAt(meta, "name")
+	if node == nil {
+		if meta == nil {
+			meta = f.root
+		}
+		return []finding{{node: meta, message: fmt.Sprintf("meta.name is missing, it should be %q", filepath.Base(f.name))}}
+	}
+	expected := f.id
+	if strings.HasSuffix(name, filepath.Ext(f.name)) {
+		expected = filepath.Base(f.name)
+	}
+	return []finding{{
+		node:    node,
+		message: fmt.Sprintf("meta.name %q does not match the file name, it should be %q", name, expected),
+		fix:     replaceValue(node, name, expected),
+	}}
+}
+
+func checkDesc(c *corpus, f *file) []finding {
+	var found []finding
+	for i, t := range f.test.Tests {
+		if strings.TrimSpace(t.TestDescription) == "" {
+			found = append(found, finding{node: f.testNode(i), message: fmt.Sprintf("test %s has no desc", t.TestTitle)})
+		}
+	}
+	return found
+}
+
+func checkAssertions(c *corpus, f *file) []finding {
+	var found []finding
+	for i, t := range f.test.Tests {
+		for s, stage := range t.Stages {
+			if hasAssertions(&stage.Stage.Output) {
+				continue
+			}
+			stageNode := test.ValueAt(f.testNode(i), "stages", s, "stage")
+			node := test.ValueAt(stageNode, "output")
+			if node == nil {
+				node = stageNode
+			}
+			found = append(found, finding{node: node, message: fmt.Sprintf("stage %d of test %s expects nothing in its output", s+1, t.TestTitle)})
+		}
+	}
+	return found
+}
+
+// hasAssertions is true when the output checks something
+func hasAssertions(o *test.Output) bool {
+	return len(o.Status) > 0 || o.ResponseContains != "" || o.LogContains != "" || o.NoLogContains != "" ||
+		o.ExpectError.Expected() || len(o.GRPCStatus) > 0 || o.WebSocket != nil
+}
@@ -0,0 +1,68 @@
+package lint
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+var yamlUnorderedTests = `---
+meta:
+  name: "920110"
+tests:
+  - test_title: 920100-1
+    desc: "first test"
+    stages:
+      - stage:
+          input:
+            uri: "/"
+          output:
+            status: [200]
+  - test_title: "920100-3"
+    desc: "second test"
+    stages:
+      - stage:
+          input:
+            uri: "/"
+          output:
+            expect_error: true
+`
+
+func TestSequentialTitles(t *testing.T) {
+	dir, files := writeTests(t, map[string]string{"920100.yaml": yamlUnorderedTests})
+	defer os.RemoveAll(dir)
+
+	l, _ := NewLinter(nil)
+	d := diagnostics(l.Lint(files))["sequential-titles"]
+	if len(d) != 1 || d[0].Line != 13 || !strings.Contains(d[0].Message, `"920100-2"`) {
+		t.Errorf("Failed ! sequential-titles diagnostics are %v", d)
+	}
+}
+
+func TestMetaName(t *testing.T) {
+	dir, files := writeTests(t, map[string]string{"920100.yaml": yamlUnorderedTests})
+	defer os.RemoveAll(dir)
+
+	l, _ := NewLinter(nil)
+	d := diagnostics(l.Lint(files))["meta-name"]
+	if len(d) != 1 || d[0].Line != 3 || !strings.Contains(d[0].Message, `should be "920100"`) {
+		t.Errorf("Failed ! meta-name diagnostics are %v", d)
+	}
+}
+
+func TestMetaNameMissing(t *testing.T) {
+	dir, files := writeTests(t, map[string]string{"920100.yaml": strings.Replace(yamlUnorderedTests, `  name: "920110"`, `  author: "tester"`, 1)})
+	defer os.RemoveAll(dir)
+
+	l, _ := NewLinter(nil)
+	d := diagnostics(l.Lint(files))["meta-name"]
+	if len(d) != 1 || d[0].Line != 3 || !strings.Contains(d[0].Message, "missing") {
+		t.Errorf("Failed ! meta-name diagnostics are %v", d)
+	}
+}
+
+func TestRuleNames(t *testing.T) {
+	if len(RuleNames()) != len(Rules) || RuleNames()[0] != "unique-title" {
+		t.Errorf("Failed ! rule names are %v", RuleNames())
+	}
+}
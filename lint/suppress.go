@@ -0,0 +1,68 @@
+package lint
+
+import (
+	"regexp"
+	"strings"
+)
+
+// suppressionComment matches `# ftw-lint-disable` and `# ftw-lint-disable-file`, with an optional list of rules
+var suppressionComment = regexp.MustCompile(`#\s*ftw-lint-disable(-file)?\b([^#]*)`)
+
+// suppressions has the rules disabled by comments in a file. A nil list of rules disables all of them.
+type suppressions struct {
+	file  []string
+	all   bool
+	lines map[int][]string
+	// allLines has the lines where every rule is disabled
+	allLines map[int]bool
+}
+
+// parseSuppressions finds the comments disabling rules. `# ftw-lint-disable` applies to its own line
+// when it follows some yaml, or to the next line when the comment is alone in its line.
+// `# ftw-lint-disable-file` applies to the whole file.
+func parseSuppressions(lines []string) *suppressions {
+	s := &suppressions{lines: make(map[int][]string), allLines: make(map[int]bool)}
+	for i, line := range lines {
+		m := suppressionComment.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		rules := strings.FieldsFunc(m[2], func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t' || r == '\r'
+		})
+		if m[1] != "" {
+			if len(rules) == 0 {
+				s.all = true
+			}
+			s.file = append(s.file, rules...)
+			continue
+		}
+		// lines are numbered from 1, as in diagnostics
+		target := i + 1
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			target++
+		}
+		if len(rules) == 0 {
+			s.allLines[target] = true
+		}
+		s.lines[target] = append(s.lines[target], rules...)
+	}
+	return s
+}
+
+// suppressed is true when the rule is disabled in the line
+func (s *suppressions) suppressed(rule string, line int) bool {
+	if s.all || s.allLines[line] || contains(s.file, rule) {
+		return true
+	}
+	return contains(s.lines[line], rule)
+}
+
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,39 @@
+package lint
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestParseSuppressions(t *testing.T) {
+	s := parseSuppressions([]string{
+		"# ftw-lint-disable-file meta-name",
+		"tests:",
+		"  - test_title: 1 # ftw-lint-disable title-prefix, desc",
+		"    # ftw-lint-disable",
+		"    stages:",
+	})
+	if !s.suppressed("meta-name", 10) {
+		t.Errorf("Failed ! rule disabled in the file was used")
+	}
+	if !s.suppressed("title-prefix", 3) || !s.suppressed("desc", 3) || s.suppressed("assertions", 3) {
+		t.Errorf("Failed ! rules disabled in the line are wrong")
+	}
+	if !s.suppressed("assertions", 5) || s.suppressed("assertions", 4) {
+		t.Errorf("Failed ! comment alone must disable all rules in the next line")
+	}
+}
+
+func TestSuppressedFindings(t *testing.T) {
+	content := strings.Replace(yamlBadTests, "  - test_title: 920200-2", "  # ftw-lint-disable title-prefix\n  - test_title: 920200-2", 1)
+	content = strings.Replace(content, "output: {}", "output: {} # ftw-lint-disable", 1)
+	dir, files := writeTests(t, map[string]string{"920100.yaml": content})
+	defer os.RemoveAll(dir)
+
+	l, _ := NewLinter(nil)
+	found := diagnostics(l.Lint(files))
+	if len(found["title-prefix"]) != 0 || len(found["assertions"]) != 0 || len(found["desc"]) != 1 {
+		t.Errorf("Failed ! suppression comments were not used: %v", found)
+	}
+}
@@ -0,0 +1,173 @@
+package runner
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fzipi/go-ftw/evasion"
+	"github.com/fzipi/go-ftw/ftwhttp"
+	"github.com/fzipi/go-ftw/test"
+	"github.com/kyokomi/emoji"
+	"github.com/rs/zerolog/log"
+)
+
+// EvasionStats accumulates the results of checking evasions
+type EvasionStats struct {
+	// Tests is the number of tests with an attack string whose variants were run
+	Tests int
+	// Variants is the number of variants run
+	Variants int
+	// Bypasses are the titles of the variants that were not detected, while their test was
+	Bypasses []string
+	// Undetected are the titles of the tests with an attack string that failed, so their variants were not run
+	Undetected []string
+	// NoAttack are the titles of the tests whose attack string is not in any stage
+	NoAttack []string
+	// Errors are the titles of the tests and variants that could not be run, with the error
+	Errors []string
+}
+
+// RunEvasions runs every test with an attack string, and when it passes, its variants using the techniques.
+// A variant that fails bypassed detection. Tests without attack string are not run.
+// Returns the number of bypasses, plus the number of tests and variants that could not be run
+func RunEvasions(ftwtests []test.FTWTest, c Config, techniques []evasion.Technique) int {
+	var stats EvasionStats
+
+	output := c.Quiet
+
+	printUnlessQuietMode(output, ":rocket:Checking evasions with go-ftw!\n")
+
+	client, err := NewClient(c)
+	if err != nil {
+		log.Fatal().Msgf("ftw/run: %s", err.Error())
+	}
+	tagExpression, err := ParseTagExpression(c.Tags)
+	if err != nil {
+		log.Fatal().Msgf("ftw/run: %s", err.Error())
+	}
+
+	for _, tests := range ftwtests {
+		changed := true
+		for _, t := range tests.Tests {
+			if t.Attack == "" {
+				continue
+			}
+			if reason := needToSkipTest(c.Include, c.Exclude, tagExpression, t.Titles(), tests.TestTags(t), tests.Meta.Enabled); reason != NotSkipped {
+				log.Debug().Msgf("ftw/run: skipping %s: %s", t.TestTitle, reason)
+				continue
+			}
+			if changed {
+				printUnlessQuietMode(output, ":point_right:checking evasions of tests in file %s\n", tests.Meta.Name)
+				changed = false
+			}
+			runEvasions(client, c, t, techniques, &stats)
+		}
+	}
+
+	return printEvasionSummary(output, stats)
+}
+
+// runEvasions runs the test, and its variants if it passed
+func runEvasions(client *ftwhttp.Client, c Config, t test.Test, techniques []evasion.Technique, stats *EvasionStats) {
+	output := c.Quiet
+
+	if !evasion.HasAttack(t) {
+		printUnlessQuietMode(output, "\t%s: :warning:attack %q is not in any stage\n", t.TestTitle, t.Attack)
+		stats.NoAttack = append(stats.NoAttack, t.TestTitle)
+		return
+	}
+
+	result, explanation, err := runTestStages(client, c, t)
+	if err != nil {
+		printUnlessQuietMode(output, "\t%s: :warning:error, variants not run: %s\n", t.TestTitle, err.Error())
+		stats.Errors = append(stats.Errors, fmt.Sprintf("%s: %s", t.TestTitle, err.Error()))
+		return
+	}
+	switch result {
+	case Success:
+		printUnlessQuietMode(output, "\t%s: :check_mark:detected\n", t.TestTitle)
+	case Failed:
+		printUnlessQuietMode(output, "\t%s: :collision:not detected, variants not run: %s\n", t.TestTitle, explanation)
+		stats.Undetected = append(stats.Undetected, t.TestTitle)
+		return
+	default:
+		printUnlessQuietMode(output, "\t%s: :equal:result overriden, variants not run\n", t.TestTitle)
+		return
+	}
+
+	stats.Tests++
+	for _, v := range evasion.Variants(t, techniques) {
+		client.CaptureComment = fmt.Sprintf("test %s", v.Test.TestTitle)
+		result, explanation, err := runTestStages(client, c, v.Test)
+		if err != nil {
+			printUnlessQuietMode(output, "\t\t%s: :warning:error, %s\n", v.Technique.Name, err.Error())
+			stats.Errors = append(stats.Errors, fmt.Sprintf("%s: %s", v.Test.TestTitle, err.Error()))
+			continue
+		}
+		switch result {
+		case Success:
+			printUnlessQuietMode(output, "\t\t%s: :check_mark:detected\n", v.Technique.Name)
+		case Failed:
+			printUnlessQuietMode(output, "\t\t%s: :collision:bypassed, %s\n", v.Technique.Name, explanation)
+			stats.Bypasses = append(stats.Bypasses, v.Test.TestTitle)
+		default:
+			printUnlessQuietMode(output, "\t\t%s: :equal:result overriden\n", v.Technique.Name)
+			continue
+		}
+		stats.Variants++
+	}
+}
+
+// runTestStages runs all the stages of the test, stopping at the first one that does not pass.
+// Forced failures are failures, and other overriden results are returned as they are.
+// Errors running a stage are returned, so the other tests and variants can still run.
+func runTestStages(client *ftwhttp.Client, c Config, t test.Test) (TestResult, string, error) {
+	for _, stage := range t.Stages {
+		result, err := RunStage(client, c, t.TestTitle, stage.Stage)
+		if err != nil {
+			return Failed, "", err
+		}
+		switch result.Result {
+		case Success:
+			continue
+		case Failed, ForceFail:
+			explanation := result.Explanation
+			if explanation == "" {
+				explanation = "forced to fail"
+			}
+			return Failed, explanation, nil
+		default:
+			return result.Result, "", nil
+		}
+	}
+	return Success, "", nil
+}
+
+func printEvasionSummary(quiet bool, stats EvasionStats) int {
+	if !quiet {
+		if stats.Tests == 0 && len(stats.Undetected) == 0 && len(stats.Errors) == 0 {
+			emoji.Println(":person_shrugging:No tests with an attack string were run")
+		} else {
+			emoji.Printf(":plus:run %d variants of %d tests\n", stats.Variants, stats.Tests)
+			if len(stats.Undetected) > 0 {
+				emoji.Printf(":index_pointing_up:%d test(s) were not detected, so their variants were not run: %+q\n", len(stats.Undetected), stats.Undetected)
+			}
+			if len(stats.NoAttack) > 0 {
+				emoji.Printf(":index_pointing_up:%d test(s) have an attack string that is not in any stage: %+q\n", len(stats.NoAttack), stats.NoAttack)
+			}
+			if len(stats.Errors) > 0 {
+				emoji.Printf(":warning:%d test(s) or variant(s) could not be run:\n", len(stats.Errors))
+				for _, e := range stats.Errors {
+					fmt.Printf("\t%s\n", e)
+				}
+			}
+			if len(stats.Bypasses) == 0 {
+				emoji.Println(":tada:No variant bypassed detection!")
+			} else {
+				emoji.Printf(":thumbs_down:%d variant(s) bypassed detection: %s\n", len(stats.Bypasses), strings.Join(stats.Bypasses, ", "))
+			}
+		}
+	}
+
+	return len(stats.Bypasses) + len(stats.Errors)
+}
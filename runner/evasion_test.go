@@ -0,0 +1,154 @@
+package runner
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/fzipi/go-ftw/config"
+	"github.com/fzipi/go-ftw/evasion"
+	"github.com/fzipi/go-ftw/ftwhttp"
+	"github.com/fzipi/go-ftw/test"
+	"github.com/fzipi/go-ftw/utils"
+)
+
+var yamlTestEvasion = `---
+meta:
+  author: "tester"
+  enabled: true
+  name: "gotest-ftw.yaml"
+tests:
+  - test_title: "601"
+    attack: "' or 1=1"
+    stages:
+      - stage:
+          input:
+            dest_addr: "waf.example.com"
+            uri: "/?q=%27+or+1%3D1"
+          output:
+            status: [403]
+  - test_title: "602"
+    attack: "<script>"
+    stages:
+      - stage:
+          input:
+            dest_addr: "waf.example.com"
+            uri: "/?q=%3Cscript%3E"
+          output:
+            status: [403]
+  - test_title: "603"
+    attack: "not there"
+    stages:
+      - stage:
+          input:
+            dest_addr: "waf.example.com"
+          output:
+            status: [403]
+  - test_title: "604"
+    stages:
+      - stage:
+          input:
+            dest_addr: "waf.example.com"
+          output:
+            status: [403]
+`
+
+func TestRunEvasions(t *testing.T) {
+	config.FTWConfig = nil
+	// a naive waf, which only decodes the query once and ignores case
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(strings.ToLower(r.URL.Query().Get("q")), "' or 1=1") {
+			w.WriteHeader(http.StatusForbidden)
+		}
+	})
+
+	filename, err := utils.CreateTempFileWithContent(yamlTestEvasion, "goftw-test-*.yaml")
+	if err != nil {
+		t.Fatalf("Failed!: %s\n", err.Error())
+	}
+	defer os.Remove(filename)
+
+	tests, err := test.GetTestsFromFiles(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := Config{Quiet: true, Handler: handler}
+	client, err := NewClient(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stats EvasionStats
+	for _, tt := range tests[0].Tests {
+		if tt.Attack != "" {
+			runEvasions(client, c, tt, evasion.Techniques, &stats)
+		}
+	}
+
+	if stats.Tests != 1 || stats.Variants != 7 {
+		t.Errorf("Failed ! expected 7 variants of 1 test, got %d of %d", stats.Variants, stats.Tests)
+	}
+	expected := "601~double-url,601~unicode,601~overlong-utf8,601~whitespace,601~comments,601~parameter-pollution"
+	if bypasses := strings.Join(stats.Bypasses, ","); bypasses != expected {
+		t.Errorf("Failed ! expected bypasses %s, got %s", expected, bypasses)
+	}
+	if len(stats.Undetected) != 1 || stats.Undetected[0] != "602" {
+		t.Errorf("Failed ! expected 602 to be undetected, got %q", stats.Undetected)
+	}
+	if len(stats.NoAttack) != 1 || stats.NoAttack[0] != "603" {
+		t.Errorf("Failed ! expected 603 to have no attack, got %q", stats.NoAttack)
+	}
+
+	if res := RunEvasions(tests, Config{Quiet: true, Handler: handler, Include: "^602$"}, evasion.Techniques); res != 0 {
+		t.Errorf("Failed ! expected no bypasses, got %d", res)
+	}
+}
+
+func TestRunEvasionsErrors(t *testing.T) {
+	config.FTWConfig = nil
+	// the server detects the test, and then stops accepting connections, so the variants cannot be run
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server.Listener.Close()
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+	d, err := ftwhttp.DestinationFromString(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	yamlTest := strings.ReplaceAll(yamlTestEvasion, `dest_addr: "waf.example.com"`,
+		fmt.Sprintf("dest_addr: %q\n            port: %d\n            headers:\n              Host: localhost", d.DestAddr, d.Port))
+	filename, err := utils.CreateTempFileWithContent(yamlTest, "goftw-test-*.yaml")
+	if err != nil {
+		t.Fatalf("Failed!: %s\n", err.Error())
+	}
+	defer os.Remove(filename)
+
+	tests, err := test.GetTestsFromFiles(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := Config{Quiet: true}
+	client, err := NewClient(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stats EvasionStats
+	runEvasions(client, c, tests[0].Tests[0], evasion.Techniques, &stats)
+
+	if stats.Tests != 1 || stats.Variants != 0 || len(stats.Bypasses) != 0 {
+		t.Errorf("Failed ! expected no variants run, got %d variants and bypasses %q", stats.Variants, stats.Bypasses)
+	}
+	if len(stats.Errors) != 7 || !strings.HasPrefix(stats.Errors[0], "601~double-url: ") {
+		t.Errorf("Failed ! expected an error for each variant, got %q", stats.Errors)
+	}
+	if res := printEvasionSummary(true, stats); res != 7 {
+		t.Errorf("Failed ! expected errors to be counted, got %d", res)
+	}
+}
@@ -0,0 +1,61 @@
+package runner
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// ReadIDs reads test ids from a file, one per line. Blank lines and lines starting with `#` are ignored.
+func ReadIDs(filename string) ([]string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var ids []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		ids = append(ids, line)
+	}
+	return ids, scanner.Err()
+}
+
+// MatchNone is a regexp matching no title. It is used for including the tests in id files without ids,
+// as an empty include regexp would include every test.
+const MatchNone = `[^\s\S]`
+
+// MatchAny returns a regexp matching titles that match any of the regexps, or are equal to any of the ids.
+// It is empty when there are no regexps nor ids, and fails when some regexp is not valid.
+func MatchAny(patterns []string, ids []string) (string, error) {
+	var valid []string
+	for _, p := range patterns {
+		if p == "" {
+			continue
+		}
+		if _, err := regexp.Compile(p); err != nil {
+			return "", fmt.Errorf("bad regexp %q: %w", p, err)
+		}
+		valid = append(valid, p)
+	}
+	if len(valid) == 1 && len(ids) == 0 {
+		// keep a single regexp as it was written
+		return valid[0], nil
+	}
+
+	var alternatives []string
+	for _, p := range valid {
+		alternatives = append(alternatives, "(?:"+p+")")
+	}
+	for _, id := range ids {
+		alternatives = append(alternatives, "^"+regexp.QuoteMeta(id)+"$")
+	}
+	return strings.Join(alternatives, "|"), nil
+}
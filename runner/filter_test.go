@@ -0,0 +1,77 @@
+package runner
+
+import (
+	"os"
+	"regexp"
+	"testing"
+
+	"github.com/fzipi/go-ftw/utils"
+)
+
+func TestReadIDs(t *testing.T) {
+	filename, err := utils.CreateTempFileWithContent("# flaky tests\n942100-1\n\n  920100-3  \n#920100-4\n", "goftw-ids-*.txt")
+	if err != nil {
+		t.Fatalf("Failed!: %s\n", err.Error())
+	}
+	defer os.Remove(filename)
+
+	ids, err := ReadIDs(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 2 || ids[0] != "942100-1" || ids[1] != "920100-3" {
+		t.Errorf("Failed ! got ids %q", ids)
+	}
+
+	if _, err = ReadIDs("/nonexistent/ids.txt"); err == nil {
+		t.Errorf("Failed ! reading a missing file should fail")
+	}
+}
+
+func TestMatchAny(t *testing.T) {
+	for _, c := range []struct {
+		patterns []string
+		ids      []string
+		expected string
+	}{
+		{nil, nil, ""},
+		{[]string{""}, nil, ""},
+		{[]string{"91.*"}, nil, "91.*"},
+		{[]string{"91.*", "942100-.*"}, nil, "(?:91.*)|(?:942100-.*)"},
+		{[]string{"91.*"}, []string{"942100-1"}, "(?:91.*)|^942100-1$"},
+		{nil, []string{"942100-1", "a.b"}, `^942100-1$|^a\.b$`},
+	} {
+		if got, err := MatchAny(c.patterns, c.ids); err != nil || got != c.expected {
+			t.Errorf("Failed ! expected %q, got %q (%v) for %+v", c.expected, got, err, c)
+		}
+	}
+
+	// ids match whole titles only
+	expression, _ := MatchAny([]string{"^920"}, []string{"942100-1"})
+	re := regexp.MustCompile(expression)
+	for title, expected := range map[string]bool{
+		"920100-1":  true,
+		"942100-1":  true,
+		"942100-10": false,
+		"1942100-1": false,
+	} {
+		if re.MatchString(title) != expected {
+			t.Errorf("Failed ! %q matching %q should be %t", expression, title, expected)
+		}
+	}
+
+	if _, err := MatchAny([]string{"91.*", "(942"}, nil); err == nil {
+		t.Errorf("Failed ! a bad regexp should fail")
+	}
+}
+
+func TestMatchNone(t *testing.T) {
+	for _, title := range []string{"", "942100-1", "942100-7[3]"} {
+		if regexp.MustCompile(MatchNone).MatchString(title) {
+			t.Errorf("Failed ! %q should not match %q", MatchNone, title)
+		}
+		if reason := needToSkipTest(MatchNone, "", nil, []string{title}, nil, true); reason != SkipNotIncluded {
+			t.Errorf("Failed ! %q should not be included, got %q", title, reason)
+		}
+	}
+}
@@ -2,15 +2,17 @@ package runner
 
 import (
 	"errors"
+	"fmt"
+	"net"
+	"net/url"
 	"regexp"
 	"strconv"
-	"time"
+	"strings"
 
 	"github.com/fzipi/go-ftw/check"
 	"github.com/fzipi/go-ftw/config"
 	"github.com/fzipi/go-ftw/ftwhttp"
 	"github.com/fzipi/go-ftw/test"
-	"github.com/fzipi/go-ftw/utils"
 
 	"github.com/kyokomi/emoji"
 	"github.com/rs/zerolog/log"
@@ -21,20 +23,59 @@ import (
 // exclude is a regexp that matches the test name: e.g. "920*", excludes all tests starting with "920"
 // Returns error if some test failed
 func Run(include string, exclude string, showTime bool, output bool, ftwtests []test.FTWTest) int {
-	var testResult TestResult
+	return RunWithConfig(ftwtests, Config{
+		Include:  include,
+		Exclude:  exclude,
+		ShowTime: showTime,
+		Quiet:    output,
+	})
+}
+
+// RunWithConfig runs your tests using the options in the runner Config.
+// When a Handler is passed, requests never reach the network: the handler is called in-process.
+// Returns the number of failed tests
+func RunWithConfig(ftwtests []test.FTWTest, c Config) int {
 	var stats TestStats
-	var duration time.Duration
+
+	output := c.Quiet
 
 	printUnlessQuietMode(output, ":rocket:Running go-ftw!\n")
 
-	client := ftwhttp.NewClient()
+	client, err := NewClient(c)
+	if err != nil {
+		log.Fatal().Msgf("ftw/run: %s", err.Error())
+	}
+	if _, err = ParseTagExpression(c.Tags); err != nil {
+		log.Fatal().Msgf("ftw/run: %s", err.Error())
+	}
+
+	for _, vhost := range virtualHosts(c) {
+		if vhost != "" {
+			printUnlessQuietMode(output, ":globe_with_meridians:running tests against virtual host %s\n", vhost)
+		}
+		c.VirtualHost = vhost
+		runTests(client, c, ftwtests, &stats)
+	}
+
+	return printSummary(output, stats)
+}
+
+// runTests runs the tests once, adding the results to stats
+func runTests(client *ftwhttp.Client, c Config, ftwtests []test.FTWTest, stats *TestStats) {
+	output := c.Quiet
+	// the expression was checked before running any test
+	tagExpression, _ := ParseTagExpression(c.Tags)
 
 	for _, tests := range ftwtests {
 		changed := true
+		// parent is the test whose expanded cases are being run, so its title is shown once
+		parent := ""
 		for _, t := range tests.Tests {
+			tags := tests.TestTags(t)
 			// if we received a particular testid, skip until we find it
-			if needToSkipTest(include, exclude, t.TestTitle, tests.Meta.Enabled) {
-				addResultToStats(Skipped, t.TestTitle, &stats)
+			if reason := needToSkipTest(c.Include, c.Exclude, tagExpression, t.Titles(), tags, tests.Meta.Enabled); reason != NotSkipped {
+				log.Debug().Msgf("ftw/run: skipping %s: %s", t.TestTitle, reason)
+				addSkippedToStats(reason, resultTitle(c, t.TestTitle), stats)
 				continue
 			}
 			// this is just for printing once the next text
@@ -44,116 +85,276 @@ func Run(include string, exclude string, showTime bool, output bool, ftwtests []
 			}
 
 			// can we use goroutines here?
-			printUnlessQuietMode(output, "\trunning %s: ", t.TestTitle)
+			if t.ParentTitle == "" {
+				printTestTitle(output, t.TestTitle, tags)
+			} else {
+				// cases expanded from the same test are shown under it
+				if t.ParentTitle != parent {
+					printTestTitle(output, t.ParentTitle, tags)
+					printUnlessQuietMode(output, "\n")
+				}
+				printUnlessQuietMode(output, "\t\tcase %s: ", strings.TrimPrefix(t.TestTitle, t.ParentTitle))
+			}
+			parent = t.ParentTitle
 			// Iterate over stages
-			for _, stage := range t.Stages {
-				// Apply global overrides initially
-				testRequest := stage.Stage.Input
-				err := applyInputOverride(&testRequest)
+			for i, stage := range t.Stages {
+				client.CaptureComment = fmt.Sprintf("test %s, stage %d", resultTitle(c, t.TestTitle), i+1)
+				result, err := RunStage(client, c, t.TestTitle, stage.Stage)
 				if err != nil {
-					log.Debug().Msgf("ftw/run: problem overriding input: %s", err.Error())
-				}
-				expectedOutput := stage.Stage.Output
-
-				// Check sanity first
-				if checkTestSanity(testRequest) {
-					log.Fatal().Msgf("ftw/run: bad test: choose between data, encoded_request, or raw_request")
+					log.Fatal().Msgf("ftw/run: %s", err.Error())
 				}
 
-				// Create a new check
-				ftwcheck := check.NewCheck(config.FTWConfig)
+				addResultToStats(result.Result, resultTitle(c, t.TestTitle), stats)
 
-				// Do not even run test if result is overriden. Just use the override.
-				if overriden := overridenTestResult(ftwcheck, t.TestTitle); overriden != Failed {
-					addResultToStats(overriden, t.TestTitle, &stats)
+				// overriden results are not run
+				if result.Result == Ignored || result.Result == ForcePass || result.Result == ForceFail {
 					continue
 				}
 
-				var req *ftwhttp.Request
+				// show the result unless quiet was passed in the command line
+				displayResult(output, c.ShowTime, result)
 
-				// Destination is needed for an request
-				dest := &ftwhttp.Destination{
-					DestAddr: testRequest.GetDestAddr(),
-					Port:     testRequest.GetPort(),
-					Protocol: testRequest.GetProtocol(),
+				stats.Run++
+				stats.RunTime += result.Duration
+				if result.Timing != nil {
+					stats.TimeToFirstByte += result.Timing.TimeToFirstByte()
 				}
+			}
+		}
+	}
+}
 
-				err = client.NewConnection(*dest)
+// printTestTitle shows the test that is running, with its tags if any
+func printTestTitle(quiet bool, title string, tags []string) {
+	if len(tags) > 0 {
+		printUnlessQuietMode(quiet, "\trunning %s [%s]: ", title, strings.Join(tags, ", "))
+	} else {
+		printUnlessQuietMode(quiet, "\trunning %s: ", title)
+	}
+}
 
-				if err != nil && !expectedOutput.ExpectError {
-					log.Fatal().Msgf("ftw/run: can't connect to destination %+v - unexpected error found. Is your waf running?", dest)
-				}
+// virtualHosts returns the virtual hosts used for running the tests. There is always
+// at least one: the empty string means no virtual host.
+func virtualHosts(c Config) []string {
+	vhosts := c.VirtualHosts
+	if len(vhosts) == 0 {
+		vhosts = config.FTWConfig.VirtualHosts
+	}
+	if len(vhosts) == 0 {
+		return []string{""}
+	}
+	return vhosts
+}
 
-				req = getRequestFromTest(testRequest)
+// resultTitle is the test title used in the stats, with the virtual host if any
+func resultTitle(c Config, title string) string {
+	if c.VirtualHost == "" {
+		return title
+	}
+	return fmt.Sprintf("%s@%s", title, c.VirtualHost)
+}
 
-				client.StartTrackingTime()
+// NewClient creates the client used for sending the requests, using the proxy from the
+// global config and the Handler from the runner Config, if any
+func NewClient(c Config) (*ftwhttp.Client, error) {
+	// allow using the runner from go code without loading a config first
+	if config.FTWConfig == nil {
+		config.FTWConfig = &config.FTWConfiguration{}
+	}
 
-				response, err := client.Do(*req)
+	var err error
+	client := ftwhttp.NewClient()
+	if err = applyProxyConfig(client); err != nil {
+		return nil, fmt.Errorf("bad proxy in config: %w", err)
+	}
+	if client.Resolve, err = ftwhttp.ParseResolve(config.FTWConfig.Resolve); err != nil {
+		return nil, fmt.Errorf("bad resolve in config: %w", err)
+	}
+	client.Capture = c.Capture
+	if c.Handler != nil {
+		client.Dial = ftwhttp.HandlerDialer(c.Handler)
+	}
 
-				client.StopTrackingTime()
+	return client, nil
+}
 
-				ftwcheck.SetRoundTripTime(client.GetRoundTripTime().StartTime(), client.GetRoundTripTime().StopTime())
+// RunStage sends the request from the stage using the client, and checks the response against the expected output.
+// An error is returned only when the stage cannot be run at all, like when the test is not sane or
+// the destination cannot be reached and no error was expected.
+func RunStage(client *ftwhttp.Client, c Config, testTitle string, stage test.StageData) (StageResult, error) {
+	var result StageResult
 
-				// Set expected test output in check
-				ftwcheck.SetExpectTestOutput(&expectedOutput)
+	// Apply global overrides initially
+	testRequest := stage.Input
+	err := applyInputOverride(&testRequest)
+	if err != nil {
+		log.Debug().Msgf("ftw/run: problem overriding input: %s", err.Error())
+	}
+	if c.VirtualHost != "" {
+		applyVirtualHost(&testRequest, c.VirtualHost)
+	}
+	expectedOutput := stage.Output
 
-				// now get the test result based on output
-				testResult = checkResult(ftwcheck, response, err)
+	result.Position = stage.Position
 
-				duration = client.GetRoundTripTime().RoundTripDuration()
+	// Check sanity first
+	if err = testRequest.Validate(); err != nil {
+		return result, badTest(testRequest.Position, err)
+	}
+	if err = expectedOutput.Validate(); err != nil {
+		return result, badTest(expectedOutput.Position, err)
+	}
 
-				addResultToStats(testResult, t.TestTitle, &stats)
+	// Create a new check
+	ftwcheck := check.NewCheck(config.FTWConfig)
+	if c.LogSink != nil {
+		ftwcheck.SetLogSink(c.LogSink)
+	}
 
-				// show the result unless quiet was passed in the command line
-				displayResult(output, testResult, duration)
+	// Do not even run test if result is overriden. Just use the override.
+	if overriden := overridenTestResult(ftwcheck, testTitle); overriden != Failed {
+		result.Result = overriden
+		return result, nil
+	}
 
-				stats.Run++
-				stats.RunTime += duration
-			}
+	// Set expected test output in check
+	ftwcheck.SetExpectTestOutput(&expectedOutput)
+
+	// The body must be read before connecting, so a test that cannot be sent is reported instead of run
+	req, err := getRequestFromTest(testRequest)
+	if err != nil {
+		return result, badTest(testRequest.Position, err)
+	}
+
+	// Destination is needed for an request
+	dest := getDestinationFromTest(testRequest)
+	if c.Handler != nil {
+		// the handler is called directly, there is no TLS
+		dest.Protocol = "http"
+	}
+	if c.VirtualHost != "" {
+		dest.ServerName = virtualHostName(c.VirtualHost)
+	}
+	if testRequest.GRPC != nil {
+		dest.NextProtos = []string{ftwhttp.GRPCProto}
+	}
+
+	err = client.NewConnection(*dest)
+	if err != nil {
+		if !expectedOutput.ExpectError.Expected() {
+			return result, fmt.Errorf("can't connect to destination %+v - unexpected error found. Is your waf running?", dest)
 		}
+		result.Result, result.Explanation = checkResult(ftwcheck, nil, err)
+		return result, nil
 	}
 
-	return printSummary(output, stats)
+	client.StartTrackingTime()
+
+	var response *ftwhttp.Response
+	switch {
+	case testRequest.GRPC != nil:
+		response, err = client.DoGRPC(grpcAuthority(testRequest, dest), testRequest.GRPC)
+	case testRequest.WebSocket != nil:
+		response, err = client.DoWebSocket(*req, testRequest.WebSocket)
+	default:
+		response, err = client.Do(*req)
+	}
+
+	client.StopTrackingTime()
+
+	// the request could not be built, so it was never sent
+	var buildErr *ftwhttp.BuildError
+	if errors.As(err, &buildErr) {
+		return result, badTest(testRequest.Position, buildErr.Err)
+	}
+
+	ftwcheck.SetRoundTripTime(client.GetRoundTripTime().StartTime(), client.GetRoundTripTime().StopTime())
+
+	// now get the test result based on output
+	result.Result, result.Explanation = checkResult(ftwcheck, response, err)
+
+	result.Duration = client.GetRoundTripTime().RoundTripDuration()
+	result.Timing = client.GetRoundTripTime()
+
+	return result, nil
 }
 
-func needToSkipTest(include string, exclude string, title string, skip bool) bool {
-	result := false
-	// if we need to exclude tests, and the title matches,
+// badTest is the error for tests that cannot be run, with the position of the problem when known
+func badTest(position test.Position, err error) error {
+	if position.Line == 0 {
+		return fmt.Errorf("bad test: %w", err)
+	}
+	return fmt.Errorf("bad test at %s: %w", position, err)
+}
+
+// SkipReason tells why a test was not run
+type SkipReason string
+
+// Reasons for skipping tests
+const (
+	// NotSkipped is used for tests that are run
+	NotSkipped SkipReason = ""
+	// SkipDisabled is used for tests in files that are not enabled in their meta
+	SkipDisabled SkipReason = "disabled in meta"
+	// SkipExcluded is used for tests matching the exclude regexp
+	SkipExcluded SkipReason = "excluded by pattern"
+	// SkipNotIncluded is used for tests not matching the include regexp
+	SkipNotIncluded SkipReason = "not included"
+	// SkipTags is used for tests whose tags do not match the tag expression
+	SkipTags SkipReason = "filtered by tag"
+)
+
+// needToSkipTest returns why the test must not be run, or NotSkipped. The titles are the title of the test,
+// and of the test it was expanded from, so both select it.
+func needToSkipTest(include string, exclude string, tags *TagExpression, titles []string, testTags []string, enabled bool) SkipReason {
+	// if the test itself is disabled, needs to be skipped
+	if !enabled {
+		return SkipDisabled
+	}
+
+	// if we need to exclude tests, and a title matches,
 	// it needs to be skipped
-	if exclude != "" {
-		ok, err := regexp.MatchString(exclude, title)
-		if ok && err == nil {
-			result = true
-		}
+	if exclude != "" && matchesTitle(exclude, titles) {
+		return SkipExcluded
 	}
 
-	// if we need to include tests, but the title does not match
+	// if we need to include tests, but no title matches
 	// it needs to be skipped
-	if include != "" {
-		ok, err := regexp.MatchString(include, title)
-		if !ok && err == nil {
-			result = true
+	if include != "" && !matchesTitle(include, titles) {
+		if _, err := regexp.Compile(include); err == nil {
+			return SkipNotIncluded
 		}
 	}
 
-	// if the test itself is disabled, needs to be skipped
-	if !skip {
-		result = true
+	if tags != nil && !tags.Match(testTags) {
+		return SkipTags
 	}
-	return result
+	return NotSkipped
 }
 
-func checkTestSanity(testRequest test.Input) bool {
-	return (utils.IsNotEmpty(testRequest.Data) && testRequest.EncodedRequest != "") ||
-		(utils.IsNotEmpty(testRequest.Data) && testRequest.RAWRequest != "") ||
-		(testRequest.EncodedRequest != "" && testRequest.RAWRequest != "")
+// matchesTitle returns true if the regexp matches any of the titles
+func matchesTitle(expression string, titles []string) bool {
+	for _, title := range titles {
+		if ok, err := regexp.MatchString(expression, title); ok && err == nil {
+			return true
+		}
+	}
+	return false
 }
 
-func displayResult(quiet bool, result TestResult, duration time.Duration) {
-	switch result {
+// displayResult shows the result of a stage. If showTime is true, the time spent in each phase is also shown.
+func displayResult(quiet bool, showTime bool, result StageResult) {
+	duration := result.Duration.String()
+	if showTime && result.Timing != nil {
+		duration = fmt.Sprintf("%s (%s)", duration, result.Timing.Phases())
+	}
+	switch result.Result {
 	case Success:
 		printUnlessQuietMode(quiet, ":check_mark:passed in %s\n", duration)
 	case Failed:
+		if result.Position.Line > 0 {
+			duration = fmt.Sprintf("%s at %s", duration, result.Position)
+		}
 		printUnlessQuietMode(quiet, ":collision:failed in %s\n", duration)
 	case Ignored:
 		printUnlessQuietMode(quiet, ":equal:test result ignored in %s\n", duration)
@@ -178,16 +379,17 @@ func overridenTestResult(c *check.FTWCheck, id string) TestResult {
 	return Failed
 }
 
-// checkResult has the logic for verifying the result for the test sent
-func checkResult(c *check.FTWCheck, response *ftwhttp.Response, responseError error) TestResult {
+// checkResult has the logic for verifying the result for the test sent.
+// When the test fails, it also returns an explanation of what was expected.
+func checkResult(c *check.FTWCheck, response *ftwhttp.Response, responseError error) (TestResult, string) {
 	// Request might return an error, but it could be expected, we check that first
 	if responseError != nil && c.AssertExpectError(responseError) {
-		return Success
+		return Success, ""
 	}
 
 	// If there was no error, perform the remaining checks
 	if responseError != nil {
-		return Failed
+		return Failed, c.ExplainError(responseError)
 	}
 	if c.CloudMode() {
 		// Cloud mode assumes that we cannot read logs. So we rely entirely on status code
@@ -196,35 +398,75 @@ func checkResult(c *check.FTWCheck, response *ftwhttp.Response, responseError er
 
 	// If we didn't expect an error, check the actual response from the waf
 	if c.AssertStatus(response.Parsed.StatusCode) {
-		return Success
+		return Success, ""
 	}
 	// Check response
 	if c.AssertResponseContains(response.GetBodyAsString()) {
-		return Success
+		return Success, ""
+	}
+	// Check grpc status
+	if c.AssertGRPCStatus(response) {
+		return Success, ""
+	}
+	// Check websocket messages
+	if c.AssertWebSocket(response.WebSocket) {
+		return Success, ""
 	}
 	// Lastly, check logs
 	if c.AssertLogContains() {
-		return Success
+		return Success, ""
 	}
 	// We assume that the they were already setup, for comparing
 	if c.AssertNoLogContains() {
-		return Success
+		return Success, ""
 	}
 
-	return Failed
+	return Failed, c.Explain(response.Parsed.StatusCode)
 }
 
-func getRequestFromTest(testRequest test.Input) *ftwhttp.Request {
+// grpcAuthority returns the authority for gRPC calls: the Host header, or the destination
+func grpcAuthority(testRequest test.Input, dest *ftwhttp.Destination) string {
+	if host := testRequest.Headers.Get("Host"); host != "" {
+		return host
+	}
+	if dest.Network == ftwhttp.UnixNetwork {
+		return "localhost"
+	}
+	return net.JoinHostPort(dest.DestAddr, strconv.Itoa(dest.Port))
+}
+
+// getDestinationFromTest returns the destination for the test. A `dest_addr` like
+// `unix:///run/waf.sock` makes the request go through the unix socket instead.
+func getDestinationFromTest(testRequest test.Input) *ftwhttp.Destination {
+	destAddr := testRequest.GetDestAddr()
+	if strings.HasPrefix(destAddr, ftwhttp.UnixNetwork+"://") {
+		dest, err := ftwhttp.DestinationFromString(destAddr)
+		if err == nil {
+			return dest
+		}
+		log.Error().Msgf("ftw/run: bad unix socket destination %s: %s", destAddr, err.Error())
+	}
+
+	return &ftwhttp.Destination{
+		// IPv6 addresses can be written as `[::1]` in tests
+		DestAddr: strings.Trim(destAddr, "[]"),
+		Port:     testRequest.GetPort(),
+		Protocol: testRequest.GetProtocol(),
+		Network:  ftwhttp.TCPNetwork,
+	}
+}
+
+func getRequestFromTest(testRequest test.Input) (*ftwhttp.Request, error) {
 	var req *ftwhttp.Request
 	// get raw request, if anything
 	raw, err := testRequest.GetRawRequest()
 	if err != nil {
-		log.Error().Msgf("ftw/run: error getting raw data: %s\n", err.Error())
+		return nil, fmt.Errorf("cannot decode encoded_request: %w", err)
 	}
 
 	// If we use raw or encoded request, then we don't use other fields
 	if raw != nil {
-		req = ftwhttp.NewRawRequest(raw, !testRequest.StopMagic)
+		req = ftwhttp.NewRawRequest(raw, !testRequest.GetStopMagic())
 	} else {
 		rline := &ftwhttp.RequestLine{
 			Method:  testRequest.GetMethod(),
@@ -232,13 +474,33 @@ func getRequestFromTest(testRequest test.Input) *ftwhttp.Request {
 			Version: testRequest.GetVersion(),
 		}
 
-		data := testRequest.ParseData()
-		// create a new request
-		req = ftwhttp.NewRequest(rline, testRequest.Headers,
-			data, !testRequest.StopMagic)
+		exactData, err := testRequest.GetExactData()
+		if err != nil {
+			return nil, fmt.Errorf("cannot read body: %w", err)
+		}
 
+		if exactData != nil {
+			req = ftwhttp.NewRequest(rline, testRequest.Headers, nil, !testRequest.GetStopMagic())
+			req.SetExactData(exactData)
+		} else {
+			data := testRequest.ParseData()
+			// create a new request
+			req = ftwhttp.NewRequest(rline, testRequest.Headers,
+				data, !testRequest.GetStopMagic())
+		}
+
+	}
+	if testRequest.Chunked != nil && req.RawData() == nil {
+		req.SetChunkedBody(testRequest.Chunked)
+	}
+	if testRequest.Multipart != nil && req.RawData() == nil {
+		req.SetMultipartBody(testRequest.Multipart)
 	}
-	return req
+	if testRequest.Compression != "" && req.RawData() == nil {
+		req.SetCompression(testRequest.Compression)
+	}
+	req.SetTransmission(testRequest.Transmission)
+	return req, nil
 }
 
 // We want to have output unless we are in quiet mode
@@ -248,6 +510,43 @@ func printUnlessQuietMode(quiet bool, format string, a ...interface{}) {
 	}
 }
 
+// applyProxyConfig sets the proxy from the config in the client, if there is one.
+// Otherwise the client keeps using the proxy from the environment.
+func applyProxyConfig(client *ftwhttp.Client) error {
+	if config.FTWConfig.Proxy == "" {
+		return nil
+	}
+	proxyURL, err := url.Parse(config.FTWConfig.Proxy)
+	if err != nil {
+		return err
+	}
+	client.Proxy = ftwhttp.ProxyURL(proxyURL)
+	return nil
+}
+
+// applyVirtualHost replaces the Host header in the test. Raw requests are sent as they are.
+func applyVirtualHost(testRequest *test.Input, vhost string) {
+	headers := testRequest.Headers.Clone()
+	if headers == nil {
+		headers = make(ftwhttp.Header)
+	}
+	for name := range headers {
+		if strings.EqualFold(name, "Host") {
+			headers.Del(name)
+		}
+	}
+	headers.Set("Host", vhost)
+	testRequest.Headers = headers
+}
+
+// virtualHostName returns the virtual host without the port, as used for SNI
+func virtualHostName(vhost string) string {
+	if host, _, err := net.SplitHostPort(vhost); err == nil {
+		return host
+	}
+	return strings.Trim(vhost, "[]")
+}
+
 // applyInputOverride will check if config had global overrides and write that into the test.
 func applyInputOverride(testRequest *test.Input) error {
 	var retErr error
@@ -1,10 +1,12 @@
 package runner
 
 import (
+	"bytes"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"testing"
@@ -13,6 +15,7 @@ import (
 	"github.com/fzipi/go-ftw/ftwhttp"
 	"github.com/fzipi/go-ftw/test"
 	"github.com/fzipi/go-ftw/utils"
+	"github.com/fzipi/go-ftw/waflog"
 )
 
 var yamlConfig = `
@@ -523,3 +526,549 @@ func TestFailedTestsRun(t *testing.T) {
 	os.Remove(logName)
 	os.Remove(filename)
 }
+
+func TestApplyProxyConfig(t *testing.T) {
+	err := config.NewConfigFromString(yamlConfig)
+	if err != nil {
+		t.Errorf("Failed!")
+	}
+	config.FTWConfig.Proxy = "http://proxy.example.com:3128"
+
+	client := ftwhttp.NewClient()
+	if err = applyProxyConfig(client); err != nil {
+		t.Fatalf("Failed!: %s\n", err.Error())
+	}
+
+	proxyURL, err := client.Proxy(ftwhttp.Destination{DestAddr: "localhost", Port: 80, Protocol: "http"})
+	if err != nil || proxyURL == nil || proxyURL.Host != "proxy.example.com:3128" {
+		t.Errorf("Proxy from config was not applied: %v", proxyURL)
+	}
+
+	config.FTWConfig.Proxy = "http://bad proxy:%%"
+	if err = applyProxyConfig(client); err == nil {
+		t.Error("Bad proxy URL should error")
+	}
+}
+
+func TestGetDestinationFromTest(t *testing.T) {
+	socket := "unix:///run/waf.sock"
+	dest := getDestinationFromTest(test.Input{DestAddr: &socket})
+	if dest.Network != ftwhttp.UnixNetwork || dest.DestAddr != "/run/waf.sock" {
+		t.Errorf("Wrong unix destination: %+v", dest)
+	}
+
+	addr := "127.0.0.1"
+	port := 8080
+	dest = getDestinationFromTest(test.Input{DestAddr: &addr, Port: &port})
+	if dest.Network != ftwhttp.TCPNetwork || dest.DestAddr != addr || dest.Port != port || dest.Protocol != "http" {
+		t.Errorf("Wrong tcp destination: %+v", dest)
+	}
+
+	addr = "[::1]"
+	dest = getDestinationFromTest(test.Input{DestAddr: &addr, Port: &port})
+	if dest.DestAddr != "::1" {
+		t.Errorf("Wrong IPv6 destination: %+v", dest)
+	}
+}
+
+var yamlTestHandler = `---
+meta:
+  author: "tester"
+  enabled: true
+  name: "gotest-ftw.yaml"
+  description: "Example Test"
+tests:
+  - test_title: "300"
+    stages:
+      - stage:
+          input:
+            dest_addr: "waf.example.com"
+            protocol: "https"
+            port: 443
+            uri: "/?q=attack"
+            headers:
+              User-Agent: "ModSecurity CRS 3 Tests"
+              Host: "localhost"
+          output:
+            log_contains: id \"949110\"
+  - test_title: "301"
+    stages:
+      - stage:
+          input:
+            dest_addr: "waf.example.com"
+            uri: "/?q=hello"
+            headers:
+              User-Agent: "ModSecurity CRS 3 Tests"
+              Host: "localhost"
+          output:
+            no_log_contains: id \"949110\"
+  - test_title: "302"
+    stages:
+      - stage:
+          input:
+            dest_addr: "waf.example.com"
+            uri: "/?q=attack"
+            headers:
+              User-Agent: "ModSecurity CRS 3 Tests"
+              Host: "localhost"
+          output:
+            status: [403]
+`
+
+func TestRunWithHandler(t *testing.T) {
+	config.FTWConfig = nil
+	sink := waflog.NewMemorySink()
+
+	// a tiny waf: block anything with "attack" in the query string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.RawQuery, "attack") {
+			sink.WriteLine(`ModSecurity: Access denied with code 403 [id "949110"]`)
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		_, _ = w.Write([]byte("Hello, client"))
+	})
+
+	filename, err := utils.CreateTempFileWithContent(yamlTestHandler, "goftw-test-*.yaml")
+	if err != nil {
+		t.Fatalf("Failed!: %s\n", err.Error())
+	}
+	defer os.Remove(filename)
+
+	tests, err := test.GetTestsFromFiles(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res := RunWithConfig(tests, Config{Quiet: true, Handler: handler, LogSink: sink}); res > 0 {
+		t.Errorf("Oops, %d tests failed to run!", res)
+	}
+}
+
+func TestRunStageExplainsFailure(t *testing.T) {
+	config.FTWConfig = nil
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("Hello, client"))
+	})
+	c := Config{Quiet: true, Handler: handler}
+
+	client, err := NewClient(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	addr := "waf.example.com"
+	stage := test.StageData{
+		Input:  test.Input{DestAddr: &addr},
+		Output: test.Output{Status: []int{403}},
+	}
+
+	result, err := RunStage(client, c, "999", stage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Result != Failed || result.Explanation != "expected status in [403], got 200" {
+		t.Errorf("Unexpected result %+v", result)
+	}
+}
+
+func TestRunStageBadTestPosition(t *testing.T) {
+	config.FTWConfig = nil
+	c := Config{Quiet: true, Handler: http.NotFoundHandler()}
+	client, err := NewClient(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stage := test.StageData{
+		Output: test.Output{Status: []int{999}, Position: test.Position{File: "tests/999.yaml", Line: 12, Column: 11}},
+	}
+	_, err = RunStage(client, c, "999", stage)
+	if err == nil || !strings.HasPrefix(err.Error(), "bad test at tests/999.yaml:12:11: ") {
+		t.Errorf("Failed ! error should show the position: %v", err)
+	}
+}
+
+func TestRunStageMissingMultipartFile(t *testing.T) {
+	config.FTWConfig = nil
+	c := Config{Quiet: true, Handler: http.NotFoundHandler()}
+	client, err := NewClient(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stage := test.StageData{
+		Input: test.Input{Multipart: &ftwhttp.MultipartBody{Parts: []ftwhttp.Part{
+			{Name: "f", File: filepath.Join(t.TempDir(), "missing.txt")},
+		}}},
+		Output: test.Output{Status: []int{200}},
+	}
+	if _, err = RunStage(client, c, "999", stage); err == nil || !strings.HasPrefix(err.Error(), "bad test: ") {
+		t.Errorf("Failed ! a missing file should be a bad test: %v", err)
+	}
+}
+
+func TestRunStageUnreadableData(t *testing.T) {
+	config.FTWConfig = nil
+	sent := false
+	c := Config{Quiet: true, Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sent = true
+	})}
+	client, err := NewClient(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a directory passes the check for data_file, but cannot be read
+	stage := test.StageData{
+		Input:  test.Input{DataFile: t.TempDir()},
+		Output: test.Output{Status: []int{200}},
+	}
+	if _, err = RunStage(client, c, "999", stage); err == nil || !strings.Contains(err.Error(), "cannot read body") {
+		t.Errorf("Failed ! an unreadable body should be a stage error: %v", err)
+	}
+	if sent {
+		t.Errorf("Failed ! the request should not be sent without its body")
+	}
+}
+
+func TestGRPCAuthority(t *testing.T) {
+	dest := &ftwhttp.Destination{DestAddr: "::1", Port: 50051, Network: ftwhttp.TCPNetwork}
+
+	if authority := grpcAuthority(test.Input{}, dest); authority != "[::1]:50051" {
+		t.Errorf("Failed ! got %s", authority)
+	}
+	if authority := grpcAuthority(test.Input{Headers: ftwhttp.Header{"Host": "grpc.example.com"}}, dest); authority != "grpc.example.com" {
+		t.Errorf("Failed ! got %s", authority)
+	}
+}
+
+var yamlTestVirtualHosts = `---
+meta:
+  author: "tester"
+  enabled: true
+  name: "gotest-ftw.yaml"
+  description: "Example Test"
+tests:
+  - test_title: "303"
+    stages:
+      - stage:
+          input:
+            dest_addr: "waf.example.com"
+            uri: "/?q=attack"
+            headers:
+              host: "localhost"
+          output:
+            status: [403]
+`
+
+func TestRunWithVirtualHosts(t *testing.T) {
+	config.FTWConfig = nil
+	var hosts []string
+
+	// only the "blocking.example.com" virtual host has rules
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hosts = append(hosts, r.Host)
+		if r.Host == "blocking.example.com" && strings.Contains(r.URL.RawQuery, "attack") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		_, _ = w.Write([]byte("Hello, client"))
+	})
+
+	filename, err := utils.CreateTempFileWithContent(yamlTestVirtualHosts, "goftw-test-*.yaml")
+	if err != nil {
+		t.Fatalf("Failed!: %s\n", err.Error())
+	}
+	defer os.Remove(filename)
+
+	tests, err := test.GetTestsFromFiles(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := Config{Quiet: true, Handler: handler, VirtualHosts: []string{"blocking.example.com", "open.example.com:8080"}}
+	if res := RunWithConfig(tests, c); res != 1 {
+		t.Errorf("Failed ! expected 1 failed test, got %d", res)
+	}
+	if len(hosts) != 2 || hosts[0] != "blocking.example.com" || hosts[1] != "open.example.com:8080" {
+		t.Errorf("Failed ! got hosts %v", hosts)
+	}
+	if tests[0].Tests[0].Stages[0].Stage.Input.Headers["host"] != "localhost" {
+		t.Errorf("Failed ! virtual hosts should not change the test")
+	}
+}
+
+var yamlTestTags = `---
+meta:
+  author: "tester"
+  enabled: true
+  name: "gotest-ftw.yaml"
+  tags: [sqli]
+tests:
+  - test_title: "401"
+    tags: [pl1]
+    stages:
+      - stage:
+          input:
+            dest_addr: "waf.example.com"
+            uri: "/?q=attack"
+          output:
+            status: [403]
+  - test_title: "402"
+    tags: [pl2, slow]
+    stages:
+      - stage:
+          input:
+            dest_addr: "waf.example.com"
+            uri: "/?q=attack"
+          output:
+            status: [403]
+`
+
+func TestRunWithTags(t *testing.T) {
+	config.FTWConfig = nil
+	// the waf blocks nothing, so every test run fails
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("Hello, client"))
+	})
+
+	filename, err := utils.CreateTempFileWithContent(yamlTestTags, "goftw-test-*.yaml")
+	if err != nil {
+		t.Fatalf("Failed!: %s\n", err.Error())
+	}
+	defer os.Remove(filename)
+
+	tests, err := test.GetTestsFromFiles(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for expression, expected := range map[string]int{
+		"":                    2,
+		"sqli":                2,
+		"sqli && !slow":       1,
+		"pl1 || pl2":          2,
+		"xss":                 0,
+		"sqli && pl2 && !pl1": 1,
+	} {
+		c := Config{Quiet: true, Handler: handler, Tags: expression}
+		if res := RunWithConfig(tests, c); res != expected {
+			t.Errorf("Failed ! expected %d failed tests using %q, got %d", expected, expression, res)
+		}
+	}
+}
+
+func TestRunIncludeAndExclude(t *testing.T) {
+	config.FTWConfig = nil
+	// the waf blocks nothing, so every test run fails
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("Hello, client"))
+	})
+
+	filename, err := utils.CreateTempFileWithContent(yamlTestTags, "goftw-test-*.yaml")
+	if err != nil {
+		t.Fatalf("Failed!: %s\n", err.Error())
+	}
+	defer os.Remove(filename)
+
+	tests, err := test.GetTestsFromFiles(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := Config{Quiet: true, Handler: handler, Include: "^40", Exclude: "^402$"}
+	client, err := NewClient(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stats TestStats
+	runTests(client, c, tests, &stats)
+	if len(stats.Failed) != 1 || stats.Failed[0] != "401" {
+		t.Errorf("Failed ! expected only 401 to run, got %q", stats.Failed)
+	}
+	if len(stats.Skipped) != 1 || stats.SkipReasons[SkipExcluded] != 1 {
+		t.Errorf("Failed ! expected 402 to be excluded, got %v", stats.SkipReasons)
+	}
+}
+
+var yamlTestParameters = `---
+meta:
+  author: "tester"
+  enabled: true
+  name: "gotest-ftw.yaml"
+tests:
+  - test_title: "501"
+    parameters:
+      - name: payload
+        values: ["attack", "harmless", "another attack"]
+    stages:
+      - stage:
+          input:
+            dest_addr: "waf.example.com"
+            uri: "/?q={{ .payload | urlquery }}"
+          output:
+            status: [403]
+`
+
+func TestRunWithParameters(t *testing.T) {
+	config.FTWConfig = nil
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Query().Get("q"), "attack") {
+			w.WriteHeader(http.StatusForbidden)
+		}
+	})
+
+	filename, err := utils.CreateTempFileWithContent(yamlTestParameters, "goftw-test-*.yaml")
+	if err != nil {
+		t.Fatalf("Failed!: %s\n", err.Error())
+	}
+	defer os.Remove(filename)
+
+	tests, err := test.GetTestsFromFiles(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := Config{Quiet: true, Handler: handler}
+	client, err := NewClient(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stats TestStats
+	runTests(client, c, tests, &stats)
+	if stats.Success != 2 || len(stats.Failed) != 1 || stats.Failed[0] != "501[2]" {
+		t.Errorf("Failed ! expected only 501[2] to fail, got %q", stats.Failed)
+	}
+}
+
+func TestRunWithParametersFromIDFiles(t *testing.T) {
+	config.FTWConfig = nil
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Query().Get("q"), "attack") {
+			w.WriteHeader(http.StatusForbidden)
+		}
+	})
+
+	filename, err := utils.CreateTempFileWithContent(yamlTestParameters, "goftw-test-*.yaml")
+	if err != nil {
+		t.Fatalf("Failed!: %s\n", err.Error())
+	}
+	defer os.Remove(filename)
+
+	tests, err := test.GetTestsFromFiles(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		includes string
+		excludes string
+		run      int
+		skipped  int
+	}{
+		// the id of the test selects all its cases
+		{"501\n", "", 3, 0},
+		{"", "501\n", 0, 3},
+		// and the id of a case only that case
+		{"501[2]\n", "", 1, 2},
+		{"501\n", "501[2]\n", 2, 1},
+	} {
+		selection := func(content string) string {
+			if content == "" {
+				return ""
+			}
+			idFile, err := utils.CreateTempFileWithContent(content, "goftw-ids-*.txt")
+			if err != nil {
+				t.Fatalf("Failed!: %s\n", err.Error())
+			}
+			defer os.Remove(idFile)
+			ids, err := ReadIDs(idFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			expression, err := MatchAny(nil, ids)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return expression
+		}
+
+		conf := Config{Quiet: true, Handler: handler, Include: selection(c.includes), Exclude: selection(c.excludes)}
+		client, err := NewClient(conf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var stats TestStats
+		runTests(client, conf, tests, &stats)
+		if run := stats.Success + len(stats.Failed); run != c.run || len(stats.Skipped) != c.skipped {
+			t.Errorf("Failed ! including %q and excluding %q ran %d and skipped %q", c.includes, c.excludes, run, stats.Skipped)
+		}
+	}
+}
+
+func TestVirtualHostName(t *testing.T) {
+	for vhost, expected := range map[string]string{
+		"app.example.com":      "app.example.com",
+		"app.example.com:8443": "app.example.com",
+		"[::1]:8443":           "::1",
+	} {
+		if name := virtualHostName(vhost); name != expected {
+			t.Errorf("Failed ! %s should be %s, got %s", vhost, expected, name)
+		}
+	}
+}
+
+func TestNewClientResolve(t *testing.T) {
+	config.FTWConfig = &config.FTWConfiguration{Resolve: []string{"app.example.com:443:127.0.0.1"}}
+	client, err := NewClient(Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if address := client.Resolve.Address("app.example.com", 443); address != "127.0.0.1:443" {
+		t.Errorf("Failed ! got %s", address)
+	}
+
+	config.FTWConfig = &config.FTWConfiguration{Resolve: []string{"app.example.com"}}
+	if _, err = NewClient(Config{}); err == nil {
+		t.Errorf("Failed ! bad resolve entry should error")
+	}
+	config.FTWConfig = nil
+}
+
+func TestRunWithCapture(t *testing.T) {
+	config.FTWConfig = nil
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	})
+
+	filename, err := utils.CreateTempFileWithContent(yamlTestVirtualHosts, "goftw-test-*.yaml")
+	if err != nil {
+		t.Fatalf("Failed!: %s\n", err.Error())
+	}
+	defer os.Remove(filename)
+
+	tests, err := test.GetTestsFromFiles(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var file bytes.Buffer
+	capture, err := ftwhttp.NewCapture(&file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res := RunWithConfig(tests, Config{Quiet: true, Handler: handler, Capture: capture, VirtualHosts: []string{"app.example.com"}}); res > 0 {
+		t.Errorf("Oops, %d tests failed to run!", res)
+	}
+	if err = capture.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Contains(file.Bytes(), []byte("test 303@app.example.com, stage 1")) {
+		t.Errorf("Failed ! the capture should have the test title")
+	}
+	if !bytes.Contains(file.Bytes(), []byte("GET /?q=attack HTTP/1.1")) {
+		t.Errorf("Failed ! the capture should have the request")
+	}
+}
@@ -1,6 +1,9 @@
 package runner
 
 import (
+	"fmt"
+	"regexp"
+	"strings"
 	"time"
 
 	"github.com/kyokomi/emoji"
@@ -22,14 +25,18 @@ const (
 
 // TestStats accumulates test statistics
 type TestStats struct {
-	Run        int
-	Failed     []string
-	Skipped    []string
-	Ignored    []string
-	ForcedPass []string
-	ForcedFail []string
-	Success    int
-	RunTime    time.Duration
+	Run     int
+	Failed  []string
+	Skipped []string
+	// SkipReasons counts the skipped tests by the reason they were skipped
+	SkipReasons map[SkipReason]int
+	Ignored     []string
+	ForcedPass  []string
+	ForcedFail  []string
+	Success     int
+	RunTime     time.Duration
+	// TimeToFirstByte adds the time the WAF took to start answering in each test
+	TimeToFirstByte time.Duration
 }
 
 func addResultToStats(result TestResult, title string, stats *TestStats) {
@@ -51,13 +58,71 @@ func addResultToStats(result TestResult, title string, stats *TestStats) {
 	}
 }
 
+// addSkippedToStats adds a skipped test, counting the reason
+func addSkippedToStats(reason SkipReason, title string, stats *TestStats) {
+	addResultToStats(Skipped, title, stats)
+	if stats.SkipReasons == nil {
+		stats.SkipReasons = make(map[SkipReason]int)
+	}
+	stats.SkipReasons[reason]++
+}
+
+// skipSummary describes why tests were skipped, like `3 disabled in meta, 2 filtered by tag`
+func skipSummary(stats TestStats) string {
+	var reasons []string
+	for _, reason := range []SkipReason{SkipDisabled, SkipExcluded, SkipNotIncluded, SkipTags} {
+		if n := stats.SkipReasons[reason]; n > 0 {
+			reasons = append(reasons, fmt.Sprintf("%d %s", n, reason))
+		}
+	}
+	return strings.Join(reasons, ", ")
+}
+
+// caseTitle matches the titles of tests expanded from parameters, like `942100-7[3]`, maybe with a virtual host
+var caseTitle = regexp.MustCompile(`^(.*)\[(\d+)\](@.*)?$`)
+
+// groupTitles shows the cases expanded from the same test once, like `942100-7[1,3]`, keeping the order
+func groupTitles(titles []string) []string {
+	var grouped []string
+	// cases has the numbers of the cases of each test, and where the test is in grouped
+	cases := make(map[string][]string)
+	index := make(map[string]int)
+	for _, title := range titles {
+		m := caseTitle.FindStringSubmatch(title)
+		if m == nil {
+			grouped = append(grouped, title)
+			continue
+		}
+		key := m[1] + m[3]
+		numbers, ok := cases[key]
+		if !ok {
+			index[key] = len(grouped)
+			grouped = append(grouped, "")
+		}
+		// a case failing in more than one stage is shown once
+		if !ok || numbers[len(numbers)-1] != m[2] {
+			numbers = append(numbers, m[2])
+		}
+		cases[key] = numbers
+		grouped[index[key]] = fmt.Sprintf("%s[%s]%s", m[1], strings.Join(numbers, ","), m[3])
+	}
+	return grouped
+}
+
 func printSummary(quiet bool, stats TestStats) int {
 	totalFailed := len(stats.Failed) + len(stats.ForcedFail)
 
 	if !quiet {
 		if stats.Run > 0 {
 			emoji.Printf(":plus:run %d total tests in %s\n", stats.Run, stats.RunTime)
-			emoji.Printf(":next_track_button: skept %d tests\n", len(stats.Skipped))
+			if stats.TimeToFirstByte > 0 {
+				emoji.Printf(":stopwatch: waiting for the first byte of responses took %s\n", stats.TimeToFirstByte)
+			}
+			if reasons := skipSummary(stats); reasons != "" {
+				emoji.Printf(":next_track_button: skept %d tests: %s\n", len(stats.Skipped), reasons)
+			} else {
+				emoji.Printf(":next_track_button: skept %d tests\n", len(stats.Skipped))
+			}
 			if len(stats.Ignored) > 0 {
 				emoji.Printf(":index_pointing_up: ignored %d tests\n", len(stats.Ignored))
 			}
@@ -67,9 +132,9 @@ func printSummary(quiet bool, stats TestStats) int {
 			if totalFailed == 0 {
 				emoji.Println(":tada:All tests successful!")
 			} else {
-				emoji.Printf(":thumbs_down:%d test(s) failed to run: %+q\n", len(stats.Failed), stats.Failed)
+				emoji.Printf(":thumbs_down:%d test(s) failed to run: %+q\n", len(stats.Failed), groupTitles(stats.Failed))
 				if len(stats.ForcedFail) > 0 {
-					emoji.Printf(":index_pointing_up:%d test(s) were forced to fail: %+q\n", len(stats.ForcedFail), stats.ForcedFail)
+					emoji.Printf(":index_pointing_up:%d test(s) were forced to fail: %+q\n", len(stats.ForcedFail), groupTitles(stats.ForcedFail))
 				}
 			}
 		} else {
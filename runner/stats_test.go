@@ -0,0 +1,36 @@
+package runner
+
+import "testing"
+
+func TestAddSkippedToStats(t *testing.T) {
+	var stats TestStats
+	addSkippedToStats(SkipTags, "942100-1", &stats)
+	addSkippedToStats(SkipDisabled, "920100-1", &stats)
+	addSkippedToStats(SkipTags, "942100-2", &stats)
+
+	if len(stats.Skipped) != 3 {
+		t.Errorf("Failed ! expected 3 skipped tests, got %d", len(stats.Skipped))
+	}
+	if stats.SkipReasons[SkipTags] != 2 || stats.SkipReasons[SkipDisabled] != 1 {
+		t.Errorf("Failed ! got skip reasons %v", stats.SkipReasons)
+	}
+	if summary := skipSummary(stats); summary != "1 disabled in meta, 2 filtered by tag" {
+		t.Errorf("Failed ! got skip summary %q", summary)
+	}
+	if summary := skipSummary(TestStats{}); summary != "" {
+		t.Errorf("Failed ! expected no skip summary, got %q", summary)
+	}
+}
+
+func TestGroupTitles(t *testing.T) {
+	grouped := groupTitles([]string{"920100-1", "942100-7[1]", "942100-7[1]", "942100-7[3]", "942100-7[2]@app.example.com", "920100-2"})
+	expected := []string{"920100-1", "942100-7[1,3]", "942100-7[2]@app.example.com", "920100-2"}
+	if len(grouped) != len(expected) {
+		t.Fatalf("Failed ! got %q", grouped)
+	}
+	for i := range expected {
+		if grouped[i] != expected[i] {
+			t.Errorf("Failed ! expected %q, got %q", expected, grouped)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package runner
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fzipi/go-ftw/test"
+)
+
+// TagExpression selects tests by their tags, like `sqli && pl2 && !slow`.
+// Use `&&`, `||`, `!` and parentheses; `&&` binds tighter than `||`.
+type TagExpression struct {
+	source string
+	match  tagMatcher
+}
+
+// ParseTagExpression parses the expression. An empty expression matches all the tests.
+func ParseTagExpression(expression string) (*TagExpression, error) {
+	e := &TagExpression{source: expression}
+	if strings.TrimSpace(expression) == "" {
+		e.match = func(map[string]bool) bool { return true }
+		return e, nil
+	}
+
+	p := &tagParser{tokens: tokenizeTags(expression)}
+	match, err := p.or()
+	if err == nil && p.pos < len(p.tokens) {
+		err = fmt.Errorf("unexpected %q", p.tokens[p.pos])
+	}
+	if err != nil {
+		return nil, fmt.Errorf("bad tag expression %q: %w", expression, err)
+	}
+	e.match = match
+	return e, nil
+}
+
+// Match is true when the tags satisfy the expression
+func (e *TagExpression) Match(tags []string) bool {
+	set := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		set[tag] = true
+	}
+	return e.match(set)
+}
+
+// String returns the expression as written
+func (e *TagExpression) String() string {
+	return e.source
+}
+
+// tokenizeTags splits the expression into operators, parentheses and tag names
+func tokenizeTags(expression string) []string {
+	var tokens []string
+	for i := 0; i < len(expression); {
+		switch c := expression[i]; {
+		case c == ' ' || c == '\t':
+			i++
+		case strings.HasPrefix(expression[i:], "&&"), strings.HasPrefix(expression[i:], "||"):
+			tokens = append(tokens, expression[i:i+2])
+			i += 2
+		case c == '!' || c == '(' || c == ')':
+			tokens = append(tokens, string(c))
+			i++
+		default:
+			end := i + 1
+			for end < len(expression) && !strings.ContainsRune(" \t&|!()", rune(expression[end])) {
+				end++
+			}
+			tokens = append(tokens, expression[i:end])
+			i = end
+		}
+	}
+	return tokens
+}
+
+// tagMatcher is true when the set of tags matches
+type tagMatcher func(tags map[string]bool) bool
+
+// tagParser is a recursive descent parser for tag expressions
+type tagParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *tagParser) next() string {
+	if p.pos >= len(p.tokens) {
+		return ""
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *tagParser) or() (tagMatcher, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.next() == "||" {
+		p.pos++
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(tags map[string]bool) bool { return l(tags) || right(tags) }
+	}
+	return left, nil
+}
+
+func (p *tagParser) and() (tagMatcher, error) {
+	left, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+	for p.next() == "&&" {
+		p.pos++
+		right, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(tags map[string]bool) bool { return l(tags) && right(tags) }
+	}
+	return left, nil
+}
+
+func (p *tagParser) not() (tagMatcher, error) {
+	if p.next() != "!" {
+		return p.primary()
+	}
+	p.pos++
+	operand, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+	return func(tags map[string]bool) bool { return !operand(tags) }, nil
+}
+
+func (p *tagParser) primary() (tagMatcher, error) {
+	token := p.next()
+	switch {
+	case token == "":
+		return nil, fmt.Errorf("unexpected end of expression")
+	case token == "(":
+		p.pos++
+		inner, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if p.next() != ")" {
+			return nil, fmt.Errorf("missing )")
+		}
+		p.pos++
+		return inner, nil
+	case !test.IsValidTag(token):
+		return nil, fmt.Errorf("unexpected %q", token)
+	}
+	p.pos++
+	return func(tags map[string]bool) bool { return tags[token] }, nil
+}
@@ -0,0 +1,64 @@
+package runner
+
+import (
+	"testing"
+)
+
+func TestTagExpressionMatch(t *testing.T) {
+	tags := []string{"sqli", "pl2", "crs:942"}
+	for expression, expected := range map[string]bool{
+		"":                         true,
+		"sqli":                     true,
+		"xss":                      false,
+		"sqli && pl2 && !slow":     true,
+		"sqli && pl2 && slow":      false,
+		"xss || crs:942":           true,
+		"!sqli || pl2 && xss":      false,
+		"(!sqli || pl2) && !xss":   true,
+		"!(sqli && pl2)":           false,
+		"  sqli&&pl2  ":            true,
+		"xss || sqli && !pl2":      false,
+		"!!sqli":                   true,
+		"crs:942 && (pl1 || pl2)":  true,
+		"crs:942 && !(pl1 || pl2)": false,
+	} {
+		e, err := ParseTagExpression(expression)
+		if err != nil {
+			t.Errorf("Failed ! cannot parse %q: %s", expression, err.Error())
+			continue
+		}
+		if e.Match(tags) != expected {
+			t.Errorf("Failed ! %q should match: %v", expression, expected)
+		}
+	}
+}
+
+func TestTagExpressionErrors(t *testing.T) {
+	for _, expression := range []string{"sqli &&", "&& sqli", "sqli pl2", "(sqli", "sqli)", "sqli & pl2", "!", "sqli || 'pl2'"} {
+		if _, err := ParseTagExpression(expression); err == nil {
+			t.Errorf("Failed ! %q should not parse", expression)
+		}
+	}
+}
+
+func TestNeedToSkipTest(t *testing.T) {
+	tags, _ := ParseTagExpression("sqli && !slow")
+	for _, c := range []struct {
+		include  string
+		exclude  string
+		testTags []string
+		enabled  bool
+		expected SkipReason
+	}{
+		{"", "", []string{"sqli"}, true, NotSkipped},
+		{"", "", []string{"sqli"}, false, SkipDisabled},
+		{"", "942100-.*", []string{"sqli"}, true, SkipExcluded},
+		{"920.*", "", []string{"sqli"}, true, SkipNotIncluded},
+		{"942.*", "", []string{"sqli", "slow"}, true, SkipTags},
+		{"942.*", "", nil, true, SkipTags},
+	} {
+		if reason := needToSkipTest(c.include, c.exclude, tags, []string{"942100-1"}, c.testTags, c.enabled); reason != c.expected {
+			t.Errorf("Failed ! expected %q, got %q for %+v", c.expected, reason, c)
+		}
+	}
+}
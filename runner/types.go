@@ -0,0 +1,46 @@
+package runner
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/fzipi/go-ftw/ftwhttp"
+	"github.com/fzipi/go-ftw/test"
+	"github.com/fzipi/go-ftw/waflog"
+)
+
+// Config has the options used when running tests
+type Config struct {
+	// Include is a regexp: only tests with matching titles are run
+	Include string
+	// Exclude is a regexp: tests with matching titles are skipped
+	Exclude string
+	// Tags is a tag expression, like `sqli && !slow`: only tests with matching tags are run
+	Tags string
+	// ShowTime shows the time spent per test
+	ShowTime bool
+	// Quiet disables the output, only the summary is shown
+	Quiet bool
+	// Handler, when not nil, serves all requests in-process instead of sending them over the network
+	Handler http.Handler
+	// LogSink, when not nil, is used for checking logs instead of the log file from the config
+	LogSink *waflog.MemorySink
+	// Capture, when not nil, records all the traffic in a pcapng file
+	Capture *ftwhttp.Capture
+	// VirtualHosts makes all tests run once for each host, overriding the virtual hosts in the config
+	VirtualHosts []string
+	// VirtualHost is set by the runner while running tests against one of the VirtualHosts
+	VirtualHost string
+}
+
+// StageResult is the outcome of running a stage
+type StageResult struct {
+	Result   TestResult
+	Duration time.Duration
+	// Timing has the time spent in each phase of the request, when it was sent
+	Timing *ftwhttp.RoundTripTime
+	// Explanation tells what was expected when the stage failed
+	Explanation string
+	// Position is where the stage is in the test file
+	Position test.Position
+}
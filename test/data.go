@@ -2,6 +2,10 @@ package test
 
 import (
 	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"os"
+	"strings"
 
 	"text/template"
 
@@ -16,15 +20,36 @@ func (i *Input) ParseData() []byte {
 
 	// Parse data for Go template
 	if i.Data != nil {
+		// tests expanded from parameters use their values in the template
+		var arguments interface{}
+		if i.Arguments != nil {
+			arguments = i.Arguments
+		}
 		t := template.New("ftw").Funcs(sprig.TxtFuncMap())
 		t, err = t.Parse(*i.Data)
 		if err != nil {
 			log.Debug().Msgf("test/data: error parsing template in data: %s", err.Error())
 		}
-		if err = t.Execute(&tpl, nil); err != nil {
+		if err = t.Execute(&tpl, arguments); err != nil {
 			log.Debug().Msgf("test/data: error executing template: %s", err.Error())
 		}
 	}
 
 	return tpl.Bytes()
 }
+
+// GetExactData returns the body from data_base64, data_hex or data_file, which is sent byte-exact.
+// It returns nil if none of them is used.
+func (i *Input) GetExactData() ([]byte, error) {
+	switch {
+	case i.DataBase64 != "":
+		return base64.StdEncoding.DecodeString(i.DataBase64)
+	case i.DataHex != "":
+		// allow splitting long hex strings with spaces and newlines
+		return hex.DecodeString(strings.Join(strings.Fields(i.DataHex), ""))
+	case i.DataFile != "":
+		return os.ReadFile(i.DataFile)
+	default:
+		return nil, nil
+	}
+}
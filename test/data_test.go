@@ -26,7 +26,7 @@ uri: "/"
 	input := Input{}
 	err := yaml.Unmarshal([]byte(yamlString), &input)
 
-	if err == nil && input.StopMagic == true {
+	if err == nil && input.GetStopMagic() {
 		t.Logf("Success !")
 	} else {
 		t.Errorf("Failed !")
@@ -87,3 +87,59 @@ uri: "/"
 		t.Fatalf("Failed: %s", data)
 	}
 }
+
+func TestGetTransmissionFromYAML(t *testing.T) {
+	yamlString := `
+raw_request: "GET / HTTP/1.1\r\n\r\n"
+transmission:
+  split_at: [3, 10]
+  chunk_size: 2
+  delay_ms: 100
+  nodelay: false
+`
+	input := Input{}
+	err := yaml.Unmarshal([]byte(yamlString), &input)
+
+	if err != nil || input.Transmission == nil {
+		t.Fatalf("Failed !")
+	}
+	tr := input.Transmission
+	if len(tr.SplitAt) != 2 || tr.ChunkSize != 2 || tr.DelayMS != 100 || tr.NoDelay == nil || *tr.NoDelay {
+		t.Errorf("Failed ! %+v", tr)
+	}
+}
+
+func TestGetWebSocketFromYAML(t *testing.T) {
+	yamlString := `
+input:
+  uri: "/chat"
+  websocket:
+    frames:
+      - data: "hello"
+      - type: binary
+        data_base64: "AAEC"
+        fragments: 2
+        mask: wrong
+output:
+  status: [101]
+  websocket:
+    message_contains: "hello"
+    closed: false
+`
+	stage := StageData{}
+	err := yaml.Unmarshal([]byte(yamlString), &stage)
+
+	if err != nil || stage.Input.WebSocket == nil || len(stage.Input.WebSocket.Frames) != 2 {
+		t.Fatalf("Failed !")
+	}
+	if frame := stage.Input.WebSocket.Frames[1]; frame.Type != "binary" || frame.Fragments != 2 || frame.Mask != "wrong" {
+		t.Errorf("Failed ! %+v", frame)
+	}
+	ws := stage.Output.WebSocket
+	if ws == nil || ws.MessageContains != "hello" || ws.Closed == nil || *ws.Closed {
+		t.Errorf("Failed ! %+v", ws)
+	}
+	if err = stage.Input.Validate(); err != nil {
+		t.Errorf("Failed ! %s", err.Error())
+	}
+}
@@ -2,7 +2,9 @@ package test
 
 import (
 	"encoding/base64"
+	"strings"
 
+	"github.com/fzipi/go-ftw/ftwhttp"
 	"github.com/fzipi/go-ftw/utils"
 )
 
@@ -54,6 +56,22 @@ func (i *Input) GetPort() int {
 	return *i.Port
 }
 
+// GetSaveCookie returns the proper semantic when the field is empty
+func (i *Input) GetSaveCookie() bool {
+	if i.SaveCookie == nil {
+		return false
+	}
+	return *i.SaveCookie
+}
+
+// GetStopMagic returns the proper semantic when the field is empty
+func (i *Input) GetStopMagic() bool {
+	if i.StopMagic == nil {
+		return false
+	}
+	return *i.StopMagic
+}
+
 // GetRawRequest returns the proper raw data, and error if there was none
 func (i *Input) GetRawRequest() ([]byte, error) {
 	if utils.IsNotEmpty(i.EncodedRequest) {
@@ -65,3 +83,99 @@ func (i *Input) GetRawRequest() ([]byte, error) {
 	}
 	return nil, nil
 }
+
+// applyDefaults merges the defaults of the file, and then the ones of each test, into the input of every stage.
+// Values in the stage win, and headers are merged by name.
+func (f *FTWTest) applyDefaults() {
+	for i := range f.Tests {
+		test := &f.Tests[i]
+		var defaults Input
+		if test.Defaults != nil {
+			defaults = *test.Defaults
+		}
+		if f.Defaults != nil {
+			mergeInput(&defaults, f.Defaults)
+		}
+		for s := range test.Stages {
+			mergeInput(&test.Stages[s].Stage.Input, &defaults)
+		}
+	}
+}
+
+// mergeInput sets the fields missing in the input using the defaults. The body is taken from the defaults
+// only when the input has no body, so both are never mixed.
+func mergeInput(input *Input, defaults *Input) {
+	if input.DestAddr == nil {
+		input.DestAddr = defaults.DestAddr
+	}
+	if input.Port == nil {
+		input.Port = defaults.Port
+	}
+	if input.Protocol == nil {
+		input.Protocol = defaults.Protocol
+	}
+	if input.URI == nil {
+		input.URI = defaults.URI
+	}
+	if input.Version == nil {
+		input.Version = defaults.Version
+	}
+	if input.Method == nil {
+		input.Method = defaults.Method
+	}
+	input.Headers = mergeHeaders(input.Headers, defaults.Headers)
+	if len(input.bodySources()) == 0 {
+		input.Data = defaults.Data
+		input.DataBase64 = defaults.DataBase64
+		input.DataHex = defaults.DataHex
+		input.DataFile = defaults.DataFile
+		input.Chunked = defaults.Chunked
+		input.Multipart = defaults.Multipart
+		input.EncodedRequest = defaults.EncodedRequest
+		input.RAWRequest = defaults.RAWRequest
+	}
+	if input.SaveCookie == nil {
+		input.SaveCookie = defaults.SaveCookie
+	}
+	if input.StopMagic == nil {
+		input.StopMagic = defaults.StopMagic
+	}
+	if input.Compression == "" {
+		input.Compression = defaults.Compression
+	}
+	if input.WebSocket == nil {
+		input.WebSocket = defaults.WebSocket
+	}
+	if input.GRPC == nil {
+		input.GRPC = defaults.GRPC
+	}
+	if input.Transmission == nil {
+		input.Transmission = defaults.Transmission
+	}
+}
+
+// mergeHeaders returns the headers with the defaults that are missing, comparing names without case
+func mergeHeaders(headers ftwhttp.Header, defaults ftwhttp.Header) ftwhttp.Header {
+	if len(defaults) == 0 {
+		return headers
+	}
+	merged := make(ftwhttp.Header, len(headers)+len(defaults))
+	for name, value := range headers {
+		merged[name] = value
+	}
+	for name, value := range defaults {
+		if !hasHeader(merged, name) {
+			merged[name] = value
+		}
+	}
+	return merged
+}
+
+func hasHeader(headers ftwhttp.Header, name string) bool {
+	for h := range headers {
+		if strings.EqualFold(h, name) {
+			return true
+		}
+	}
+	return false
+}
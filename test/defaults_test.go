@@ -9,12 +9,14 @@ import (
 
 func getTestInputDefaults() *Input {
 	data := "My Data"
+	saveCookie := false
+	stopMagic := false
 
 	inputDefaults := Input{
 		Headers:    make(ftwhttp.Header),
 		Data:       &data,
-		SaveCookie: false,
-		StopMagic:  false,
+		SaveCookie: &saveCookie,
+		StopMagic:  &stopMagic,
 	}
 	return &inputDefaults
 }
@@ -26,6 +28,8 @@ func getTestExampleInput() *Input {
 	uri := "/test"
 	method := "REPORT"
 	version := "HTTP/1.1"
+	saveCookie := false
+	stopMagic := false
 
 	inputTest := Input{
 		DestAddr:       &destaddr,
@@ -37,8 +41,8 @@ func getTestExampleInput() *Input {
 		Method:         &method,
 		Data:           nil,
 		EncodedRequest: "TXkgRGF0YQo=",
-		SaveCookie:     false,
-		StopMagic:      false,
+		SaveCookie:     &saveCookie,
+		StopMagic:      &stopMagic,
 	}
 
 	return &inputTest
@@ -48,6 +52,8 @@ func getRawInput() *Input {
 	destaddr := "192.168.0.1"
 	port := 8080
 	protocol := "http"
+	saveCookie := false
+	stopMagic := true
 
 	inputTest := Input{
 		DestAddr: &destaddr,
@@ -65,8 +71,8 @@ Keep-Alive: 300
 Proxy-Connection: keep-alive
 User-Agent: Mozilla/4.0 (compatible; MSIE 7.0; Windows NT 5.1; SV1; .NET CLR 2.0.50727)
 `,
-		SaveCookie: false,
-		StopMagic:  true,
+		SaveCookie: &saveCookie,
+		StopMagic:  &stopMagic,
 	}
 
 	return &inputTest
@@ -139,7 +145,7 @@ func TestDefaultGetters(t *testing.T) {
 func TestRaw(t *testing.T) {
 	raw := getRawInput()
 
-	if raw.StopMagic != true {
+	if raw.GetStopMagic() != true {
 		t.Fatalf("Error!")
 	}
 
@@ -147,3 +153,63 @@ func TestRaw(t *testing.T) {
 		t.Fatalf("Error!")
 	}
 }
+
+func TestMergeInput(t *testing.T) {
+	stageAddr := "waf.example.com"
+	defaultAddr := "localhost"
+	port := 8080
+	data := "default body"
+	stopMagic := true
+	input := Input{
+		DestAddr:  &stageAddr,
+		Headers:   ftwhttp.Header{"user-agent": "stage"},
+		Multipart: &ftwhttp.MultipartBody{},
+	}
+	defaults := Input{
+		DestAddr:  &defaultAddr,
+		Port:      &port,
+		Headers:   ftwhttp.Header{"User-Agent": "ftw", "Host": "localhost"},
+		Data:      &data,
+		StopMagic: &stopMagic,
+	}
+
+	mergeInput(&input, &defaults)
+
+	if input.GetDestAddr() != "waf.example.com" {
+		t.Errorf("Failed ! the stage should win, got dest_addr %s", input.GetDestAddr())
+	}
+	if input.GetPort() != 8080 {
+		t.Errorf("Failed ! expected the default port, got %d", input.GetPort())
+	}
+	if len(input.Headers) != 2 || input.Headers["user-agent"] != "stage" || input.Headers["Host"] != "localhost" {
+		t.Errorf("Failed ! headers should be merged by name, got %v", input.Headers)
+	}
+	if input.Data != nil {
+		t.Errorf("Failed ! the default body should not be mixed with the body of the stage")
+	}
+	if !input.GetStopMagic() {
+		t.Errorf("Failed ! expected stop_magic from the defaults")
+	}
+	if len(defaults.Headers) != 2 || defaults.Headers["User-Agent"] != "ftw" {
+		t.Errorf("Failed ! the defaults should not change, got %v", defaults.Headers)
+	}
+}
+
+func TestMergeInputOverridesTrue(t *testing.T) {
+	stageValue := false
+	defaultValue := true
+	input := Input{SaveCookie: &stageValue, StopMagic: &stageValue}
+	mergeInput(&input, &Input{SaveCookie: &defaultValue, StopMagic: &defaultValue})
+	if input.GetSaveCookie() || input.GetStopMagic() {
+		t.Errorf("Failed ! the stage set save_cookie and stop_magic to false, over the defaults")
+	}
+}
+
+func TestMergeInputBody(t *testing.T) {
+	data := "default body"
+	var input Input
+	mergeInput(&input, &Input{Data: &data})
+	if input.Data == nil || *input.Data != data {
+		t.Errorf("Failed ! expected the default body")
+	}
+}
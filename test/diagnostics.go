@@ -0,0 +1,380 @@
+package test
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/parser"
+
+	"github.com/fzipi/go-ftw/ftwhttp"
+)
+
+// Severities of the diagnostics
+const (
+	// SeverityError is used for problems that must be fixed. Diagnostics without severity are errors.
+	SeverityError = "error"
+	// SeverityWarning is used for problems that do not make the check fail
+	SeverityWarning = "warning"
+)
+
+// Diagnostic is a problem found in a test file, with its position. Line and Column are 0 when unknown.
+type Diagnostic struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Message string `json:"message"`
+	// Rule is the name of the lint rule that found the problem, if any
+	Rule     string `json:"rule,omitempty"`
+	Severity string `json:"severity,omitempty"`
+}
+
+// String formats the diagnostic as `file:line:column: message`, adding the severity for warnings and the rule if any
+func (d Diagnostic) String() string {
+	message := d.Message
+	if d.IsWarning() {
+		message = "warning: " + message
+	}
+	if d.Rule != "" {
+		message += fmt.Sprintf(" [%s]", d.Rule)
+	}
+	if d.Line == 0 {
+		return fmt.Sprintf("%s: %s", d.File, message)
+	}
+	return fmt.Sprintf("%s:%d:%d: %s", d.File, d.Line, d.Column, message)
+}
+
+// IsWarning is true for diagnostics that do not make the check fail
+func (d Diagnostic) IsWarning() bool {
+	return d.Severity == SeverityWarning
+}
+
+// yamlUnmarshaler is implemented by types decoding themselves, which are not checked for unknown fields
+type yamlUnmarshaler interface {
+	UnmarshalYAML(func(interface{}) error) error
+}
+
+// fileChecker collects the diagnostics for one file
+type fileChecker struct {
+	file        string
+	root        ast.Node
+	diagnostics []Diagnostic
+	// unreadable has the index of the tests that could not be unmarshaled
+	unreadable map[int]bool
+}
+
+// CheckFile reads the test file strictly, and returns every problem found: yaml syntax errors,
+// unknown fields, and values that make no sense, like conflicting body sources or invalid status codes.
+// An empty result means the file is fine.
+func CheckFile(filename string) []Diagnostic {
+	c := &fileChecker{file: filename}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		c.add(nil, "%s", err.Error())
+		return c.diagnostics
+	}
+
+	f, err := parser.ParseBytes(data, 0)
+	if err != nil {
+		c.addError(err)
+		return c.diagnostics
+	}
+	if len(f.Docs) == 0 || f.Docs[0].Body == nil {
+		c.add(nil, "the file has no tests")
+		return c.diagnostics
+	}
+	if len(f.Docs) > 1 {
+		c.add(f.Docs[1].Body, "only one yaml document is allowed in a test file")
+	}
+	c.root = f.Docs[0].Body
+
+	c.checkFields(c.root, reflect.TypeOf(FTWTest{}))
+
+	var t FTWTest
+	if err = yaml.Unmarshal(data, &t); err != nil {
+		// errors from our own unmarshalers have no position, but were already reported when checking fields
+		if line, _, _ := errorPosition(err); line > 0 || len(c.diagnostics) == 0 {
+			c.addError(err)
+			return c.diagnostics
+		}
+		// keep checking the tests that can be read
+		t.Tests = c.readTests()
+	}
+	t.FileName = filename
+	t.resolveFilePaths()
+	t.applyDefaults()
+
+	c.checkTests(&t)
+	return c.diagnostics
+}
+
+// readTests reads the tests one by one, skipping the ones that cannot be unmarshaled.
+// Their position in the file is kept, so the tests after them are found in the right place.
+func (c *fileChecker) readTests() []Test {
+	var tests []Test
+	kv := mappingValue(c.root, "tests")
+	if kv == nil {
+		return nil
+	}
+	c.unreadable = make(map[int]bool)
+	for i, n := range sequenceValues(kv.Value) {
+		var test Test
+		if err := yaml.Unmarshal([]byte(n.String()), &test); err != nil {
+			c.unreadable[i] = true
+		}
+		tests = append(tests, test)
+	}
+	return tests
+}
+
+// add adds a diagnostic at the position of the node
+func (c *fileChecker) add(n ast.Node, format string, args ...interface{}) {
+	line, column := NodePosition(n)
+	c.diagnostics = append(c.diagnostics, Diagnostic{File: c.file, Line: line, Column: column, Message: fmt.Sprintf(format, args...)})
+}
+
+// addError adds a diagnostic for an error from goccy/go-yaml, using its position
+func (c *fileChecker) addError(err error) {
+	line, column, message := errorPosition(err)
+	c.diagnostics = append(c.diagnostics, Diagnostic{File: c.file, Line: line, Column: column, Message: message})
+}
+
+// at returns the node at the path from the root of the file
+func (c *fileChecker) at(path ...interface{}) ast.Node {
+	return nodeAt(c.root, path...)
+}
+
+// checkFields walks the node using the type it is unmarshaled into, reporting keys with no matching field
+func (c *fileChecker) checkFields(n ast.Node, t reflect.Type) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if n == nil {
+		return
+	}
+
+	if t == reflect.TypeOf(ExpectError("")) {
+		var e ExpectError
+		if err := yaml.Unmarshal([]byte(unwrapNode(n).GetToken().Value), &e); err != nil {
+			c.add(n, "bad expect_error: use true, false, any or one of %v", ftwhttp.ErrorClasses)
+		}
+		return
+	}
+	if reflect.PtrTo(t).Implements(reflect.TypeOf((*yamlUnmarshaler)(nil)).Elem()) {
+		return
+	}
+
+	node := unwrapNode(n)
+	if _, ok := node.(*ast.NullNode); ok {
+		return
+	}
+	if _, ok := node.(*ast.AliasNode); ok {
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if mappingValues(node) == nil {
+			c.add(n, "expected a mapping, got %s", nodeKind(node))
+			return
+		}
+		fields := yamlFields(t)
+		for _, kv := range mappingValues(node) {
+			name := keyName(kv)
+			field, ok := fields[name]
+			if !ok {
+				c.add(kv.Key, "unknown field %q%s", name, suggestField(name, fields))
+				continue
+			}
+			c.checkFields(kv.Value, field.Type)
+		}
+	case reflect.Slice:
+		if _, ok := node.(*ast.SequenceNode); !ok {
+			c.add(n, "expected a list, got %s", nodeKind(node))
+			return
+		}
+		for _, item := range sequenceValues(node) {
+			c.checkFields(item, t.Elem())
+		}
+	case reflect.Map:
+		if mappingValues(node) == nil {
+			c.add(n, "expected a mapping, got %s", nodeKind(node))
+			return
+		}
+		for _, kv := range mappingValues(node) {
+			c.checkFields(kv.Value, t.Elem())
+		}
+	case reflect.Bool:
+		if _, ok := node.(*ast.BoolNode); !ok {
+			c.add(n, "expected true or false, got %s", nodeKind(node))
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if _, ok := node.(*ast.IntegerNode); !ok {
+			c.add(n, "expected an integer, got %s", nodeKind(node))
+		}
+	case reflect.String:
+		switch node.(type) {
+		case *ast.MappingNode, *ast.MappingValueNode, *ast.SequenceNode:
+			c.add(n, "expected a string, got %s", nodeKind(node))
+		}
+	}
+}
+
+// nodeKind describes the node for diagnostics
+func nodeKind(n ast.Node) string {
+	switch n.(type) {
+	case *ast.MappingNode, *ast.MappingValueNode:
+		return "a mapping"
+	case *ast.SequenceNode:
+		return "a list"
+	}
+	return fmt.Sprintf("%q", n.GetToken().Value)
+}
+
+// yamlFields returns the fields of the struct by their yaml name. Fields without a yaml tag are not used in files.
+func yamlFields(t reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("yaml")
+		if tag == "" || tag == "-" {
+			continue
+		}
+		fields[strings.Split(tag, ",")[0]] = f
+	}
+	return fields
+}
+
+// suggestField returns a hint when the unknown field looks like a typo of a known one
+func suggestField(name string, fields map[string]reflect.StructField) string {
+	best, bestDistance := "", 3
+	for known := range fields {
+		distance := editDistance(name, known)
+		if strings.HasPrefix(known, name) || strings.HasPrefix(name, known) {
+			distance = minInt(distance, 2)
+		}
+		if distance < bestDistance || (distance == bestDistance && known < best) {
+			best, bestDistance = known, distance
+		}
+	}
+	if best == "" {
+		return ""
+	}
+	return fmt.Sprintf(", did you mean %q?", best)
+}
+
+// editDistance is the Levenshtein distance between a and b
+func editDistance(a string, b string) int {
+	previous := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current := make([]int, len(b)+1)
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = minInt(minInt(previous[j]+1, current[j-1]+1), previous[j-1]+cost)
+		}
+		previous = current
+	}
+	return previous[len(b)]
+}
+
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// checkTests validates the values in every test and stage
+func (c *fileChecker) checkTests(t *FTWTest) {
+	if len(t.Tests) == 0 {
+		c.add(c.at("tests"), "the file has no tests")
+	}
+	c.checkTags(t.Meta.Tags, "meta", "tags")
+	for i, test := range t.Tests {
+		if c.unreadable[i] {
+			continue
+		}
+		c.checkTags(test.Tags, "tests", i, "tags")
+		if test.TestTitle == "" {
+			c.add(c.at("tests", i), "test %d has no test_title", i+1)
+		}
+		if len(test.Stages) == 0 {
+			c.add(c.at("tests", i, "stages"), "test %s has no stages", test.TestTitle)
+		}
+		if len(test.Parameters) > 0 {
+			if _, err := test.expand(); err != nil {
+				c.add(c.at("tests", i, "parameters"), "%s", err.Error())
+			}
+		}
+		for s, stage := range test.Stages {
+			path := []interface{}{"tests", i, "stages", s, "stage"}
+			c.checkInput(&stage.Stage.Input, append(path, "input"))
+			c.checkOutput(&stage.Stage.Output, append(path, "output"))
+		}
+	}
+}
+
+// checkTags reports tags that cannot be used in tag expressions
+func (c *fileChecker) checkTags(tags []string, path ...interface{}) {
+	for i, tag := range tags {
+		if err := validateTag(tag); err != nil {
+			c.add(c.at(append(path, i)...), "%s", err.Error())
+		}
+	}
+}
+
+// checkInput reports conflicting body sources and values that cannot be decoded
+func (c *fileChecker) checkInput(input *Input, path []interface{}) {
+	field := func(name string) ast.Node {
+		return c.at(append(append([]interface{}(nil), path...), name)...)
+	}
+
+	if sources := input.bodySources(); len(sources) > 1 {
+		c.add(field(sources[1]), "%s", bodySourcesError(sources).Error())
+		return
+	}
+	if _, err := input.GetRawRequest(); err != nil {
+		c.add(field("encoded_request"), "bad base64 in encoded_request: %s", err.Error())
+		return
+	}
+	if input.Port != nil && (*input.Port < 1 || *input.Port > 65535) {
+		c.add(field("port"), "port %d is not valid", *input.Port)
+	}
+	if err := input.Validate(); err != nil {
+		c.add(c.at(path...), "%s", err.Error())
+	}
+}
+
+// checkOutput reports invalid status codes and log regexps that do not compile
+func (c *fileChecker) checkOutput(output *Output, path []interface{}) {
+	field := func(elements ...interface{}) ast.Node {
+		return c.at(append(append([]interface{}(nil), path...), elements...)...)
+	}
+
+	for i, status := range output.Status {
+		if err := validateStatus(status); err != nil {
+			c.add(field("status", i), "%s", err.Error())
+		}
+	}
+	for i, status := range output.GRPCStatus {
+		if err := validateGRPCStatus(status); err != nil {
+			c.add(field("grpc_status", i), "%s", err.Error())
+		}
+	}
+	for _, l := range output.logRegexps() {
+		if err := validateLogRegexp(l.name, l.value); err != nil {
+			c.add(field(l.name), "%s", err.Error())
+		}
+	}
+}
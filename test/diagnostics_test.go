@@ -0,0 +1,154 @@
+package test
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/fzipi/go-ftw/utils"
+)
+
+var yamlGoodTest = `---
+meta:
+  author: "tester"
+  enabled: true
+  name: "good.yaml"
+tests:
+  - test_title: "001"
+    desc: "a good test"
+    stages:
+      - stage:
+          input:
+            dest_addr: "localhost"
+            headers:
+              Host: "localhost"
+            data: "a=1"
+          output:
+            status: [403]
+            expect_error: connection_reset
+`
+
+var yamlBadTest = `---
+meta:
+  author: "tester"
+  enabled: true
+  name: "bad.yaml"
+tests:
+  - test_title: "001"
+    stages:
+      - stage:
+          input:
+            data: "a=1"
+            raw_request: "GET / HTTP/1.1"
+            port: 70000
+          output:
+            log_contain: "id 1"
+            status: [200, 999]
+  - test_title: "002"
+    stages: []
+  - test_title: "003"
+    stages:
+      - stage:
+          input:
+            encoded_request: "%%%"
+          output:
+            no_log_contains: "id (1"
+`
+
+func checkString(t *testing.T, content string) []Diagnostic {
+	filename, err := utils.CreateTempFileWithContent(content, "goftw-test-*.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(filename)
+
+	diagnostics := CheckFile(filename)
+	for i := range diagnostics {
+		if diagnostics[i].File != filename {
+			t.Errorf("Failed ! diagnostic for %s", diagnostics[i].File)
+		}
+		diagnostics[i].File = "test.yaml"
+	}
+	return diagnostics
+}
+
+func TestCheckFileGood(t *testing.T) {
+	if diagnostics := checkString(t, yamlGoodTest); len(diagnostics) > 0 {
+		t.Errorf("Failed ! got %v", diagnostics)
+	}
+}
+
+func TestCheckFileBad(t *testing.T) {
+	expected := []string{
+		`test.yaml:15:13: unknown field "log_contain", did you mean "log_contains"?`,
+		`test.yaml:12:13: choose only one of data, data_base64, data_hex, data_file, chunked, multipart, encoded_request, or raw_request; found data, raw_request`,
+		`test.yaml:16:27: status 999 is not a valid HTTP status code`,
+		`test.yaml:18:5: test 002 has no stages`,
+		"test.yaml:23:13: bad base64 in encoded_request: illegal base64 data at input byte 0",
+		"test.yaml:25:13: bad regexp in no_log_contains: error parsing regexp: missing closing ): `id (1`",
+	}
+
+	diagnostics := checkString(t, yamlBadTest)
+	if len(diagnostics) != len(expected) {
+		t.Fatalf("Failed ! got %v", diagnostics)
+	}
+	for i, d := range diagnostics {
+		if d.String() != expected[i] {
+			t.Errorf("Failed ! got %s, expected %s", d.String(), expected[i])
+		}
+	}
+}
+
+func TestCheckFileSemanticAfterBadExpectError(t *testing.T) {
+	content := strings.Replace(yamlBadTest, "status: [200, 999]", "expect_error: sometimes", 1)
+	diagnostics := checkString(t, content)
+
+	if len(diagnostics) != 5 || diagnostics[1].String() != "test.yaml:16:27: bad expect_error: use true, false, any or one of [connection_refused connection_reset connect_timeout read_timeout dns_failure tls_alert malformed_response premature_eof unknown]" {
+		t.Errorf("Failed ! got %v", diagnostics)
+	}
+	// the tests after the bad one are still checked
+	if diagnostics[4].Line != 25 {
+		t.Errorf("Failed ! got %v", diagnostics)
+	}
+}
+
+func TestCheckFileParameters(t *testing.T) {
+	content := strings.Replace(yamlGoodTest, `    desc: "a good test"
+`, `    desc: "a good test"
+    parameters:
+      - name: payload
+        values: ["a"]
+`, 1)
+	if diagnostics := checkString(t, content); len(diagnostics) > 0 {
+		t.Errorf("Failed ! got %v", diagnostics)
+	}
+
+	content = strings.Replace(content, "name: payload", "name: payload-1", 1)
+	diagnostics := checkString(t, content)
+	if len(diagnostics) != 1 || diagnostics[0].String() != `test.yaml:9:5: test 001: bad parameter name "payload-1": use letters, digits and _, not starting with a digit` {
+		t.Errorf("Failed ! got %v", diagnostics)
+	}
+}
+
+func TestCheckFileSyntaxError(t *testing.T) {
+	diagnostics := checkString(t, "---\nmeta:\n  author: a\n   b: c\n")
+	if len(diagnostics) != 1 || diagnostics[0].String() != "test.yaml:3:11: unexpected key name" {
+		t.Errorf("Failed ! got %v", diagnostics)
+	}
+
+	diagnostics = checkString(t, "---\nmeta:\n  enabled: maybe\ntests: []\n")
+	if len(diagnostics) != 2 || diagnostics[0].String() != `test.yaml:3:12: expected true or false, got "maybe"` {
+		t.Errorf("Failed ! got %v", diagnostics)
+	}
+
+	diagnostics = checkString(t, "")
+	if len(diagnostics) != 1 || diagnostics[0].String() != "test.yaml: the file has no tests" {
+		t.Errorf("Failed ! got %v", diagnostics)
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	if editDistance("log_contain", "log_contains") != 1 || editDistance("", "abc") != 3 || editDistance("stauts", "status") != 2 {
+		t.Errorf("Failed !")
+	}
+}
@@ -53,3 +53,74 @@ func TestGetLinesFromTestName(t *testing.T) {
 		}
 	}
 }
+
+var positionsTest = `---
+meta:
+  name: "911100.yaml"
+tests:
+  - test_title: "911100-(1)"
+    stages:
+      - stage:
+          input:
+            method: "OPTIONS"
+          output:
+            status: [405]
+      - stage:
+          input: {}
+          output:
+            status: [200]
+  - test_title: '911100-*'
+    stages:
+      - stage:
+          output:
+            status: [200]
+          input:
+            uri: "/"
+`
+
+func TestPositions(t *testing.T) {
+	filename, _ := utils.CreateTempFileWithContent(positionsTest, "test-yaml-*")
+	tests, err := GetTestsFromFiles(filename)
+	if err != nil || len(tests) != 1 {
+		t.Fatalf("Failed ! cannot read tests: %v", err)
+	}
+	ft := tests[0]
+
+	expected := []struct {
+		name     string
+		position Position
+		line     int
+		column   int
+	}{
+		{"test 1", ft.Tests[0].Position, 5, 5},
+		{"stage 1", ft.Tests[0].Stages[0].Stage.Position, 7, 9},
+		{"input 1", ft.Tests[0].Stages[0].Stage.Input.Position, 8, 11},
+		{"output 1", ft.Tests[0].Stages[0].Stage.Output.Position, 10, 11},
+		{"stage 2", ft.Tests[0].Stages[1].Stage.Position, 12, 9},
+		{"input 2", ft.Tests[0].Stages[1].Stage.Input.Position, 13, 11},
+		{"test 2", ft.Tests[1].Position, 16, 5},
+		{"output 3", ft.Tests[1].Stages[0].Stage.Output.Position, 19, 11},
+		{"input 3", ft.Tests[1].Stages[0].Stage.Input.Position, 21, 11},
+	}
+	for _, e := range expected {
+		if e.position.File != filename || e.position.Line != e.line || e.position.Column != e.column {
+			t.Errorf("Failed ! %s is at %s, expected line %d column %d", e.name, e.position, e.line, e.column)
+		}
+	}
+
+	if line, err := ft.GetLinesFromTest("911100-*"); err != nil || line != 16 {
+		t.Errorf("Failed ! quoted title with regexp characters found at line %d", line)
+	}
+	if _, err := ft.GetLinesFromTest("911100-3"); err == nil {
+		t.Errorf("Failed ! missing test should fail")
+	}
+}
+
+func TestPositionString(t *testing.T) {
+	if s := (Position{File: "a.yaml", Line: 3, Column: 5}).String(); s != "a.yaml:3:5" {
+		t.Errorf("Failed ! got %s", s)
+	}
+	if s := (Position{File: "a.yaml"}).String(); s != "a.yaml" {
+		t.Errorf("Failed ! got %s", s)
+	}
+}
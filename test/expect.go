@@ -0,0 +1,73 @@
+package test
+
+import (
+	"fmt"
+
+	"github.com/fzipi/go-ftw/ftwhttp"
+)
+
+// ExpectError is the error expected in a test output. In yaml it can be a boolean, where `true`
+// means any error, `any`, which is the same as `true`, or the name of an error class, like `connection_reset`, so only errors of that
+// class match.
+type ExpectError string
+
+const (
+	// NoError means no error is expected
+	NoError ExpectError = ""
+	// AnyError means any error is expected
+	AnyError ExpectError = "any"
+)
+
+// Expected returns true if an error is expected
+func (e ExpectError) Expected() bool {
+	return e != NoError
+}
+
+// Matches returns true if an error of the class passed is expected
+func (e ExpectError) Matches(class ftwhttp.ErrorClass) bool {
+	if class == "" {
+		return false
+	}
+	return e == AnyError || e == ExpectError(class)
+}
+
+// UnmarshalYAML accepts a boolean, `any` or one of the ftwhttp error classes
+func (e *ExpectError) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var expected bool
+	if err := unmarshal(&expected); err == nil {
+		if expected {
+			*e = AnyError
+		} else {
+			*e = NoError
+		}
+		return nil
+	}
+
+	var class string
+	if err := unmarshal(&class); err != nil {
+		return err
+	}
+	if class == string(AnyError) {
+		*e = AnyError
+		return nil
+	}
+	for _, known := range ftwhttp.ErrorClasses {
+		if class == string(known) {
+			*e = ExpectError(class)
+			return nil
+		}
+	}
+	return fmt.Errorf("ftw/test: unknown error class %q in expect_error, use true, any or one of %v", class, ftwhttp.ErrorClasses)
+}
+
+// MarshalYAML writes `true` for any error, or the error class
+func (e ExpectError) MarshalYAML() (interface{}, error) {
+	switch e {
+	case NoError:
+		return false, nil
+	case AnyError:
+		return true, nil
+	default:
+		return string(e), nil
+	}
+}
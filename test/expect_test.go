@@ -0,0 +1,51 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/fzipi/go-ftw/ftwhttp"
+	"github.com/goccy/go-yaml"
+)
+
+var expectErrorTests = []struct {
+	yamlString string
+	expected   ExpectError
+}{
+	{"expect_error: true", AnyError},
+	{"expect_error: false", NoError},
+	{"expect_error: any", AnyError},
+	{"status: [200]", NoError},
+	{"expect_error: connection_reset", ExpectError(ftwhttp.ConnectionReset)},
+}
+
+func TestExpectErrorFromYAML(t *testing.T) {
+	for _, e := range expectErrorTests {
+		output := Output{}
+		if err := yaml.Unmarshal([]byte(e.yamlString), &output); err != nil {
+			t.Errorf("Failed ! %s", err.Error())
+		}
+		if output.ExpectError != e.expected {
+			t.Errorf("Failed ! %q parsed as %q", e.yamlString, output.ExpectError)
+		}
+	}
+}
+
+func TestExpectErrorUnknownClass(t *testing.T) {
+	output := Output{}
+	if err := yaml.Unmarshal([]byte("expect_error: connection_rest"), &output); err == nil {
+		t.Errorf("Failed !")
+	}
+}
+
+func TestExpectErrorMatches(t *testing.T) {
+	if !AnyError.Matches(ftwhttp.ReadTimeout) {
+		t.Errorf("Failed !")
+	}
+	if NoError.Matches(ftwhttp.ReadTimeout) {
+		t.Errorf("Failed !")
+	}
+	expected := ExpectError(ftwhttp.ConnectionReset)
+	if !expected.Matches(ftwhttp.ConnectionReset) || expected.Matches(ftwhttp.DNSFailure) {
+		t.Errorf("Failed !")
+	}
+}
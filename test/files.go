@@ -2,8 +2,11 @@ package test
 
 import (
 	"os"
+	"path/filepath"
 
 	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/parser"
 	"github.com/rs/zerolog/log"
 	"github.com/yargevad/filepathx"
 )
@@ -43,5 +46,56 @@ func readTest(filename string) (t FTWTest, err error) {
 	err = yaml.Unmarshal(yamlFile, &t)
 	t.FileName = filename
 	// Set Defaults
+	t.resolveFilePaths()
+	t.applyDefaults()
+	if err == nil {
+		var f *ast.File
+		if f, err = parser.ParseBytes(yamlFile, 0); err == nil && len(f.Docs) > 0 {
+			t.recordPositions(f.Docs[0].Body)
+		}
+	}
+	if err == nil {
+		// after recording positions, which use the place of each test in the file
+		err = t.expandParameters()
+	}
 	return t, err
 }
+
+// resolveFilePaths makes relative paths to files used in tests relative to the test file
+func (f *FTWTest) resolveFilePaths() {
+	dir := filepath.Dir(f.FileName)
+	resolveInputPaths(dir, f.Defaults)
+	for _, test := range f.Tests {
+		resolveInputPaths(dir, test.Defaults)
+		for p := range test.Parameters {
+			if file := test.Parameters[p].File; file != "" && !filepath.IsAbs(file) {
+				test.Parameters[p].File = filepath.Join(dir, file)
+			}
+		}
+		for s := range test.Stages {
+			resolveInputPaths(dir, &test.Stages[s].Stage.Input)
+		}
+	}
+}
+
+// resolveInputPaths makes relative paths to files used in the input relative to dir
+func resolveInputPaths(dir string, input *Input) {
+	if input == nil {
+		return
+	}
+	if input.DataFile != "" && !filepath.IsAbs(input.DataFile) {
+		input.DataFile = filepath.Join(dir, input.DataFile)
+	}
+	if input.GRPC != nil && input.GRPC.DescriptorSet != "" && !filepath.IsAbs(input.GRPC.DescriptorSet) {
+		input.GRPC.DescriptorSet = filepath.Join(dir, input.GRPC.DescriptorSet)
+	}
+	if input.Multipart == nil {
+		return
+	}
+	parts := input.Multipart.Parts
+	for i := range parts {
+		if parts[i].File != "" && !filepath.IsAbs(parts[i].File) {
+			parts[i].File = filepath.Join(dir, parts[i].File)
+		}
+	}
+}
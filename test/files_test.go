@@ -1,6 +1,7 @@
 package test
 
 import (
+	"path/filepath"
 	"regexp"
 	"testing"
 
@@ -80,3 +81,125 @@ func TestGetFromBadYAML(t *testing.T) {
 		t.Fatalf("Error!")
 	}
 }
+
+var yamlMultipartTest = `
+---
+  meta:
+    author: "tester"
+    enabled: true
+    name: "multipart.yaml"
+  tests:
+    -
+      test_title: multipart-1
+      stages:
+        -
+          stage:
+            input:
+              multipart:
+                parts:
+                  - name: relative
+                    file: upload.txt
+                  - name: absolute
+                    file: /tmp/upload.txt
+            output:
+              status: [200]
+        -
+          stage:
+            input:
+              data_file: payloads/body.bin
+            output:
+              status: [200]
+`
+
+func TestFilesRelativeToTest(t *testing.T) {
+	filename, _ := utils.CreateTempFileWithContent(yamlMultipartTest, "test-yaml-*")
+	tests, err := GetTestsFromFiles(filename)
+	if err != nil || len(tests) != 1 {
+		t.Fatalf("Error!")
+	}
+
+	parts := tests[0].Tests[0].Stages[0].Stage.Input.Multipart.Parts
+	if parts[0].File != filepath.Join(filepath.Dir(filename), "upload.txt") {
+		t.Errorf("Failed ! got %s", parts[0].File)
+	}
+	if parts[1].File != "/tmp/upload.txt" {
+		t.Errorf("Failed ! got %s", parts[1].File)
+	}
+	if file := tests[0].Tests[0].Stages[1].Stage.Input.DataFile; file != filepath.Join(filepath.Dir(filename), "payloads", "body.bin") {
+		t.Errorf("Failed ! got %s", file)
+	}
+}
+
+var yamlDefaultsTest = `---
+meta:
+  author: "tester"
+  enabled: true
+defaults:
+  dest_addr: "waf.example.com"
+  port: 8080
+  data_file: payloads/body.bin
+  stop_magic: true
+  headers:
+    User-Agent: "ModSecurity CRS 3 Tests"
+    Host: "localhost"
+tests:
+  - test_title: 1
+    defaults:
+      method: POST
+      port: 8443
+    stages:
+      - stage:
+          input:
+            headers:
+              user-agent: "curl"
+          output:
+            status: [200]
+      - stage:
+          input:
+            port: 80
+            data: "stage body"
+            stop_magic: false
+          output:
+            status: [200]
+  - test_title: 2
+    stages:
+      - stage:
+          input: {}
+          output:
+            status: [200]
+`
+
+func TestDefaults(t *testing.T) {
+	filename, _ := utils.CreateTempFileWithContent(yamlDefaultsTest, "test-yaml-*")
+	tests, err := GetTestsFromFiles(filename)
+	if err != nil || len(tests) != 1 {
+		t.Fatalf("Error!")
+	}
+
+	first := tests[0].Tests[0].Stages[0].Stage.Input
+	if first.GetDestAddr() != "waf.example.com" || first.GetPort() != 8443 || first.GetMethod() != "POST" {
+		t.Errorf("Failed ! got %s:%d using %s", first.GetDestAddr(), first.GetPort(), first.GetMethod())
+	}
+	if len(first.Headers) != 2 || first.Headers["user-agent"] != "curl" || first.Headers["Host"] != "localhost" {
+		t.Errorf("Failed ! got headers %v", first.Headers)
+	}
+	if first.DataFile != filepath.Join(filepath.Dir(filename), "payloads", "body.bin") {
+		t.Errorf("Failed ! got data_file %s", first.DataFile)
+	}
+	if !first.GetStopMagic() {
+		t.Errorf("Failed ! expected stop_magic from the defaults")
+	}
+
+	second := tests[0].Tests[0].Stages[1].Stage.Input
+	if second.GetPort() != 80 || second.DataFile != "" || *second.Data != "stage body" {
+		t.Errorf("Failed ! the stage should win, got port %d and data_file %q", second.GetPort(), second.DataFile)
+	}
+	if second.GetStopMagic() {
+		t.Errorf("Failed ! the stage set stop_magic to false, over the defaults")
+	}
+
+	other := tests[0].Tests[1].Stages[0].Stage.Input
+	if other.GetPort() != 8080 || other.GetMethod() != "GET" {
+		t.Errorf("Failed ! defaults of a test should not be used in other tests, got port %d using %s", other.GetPort(), other.GetMethod())
+	}
+}
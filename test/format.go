@@ -0,0 +1,610 @@
+package test
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/lexer"
+	"github.com/goccy/go-yaml/parser"
+	"github.com/goccy/go-yaml/token"
+)
+
+// plainKey matches keys that are always written without quotes
+var plainKey = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.\-/]*$`)
+
+// formatter writes a test file in the canonical layout: keys in the order of the fields in
+// the types, two spaces of indentation, lists of scalars in flow style, and comments kept
+// next to the key they were written before or after.
+type formatter struct {
+	out []string
+	// leading has the comments written in the lines before a key or list item, by its line
+	leading map[int][]string
+	// trailing has the comments written after a key or list item, by its line
+	trailing map[int][]string
+	// header and footer are the comments before the document start and after the last key
+	header []string
+	footer []string
+}
+
+// FormatFile formats the test file, returning the formatted content and whether it changed
+func FormatFile(filename string) ([]byte, bool, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, false, err
+	}
+	formatted, err := Format(data)
+	if err != nil {
+		return nil, false, fmt.Errorf("%s: %w", filename, err)
+	}
+	return formatted, !bytes.Equal(data, formatted), nil
+}
+
+// Format returns the test file in the canonical layout. It fails when the result would not
+// read back as the same values, so payloads are never changed.
+func Format(data []byte) ([]byte, error) {
+	file, err := parser.ParseBytes(data, 0)
+	if err != nil {
+		return nil, fmt.Errorf("ftw/test: cannot format: %w", err)
+	}
+	if len(file.Docs) != 1 || file.Docs[0].Body == nil {
+		return nil, fmt.Errorf("ftw/test: cannot format: a test file has exactly one yaml document")
+	}
+	root := file.Docs[0].Body
+
+	f := &formatter{leading: make(map[int][]string), trailing: make(map[int][]string)}
+	tokens := lexer.Tokenize(string(data))
+	f.placeComments(tokens, anchorLines(root))
+
+	f.out = append(f.out, f.header...)
+	f.out = append(f.out, withComments("---", f.trailing[0]))
+	if err = f.value(root, reflect.TypeOf(FTWTest{}), 0); err != nil {
+		return nil, err
+	}
+	f.out = append(f.out, f.footer...)
+	formatted := []byte(strings.Join(f.out, "\n") + "\n")
+
+	if err = sameValues(data, formatted); err != nil {
+		return nil, err
+	}
+	if countComments(tokens) != countComments(lexer.Tokenize(string(formatted))) {
+		return nil, fmt.Errorf("ftw/test: cannot format: some comments could not be kept")
+	}
+	return formatted, nil
+}
+
+// anchorLines returns the lines with a key or a list item, which comments are attached to
+func anchorLines(n ast.Node) []int {
+	var lines []int
+	var walk func(n ast.Node)
+	walk = func(n ast.Node) {
+		n = unwrapNode(n)
+		if items := sequenceValues(n); items != nil {
+			for _, item := range items {
+				if line, _ := NodePosition(item); line > 0 {
+					lines = append(lines, line)
+				}
+				walk(item)
+			}
+			return
+		}
+		for _, kv := range mappingValues(n) {
+			if line, _ := NodePosition(kv.Key); line > 0 {
+				lines = append(lines, line)
+			}
+			walk(kv.Value)
+		}
+	}
+	walk(n)
+	sort.Ints(lines)
+	return lines
+}
+
+// placeComments attaches each comment to a key: comments alone in their line go with the next key,
+// and comments after some yaml go with the last key in or before their line.
+func (f *formatter) placeComments(tokens token.Tokens, anchors []int) {
+	documentLine := 0
+	for _, tk := range tokens {
+		if tk.Type == token.DocumentHeaderType {
+			documentLine = tk.Position.Line
+			break
+		}
+	}
+	lastCodeLine := 0
+	for _, tk := range tokens {
+		if tk.Type != token.CommentType {
+			if tk.Position.Line > lastCodeLine {
+				lastCodeLine = tk.Position.Line
+			}
+			continue
+		}
+		comment := "#" + tk.Value
+		line := tk.Position.Line
+		switch {
+		case lastCodeLine == line && line == documentLine:
+			f.trailing[0] = append(f.trailing[0], comment)
+		case lastCodeLine == line:
+			i := sort.SearchInts(anchors, line+1) - 1
+			if i < 0 {
+				f.trailing[0] = append(f.trailing[0], comment)
+			} else {
+				f.trailing[anchors[i]] = append(f.trailing[anchors[i]], comment)
+			}
+		case line < documentLine:
+			f.header = append(f.header, comment)
+		default:
+			i := sort.SearchInts(anchors, line+1)
+			if i == len(anchors) {
+				f.footer = append(f.footer, comment)
+			} else {
+				f.leading[anchors[i]] = append(f.leading[anchors[i]], comment)
+			}
+		}
+	}
+}
+
+func withComments(text string, comments []string) string {
+	if len(comments) == 0 {
+		return text
+	}
+	return text + " " + strings.Join(comments, " ")
+}
+
+// comments writes the comments before the key or item in the source line
+func (f *formatter) comments(indent int, source int) {
+	for _, c := range f.leading[source] {
+		f.out = append(f.out, strings.Repeat(" ", indent)+c)
+	}
+	delete(f.leading, source)
+}
+
+// line writes a line for the key or item in the source line, with its comments
+func (f *formatter) line(indent int, source int, text string) {
+	f.comments(indent, source)
+	f.out = append(f.out, strings.Repeat(" ", indent)+withComments(text, f.trailing[source]))
+	delete(f.trailing, source)
+}
+
+// value writes the entries of a mapping or the items of a list at the indentation
+func (f *formatter) value(n ast.Node, t reflect.Type, indent int) error {
+	node := unwrapNode(n)
+	if _, ok := node.(*ast.SequenceNode); ok {
+		return f.items(node, elemType(t), indent)
+	}
+	return f.entries(node, t, indent, false)
+}
+
+// entries writes the entries of a mapping in the canonical order. When dash is set,
+// the first one starts a list item.
+func (f *formatter) entries(n ast.Node, t reflect.Type, indent int, dash bool) error {
+	for i, kv := range orderedValues(mappingValues(n), t) {
+		prefix, lineIndent, keyIndent := "", indent, indent
+		if dash {
+			lineIndent, keyIndent = indent+2, indent+2
+		}
+		if dash && i == 0 {
+			prefix, lineIndent = "- ", indent
+		}
+		if err := f.entry(kv, fieldType(t, keyName(kv)), lineIndent, keyIndent, prefix); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// entry writes a key and its value. The line is written at indent with the prefix, and the key
+// is at keyIndent, which is where nested values are indented from.
+func (f *formatter) entry(kv *ast.MappingValueNode, t reflect.Type, indent int, keyIndent int, prefix string) error {
+	source, _ := NodePosition(kv.Key)
+	key, err := formatKey(kv)
+	if err != nil {
+		return err
+	}
+	properties, value := nodeProperties(kv.Value)
+
+	switch v := value.(type) {
+	case *ast.MappingNode, *ast.MappingValueNode:
+		if len(mappingValues(v)) == 0 {
+			f.line(indent, source, prefix+key+":"+properties+" {}")
+			return nil
+		}
+		f.line(indent, source, prefix+key+":"+properties)
+		return f.entries(v, t, keyIndent+2, false)
+	case *ast.SequenceNode:
+		if flow, ok := f.flowList(v, source); ok {
+			f.line(indent, source, prefix+key+":"+properties+" "+flow)
+			return nil
+		}
+		f.line(indent, source, prefix+key+":"+properties)
+		return f.items(v, elemType(t), keyIndent+2)
+	}
+
+	lines, err := formatScalar(value, false, keyIndent+2)
+	if err != nil {
+		return err
+	}
+	text := prefix + key + ":" + properties
+	if lines[0] != "" {
+		text += " " + lines[0]
+	}
+	f.line(indent, source, text)
+	// the lines of literal blocks cannot have comments
+	f.out = append(f.out, lines[1:]...)
+	return nil
+}
+
+// items writes the items of a block list
+func (f *formatter) items(n ast.Node, t reflect.Type, indent int) error {
+	for _, item := range sequenceValues(n) {
+		source, _ := NodePosition(item)
+		properties, value := nodeProperties(item)
+		if properties != "" {
+			return fmt.Errorf("ftw/test: cannot format: anchors and tags in list items at line %d are not supported", source)
+		}
+		switch v := value.(type) {
+		case *ast.MappingNode, *ast.MappingValueNode:
+			if len(mappingValues(v)) == 0 {
+				f.line(indent, source, "- {}")
+				continue
+			}
+			// comments before the item stay before it, even when its first key changes
+			f.comments(indent, source)
+			if err := f.entries(v, t, indent, true); err != nil {
+				return err
+			}
+		case *ast.SequenceNode:
+			flow, ok := f.flowList(v, source)
+			if !ok {
+				return fmt.Errorf("ftw/test: cannot format: nested lists at line %d are not supported", source)
+			}
+			f.line(indent, source, "- "+flow)
+		default:
+			lines, err := formatScalar(v, false, indent+2)
+			if err != nil {
+				return err
+			}
+			f.line(indent, source, "- "+lines[0])
+			f.out = append(f.out, lines[1:]...)
+		}
+	}
+	return nil
+}
+
+// flowList returns the list in flow style, like `[200, 403]`, when all the items are
+// scalars, and none of them has comments other than the ones in the line of its key
+func (f *formatter) flowList(n *ast.SequenceNode, source int) (string, bool) {
+	var items []string
+	for _, item := range n.Values {
+		line, _ := NodePosition(item)
+		if line != source && (len(f.leading[line]) > 0 || len(f.trailing[line]) > 0) {
+			return "", false
+		}
+		switch unwrapNode(item).(type) {
+		case *ast.MappingNode, *ast.MappingValueNode, *ast.SequenceNode, *ast.LiteralNode:
+			return "", false
+		}
+		properties, value := nodeProperties(item)
+		lines, err := formatScalar(value, true, 0)
+		if err != nil || len(lines) > 1 {
+			return "", false
+		}
+		items = append(items, strings.TrimSpace(properties+" "+lines[0]))
+	}
+	return "[" + strings.Join(items, ", ") + "]", true
+}
+
+// nodeProperties returns the anchor and tag of the node, written before its value, and the value
+func nodeProperties(n ast.Node) (string, ast.Node) {
+	properties := ""
+	for {
+		switch v := n.(type) {
+		case *ast.AnchorNode:
+			properties += " &" + v.Name.GetToken().Value
+			n = v.Value
+		case *ast.TagNode:
+			properties += " " + v.Start.Value
+			n = v.Value
+		default:
+			return properties, n
+		}
+	}
+}
+
+// orderedValues sorts the entries in the order of the fields of the struct. Entries
+// with unknown keys, and entries of other types, keep their order.
+func orderedValues(values []*ast.MappingValueNode, t reflect.Type) []*ast.MappingValueNode {
+	order := make(map[string]int)
+	for i, name := range fieldNames(t) {
+		order[name] = i
+	}
+	position := func(kv *ast.MappingValueNode) int {
+		if i, ok := order[keyName(kv)]; ok {
+			return i
+		}
+		return len(order)
+	}
+	sorted := append([]*ast.MappingValueNode(nil), values...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return position(sorted[i]) < position(sorted[j])
+	})
+	return sorted
+}
+
+// structType returns the struct type behind pointers, or nil for other types and types decoding themselves
+func structType(t reflect.Type) reflect.Type {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct || reflect.PtrTo(t).Implements(reflect.TypeOf((*yamlUnmarshaler)(nil)).Elem()) {
+		return nil
+	}
+	return t
+}
+
+// fieldNames returns the yaml names of the fields of the struct, in order
+func fieldNames(t reflect.Type) []string {
+	t = structType(t)
+	if t == nil {
+		return nil
+	}
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		tag := t.Field(i).Tag.Get("yaml")
+		if tag == "" || tag == "-" {
+			continue
+		}
+		names = append(names, strings.Split(tag, ",")[0])
+	}
+	return names
+}
+
+// fieldType returns the type of the field or map value with the name, or nil when unknown
+func fieldType(t reflect.Type, name string) reflect.Type {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil {
+		return nil
+	}
+	if t.Kind() == reflect.Map {
+		return t.Elem()
+	}
+	if s := structType(t); s != nil {
+		if field, ok := yamlFields(s)[name]; ok {
+			return field.Type
+		}
+	}
+	return nil
+}
+
+// elemType returns the type of the items of a list, or nil when unknown
+func elemType(t reflect.Type) reflect.Type {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Slice {
+		return nil
+	}
+	return t.Elem()
+}
+
+// formatKey returns the key, quoted only when needed
+func formatKey(kv *ast.MappingValueNode) (string, error) {
+	if kv.Key.Type() == ast.MergeKeyType {
+		return "<<", nil
+	}
+	name := keyName(kv)
+	if plainKey.MatchString(name) {
+		return name, nil
+	}
+	if _, ok := unwrapNode(kv.Key).(*ast.StringNode); !ok {
+		return kv.Key.GetToken().Value, nil
+	}
+	return doubleQuoted(name)
+}
+
+// formatScalar returns the lines of a scalar value. Strings use the first style that reads back as
+// the same string: plain, single quoted, a literal block for several lines, or double quoted.
+// A single line ending in a newline is double quoted, as it reads better than a block.
+// Other scalars, like numbers and booleans, are kept as written.
+func formatScalar(n ast.Node, flow bool, indent int) ([]string, error) {
+	var s string
+	switch v := n.(type) {
+	case *ast.AliasNode:
+		return []string{"*" + v.Value.GetToken().Value}, nil
+	case *ast.StringNode:
+		s = v.Value
+	case *ast.LiteralNode:
+		s = v.Value.Value
+	case *ast.NullNode:
+		// goccy/go-yaml adds a `null` token, without the spaces before it, for keys with no value
+		if tk := v.GetToken(); tk != nil && tk.Origin != tk.Value {
+			return []string{tk.Value}, nil
+		}
+		return []string{""}, nil
+	default:
+		if n == nil || n.GetToken() == nil {
+			return []string{""}, nil
+		}
+		return []string{n.GetToken().Value}, nil
+	}
+
+	if !strings.Contains(s, "\n") {
+		if plainSafe(s, flow) && readsBack(s, s, flow) {
+			return []string{s}, nil
+		}
+		if quoted, ok := singleQuoted(s); ok && readsBack(quoted, s, flow) {
+			return []string{quoted}, nil
+		}
+	} else if !flow && strings.Contains(strings.TrimRight(s, "\n"), "\n") {
+		if lines, ok := literalBlock(s, indent); ok && readsBack(strings.Join(literalLines(s, 2), "\n"), s, false) {
+			return lines, nil
+		}
+	}
+	quoted, err := doubleQuoted(s)
+	if err != nil {
+		line, _ := NodePosition(n)
+		return nil, fmt.Errorf("ftw/test: cannot format the value at line %d: %w", line, err)
+	}
+	if !readsBack(quoted, s, flow) {
+		line, _ := NodePosition(n)
+		return nil, fmt.Errorf("ftw/test: cannot format the value at line %d without changing it", line)
+	}
+	return []string{quoted}, nil
+}
+
+// plainSafe is true for strings that can be written without quotes in any yaml parser
+func plainSafe(s string, flow bool) bool {
+	if s == "" || strings.TrimSpace(s) != s || strings.ContainsAny(s, "\t\r") {
+		return false
+	}
+	if strings.ContainsAny(s[:1], "-?:,[]{}#&*!|>'\"%@`") || strings.Contains(s, ": ") || strings.Contains(s, " #") || strings.HasSuffix(s, ":") {
+		return false
+	}
+	if flow && strings.ContainsAny(s, ",[]{}") {
+		return false
+	}
+	for _, r := range s {
+		if !unicode.IsPrint(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func singleQuoted(s string) (string, bool) {
+	for _, r := range s {
+		if !unicode.IsPrint(r) && r != ' ' {
+			return "", false
+		}
+	}
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'", true
+}
+
+// doubleQuoted escapes the string. Control characters use `\x`, as goccy/go-yaml does not read
+// escapes like `\r` or `\t`. Strings that are not valid UTF-8 cannot be written in yaml.
+func doubleQuoted(s string) (string, error) {
+	if !utf8.ValidString(s) {
+		return "", fmt.Errorf("the value is not valid UTF-8")
+	}
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\n':
+			b.WriteString(`\n`)
+		default:
+			switch {
+			case r < 0x20 || r == 0x7f:
+				fmt.Fprintf(&b, `\x%02x`, r)
+			case !unicode.IsPrint(r) && r <= 0xffff:
+				fmt.Fprintf(&b, `\u%04x`, r)
+			case !unicode.IsPrint(r):
+				fmt.Fprintf(&b, `\U%08x`, r)
+			default:
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String(), nil
+}
+
+// literalBlock returns the lines of a literal block for the string, indented. Strings with
+// characters that are not printable, or with spaces at the end of lines, are not written as blocks.
+func literalBlock(s string, indent int) ([]string, bool) {
+	for _, r := range s {
+		if !unicode.IsPrint(r) && r != '\n' && r != ' ' && r != '\t' {
+			return nil, false
+		}
+	}
+	for _, l := range strings.Split(s, "\n") {
+		if strings.TrimRight(l, " \t") != l {
+			return nil, false
+		}
+	}
+	return literalLines(s, indent), true
+}
+
+// literalLines writes the string as a literal block: the header, and the indented lines
+func literalLines(s string, indent int) []string {
+	body := strings.TrimRight(s, "\n")
+	header := "|"
+	if strings.HasPrefix(body, " ") || strings.HasPrefix(body, "\n") {
+		header += "2"
+	}
+	switch len(s) - len(body) {
+	case 0:
+		header += "-"
+	case 1:
+	default:
+		header += "+"
+	}
+
+	lines := []string{header}
+	spaces := strings.Repeat(" ", indent)
+	for _, l := range strings.Split(body, "\n") {
+		if l == "" {
+			lines = append(lines, "")
+		} else {
+			lines = append(lines, spaces+l)
+		}
+	}
+	for i := 1; i < len(s)-len(body); i++ {
+		lines = append(lines, "")
+	}
+	return lines
+}
+
+// readsBack is true when the value written reads back as the string
+func readsBack(written string, s string, flow bool) bool {
+	if flow {
+		var v map[string][]interface{}
+		if err := yaml.Unmarshal([]byte("v: ["+written+"]\n"), &v); err != nil {
+			return false
+		}
+		return len(v["v"]) == 1 && v["v"][0] == s
+	}
+	var v map[string]interface{}
+	if err := yaml.Unmarshal([]byte("v: "+written+"\n"), &v); err != nil {
+		return false
+	}
+	return v["v"] == s
+}
+
+// sameValues checks that both documents have the same values
+func sameValues(original []byte, formatted []byte) error {
+	var before, after interface{}
+	if err := yaml.Unmarshal(original, &before); err != nil {
+		return fmt.Errorf("ftw/test: cannot format: %w", err)
+	}
+	if err := yaml.Unmarshal(formatted, &after); err != nil {
+		return fmt.Errorf("ftw/test: cannot format, the result cannot be read: %w", err)
+	}
+	if !reflect.DeepEqual(before, after) {
+		return fmt.Errorf("ftw/test: cannot format without changing the values")
+	}
+	return nil
+}
+
+func countComments(tokens token.Tokens) int {
+	count := 0
+	for _, tk := range tokens {
+		if tk.Type == token.CommentType {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,160 @@
+package test
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/ast"
+
+	"github.com/fzipi/go-ftw/utils"
+)
+
+var yamlUnformattedTest = `# yaml-language-server: $schema=schema.json
+---
+meta:
+  name: "920100.yaml"
+  author: tester   # who
+  enabled: true
+tests:
+    # first test
+  -   stages:
+      - stage:
+          output:
+              status:
+                - 200
+                - 403
+          input:
+            headers:
+              User-Agent: "ModSecurity CRS 3 Tests"
+              Host: 'localhost'
+              X-Num: "10"
+            uri: "/?x=<script>alert('1')</script>"
+            data: "a=1&b=2\n"
+            method: POST
+            dest_addr: 127.0.0.1
+            port: 80
+      test_title: 920100-1
+      desc: |
+        multi
+          line
+  - test_title: "920100-2"
+    desc: "tab\x09cr\x0d"
+    stages:
+    - stage:
+        input:
+          raw_request: "GET / HTTP/1.1\nHost: x\n\n"
+          data: ' leading'
+        output: {log_contains: 'id "920100"'}
+# the end
+`
+
+var yamlFormattedTest = `# yaml-language-server: $schema=schema.json
+---
+meta:
+  author: tester # who
+  enabled: true
+  name: 920100.yaml
+tests:
+  # first test
+  - test_title: 920100-1
+    desc: |
+      multi
+        line
+    stages:
+      - stage:
+          input:
+            dest_addr: 127.0.0.1
+            port: 80
+            uri: /?x=<script>alert('1')</script>
+            headers:
+              User-Agent: ModSecurity CRS 3 Tests
+              Host: localhost
+              X-Num: '10'
+            method: POST
+            data: "a=1&b=2\n"
+          output:
+            status: [200, 403]
+  - test_title: 920100-2
+    desc: "tab\x09cr\x0d"
+    stages:
+      - stage:
+          input:
+            data: ' leading'
+            raw_request: |+
+              GET / HTTP/1.1
+              Host: x
+
+          output:
+            log_contains: id "920100"
+# the end
+`
+
+func TestFormat(t *testing.T) {
+	formatted, err := Format([]byte(yamlUnformattedTest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(formatted) != yamlFormattedTest {
+		t.Errorf("Failed ! formatted test is:\n%s", formatted)
+	}
+
+	again, err := Format(formatted)
+	if err != nil || string(again) != string(formatted) {
+		t.Errorf("Failed ! formatting twice changed the file:\n%s", again)
+	}
+}
+
+func TestFormatKeepsPayloads(t *testing.T) {
+	var before, after FTWTest
+	formatted, err := Format([]byte(yamlUnformattedTest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = yaml.Unmarshal([]byte(yamlUnformattedTest), &before)
+	_ = yaml.Unmarshal(formatted, &after)
+	for i := range before.Tests {
+		b, a := before.Tests[i].Stages[0].Stage.Input, after.Tests[i].Stages[0].Stage.Input
+		if b.RAWRequest != a.RAWRequest || *b.Data != *a.Data || before.Tests[i].TestDescription != after.Tests[i].TestDescription {
+			t.Errorf("Failed ! test %d changed after formatting", i)
+		}
+	}
+}
+
+func TestFormatScalar(t *testing.T) {
+	values := []string{
+		"", "true", "10", "-1", "- a", "a: b", "a #b", "#a", "'quoted'", `"double"`, "[a]", "{a}", "*a", "&a", "!a",
+		"%27", "@a", "`a`", "a\\b", "trailing ", "\ttab", "ünïcödé", "​", "a\nb", "\nleading", " a\n b\n", "a\n\n\n",
+	}
+	for _, v := range values {
+		for _, flow := range []bool{false, true} {
+			lines, err := formatScalar(&ast.StringNode{BaseNode: &ast.BaseNode{}, Value: v}, flow, 2)
+			if err != nil {
+				t.Errorf("Failed ! cannot format %q: %s", v, err.Error())
+				continue
+			}
+			if !readsBack(strings.Join(lines, "\n"), v, flow) {
+				t.Errorf("Failed ! %q was written as %q", v, lines)
+			}
+		}
+	}
+}
+
+func TestFormatErrors(t *testing.T) {
+	if _, err := Format([]byte("meta:\n  name: a\n---\nmeta:\n  name: b\n")); err == nil {
+		t.Errorf("Failed ! several documents must fail")
+	}
+	if _, err := Format([]byte("author: a\n   b: c")); err == nil {
+		t.Errorf("Failed ! bad yaml must fail")
+	}
+}
+
+func TestFormatFile(t *testing.T) {
+	filename, _ := utils.CreateTempFileWithContent(yamlFormattedTest, "goftw-test-*.yaml")
+	defer os.Remove(filename)
+
+	if _, changed, err := FormatFile(filename); err != nil || changed {
+		t.Errorf("Failed ! formatted file should not change: %v", err)
+	}
+}
@@ -0,0 +1,197 @@
+package test
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/Masterminds/sprig"
+
+	"github.com/fzipi/go-ftw/ftwhttp"
+)
+
+// parameterName matches the names that can be used in templates, like `{{ .payload }}`
+var parameterName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Validate checks the parameter has a name that can be used in templates, and one source of values
+func (p *Parameter) Validate() error {
+	if !parameterName.MatchString(p.Name) {
+		return fmt.Errorf("bad parameter name %q: use letters, digits and _, not starting with a digit", p.Name)
+	}
+	if len(p.Values) > 0 && p.File != "" {
+		return fmt.Errorf("parameter %s: choose only one of values or file", p.Name)
+	}
+	return nil
+}
+
+// values returns the values of the parameter, reading them from its file when there is one.
+// Empty lines in the file are ignored.
+func (p *Parameter) values() ([]string, error) {
+	if p.File == "" {
+		return p.Values, nil
+	}
+	f, err := os.Open(p.File)
+	if err != nil {
+		return nil, fmt.Errorf("parameter %s: %w", p.Name, err)
+	}
+	defer f.Close()
+
+	var values []string
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if line == "" {
+			continue
+		}
+		values = append(values, line)
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("parameter %s: %w", p.Name, err)
+	}
+	return values, nil
+}
+
+// expandParameters replaces every test with parameters by one test for each combination of their values
+func (f *FTWTest) expandParameters() error {
+	var tests []Test
+	for _, test := range f.Tests {
+		if len(test.Parameters) == 0 {
+			tests = append(tests, test)
+			continue
+		}
+		cases, err := test.expand()
+		if err != nil {
+			return err
+		}
+		tests = append(tests, cases...)
+	}
+	f.Tests = tests
+	return nil
+}
+
+// Titles returns the title of the test, and the title of the test it was expanded from when it has one,
+// so tests can be selected by either
+func (t Test) Titles() []string {
+	if t.ParentTitle == "" {
+		return []string{t.TestTitle}
+	}
+	return []string{t.TestTitle, t.ParentTitle}
+}
+
+// expand returns one test for each combination of the values of the parameters, titled like `942100-7[3]`.
+// The values replace the templates in the attack, uri and headers, and are used when running the templates in data.
+func (t Test) expand() ([]Test, error) {
+	combinations, err := t.combinations()
+	if err != nil {
+		return nil, fmt.Errorf("test %s: %w", t.TestTitle, err)
+	}
+
+	cases := make([]Test, 0, len(combinations))
+	for n, arguments := range combinations {
+		c := t
+		c.TestTitle = fmt.Sprintf("%s[%d]", t.TestTitle, n+1)
+		c.ParentTitle = t.TestTitle
+		c.Parameters = nil
+		if c.Attack, err = executeTemplate(t.Attack, arguments); err != nil {
+			return nil, fmt.Errorf("test %s: bad attack: %w", c.TestTitle, err)
+		}
+		c.Stages = make([]Stage, len(t.Stages))
+		for s, stage := range t.Stages {
+			input, err := applyArguments(stage.Stage.Input, arguments)
+			if err != nil {
+				return nil, fmt.Errorf("test %s, stage %d: %w", c.TestTitle, s+1, err)
+			}
+			stage.Stage.Input = input
+			c.Stages[s] = stage
+		}
+		cases = append(cases, c)
+	}
+	return cases, nil
+}
+
+// combinations returns the values of the parameters for every test, changing the last parameter first
+func (t Test) combinations() ([]map[string]string, error) {
+	combinations := []map[string]string{{}}
+	seen := make(map[string]bool)
+	for _, p := range t.Parameters {
+		if err := p.Validate(); err != nil {
+			return nil, err
+		}
+		if seen[p.Name] {
+			return nil, fmt.Errorf("parameter %s is repeated", p.Name)
+		}
+		seen[p.Name] = true
+		values, err := p.values()
+		if err != nil {
+			return nil, err
+		}
+		if len(values) == 0 {
+			return nil, fmt.Errorf("parameter %s has no values", p.Name)
+		}
+
+		var next []map[string]string
+		for _, combination := range combinations {
+			for _, value := range values {
+				arguments := make(map[string]string, len(combination)+1)
+				for name, v := range combination {
+					arguments[name] = v
+				}
+				arguments[p.Name] = value
+				next = append(next, arguments)
+			}
+		}
+		combinations = next
+	}
+	return combinations, nil
+}
+
+// applyArguments runs the templates in the uri and headers of the input. Data keeps its template, which is
+// run with the arguments when the request is sent, but it is checked here so mistakes are found early.
+func applyArguments(input Input, arguments map[string]string) (Input, error) {
+	if input.URI != nil {
+		uri, err := executeTemplate(*input.URI, arguments)
+		if err != nil {
+			return input, fmt.Errorf("bad uri: %w", err)
+		}
+		input.URI = &uri
+	}
+	if input.Headers != nil {
+		headers := make(ftwhttp.Header, len(input.Headers))
+		for name, value := range input.Headers {
+			v, err := executeTemplate(value, arguments)
+			if err != nil {
+				return input, fmt.Errorf("bad header %s: %w", name, err)
+			}
+			headers[name] = v
+		}
+		input.Headers = headers
+	}
+	if input.Data != nil {
+		if _, err := executeTemplate(*input.Data, arguments); err != nil {
+			return input, fmt.Errorf("bad data: %w", err)
+		}
+	}
+	input.Arguments = arguments
+	return input, nil
+}
+
+// executeTemplate runs the text as a Go template with the Sprig functions, failing when it uses unknown parameters
+func executeTemplate(text string, arguments map[string]string) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+	t, err := template.New("ftw").Funcs(sprig.TxtFuncMap()).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var b bytes.Buffer
+	if err = t.Execute(&b, arguments); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,109 @@
+package test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fzipi/go-ftw/utils"
+)
+
+var yamlParametersTest = `---
+meta:
+  author: "tester"
+  enabled: true
+defaults:
+  headers:
+    X-Payload: "{{ .payload }}"
+tests:
+  - test_title: 942100-6
+    stages:
+      - stage:
+          input:
+            uri: "/{{ .not_a_parameter }}"
+          output:
+            status: [200]
+  - test_title: 942100-7
+    parameters:
+      - name: payload
+        values: ["' or 1=1", "{{7*7}}"]
+      - name: param
+        file: PARAMS
+    attack: "{{ .payload }}"
+    stages:
+      - stage:
+          input:
+            uri: "/?{{ .param }}={{ .payload | urlquery }}"
+            data: "{{ .param }}={{ .payload }}"
+          output:
+            status: [403]
+`
+
+func TestExpandParameters(t *testing.T) {
+	params, _ := utils.CreateTempFileWithContent("q\r\n\nid\n", "test-params-*")
+	defer os.Remove(params)
+	filename, _ := utils.CreateTempFileWithContent(strings.Replace(yamlParametersTest, "PARAMS", filepath.Base(params), 1), "test-yaml-*")
+	defer os.Remove(filename)
+
+	tests, err := GetTestsFromFiles(filename)
+	if err != nil || len(tests) != 1 {
+		t.Fatalf("Failed ! reading tests: %v", err)
+	}
+
+	cases := tests[0].Tests
+	if len(cases) != 5 {
+		t.Fatalf("Failed ! expected 5 tests, got %d", len(cases))
+	}
+	if cases[0].TestTitle != "942100-6" || cases[0].ParentTitle != "" || cases[0].Stages[0].Stage.Input.GetURI() != "/{{ .not_a_parameter }}" {
+		t.Errorf("Failed ! tests without parameters should not change, got %+v", cases[0])
+	}
+
+	for i, expected := range []struct {
+		uri    string
+		data   string
+		header string
+	}{
+		{"/?q=%27+or+1%3D1", "q=' or 1=1", "' or 1=1"},
+		{"/?id=%27+or+1%3D1", "id=' or 1=1", "' or 1=1"},
+		{"/?q=%7B%7B7%2A7%7D%7D", "q={{7*7}}", "{{7*7}}"},
+		{"/?id=%7B%7B7%2A7%7D%7D", "id={{7*7}}", "{{7*7}}"},
+	} {
+		c := cases[i+1]
+		input := c.Stages[0].Stage.Input
+		if c.TestTitle != fmt.Sprintf("942100-7[%d]", i+1) || c.ParentTitle != "942100-7" {
+			t.Errorf("Failed ! got title %s from %s", c.TestTitle, c.ParentTitle)
+		}
+		if c.Attack != expected.header {
+			t.Errorf("Failed ! case %s got attack %s", c.TestTitle, c.Attack)
+		}
+		if input.GetURI() != expected.uri || string(input.ParseData()) != expected.data || input.Headers["X-Payload"] != expected.header {
+			t.Errorf("Failed ! case %s got uri %s, data %s and header %s", c.TestTitle, input.GetURI(), input.ParseData(), input.Headers["X-Payload"])
+		}
+		if c.Position != cases[1].Position || c.Position.Line == 0 {
+			t.Errorf("Failed ! cases should be at the position of their test, got %s", c.Position)
+		}
+	}
+}
+
+func TestExpandParametersErrors(t *testing.T) {
+	data := "{{ .paylod }}"
+	stages := []Stage{{Stage: StageData{Input: Input{Data: &data}}}}
+	for _, c := range []struct {
+		parameters []Parameter
+		expected   string
+	}{
+		{[]Parameter{{Name: "1payload", Values: []string{"a"}}}, "bad parameter name"},
+		{[]Parameter{{Name: "payload", Values: []string{"a"}, File: "payloads.txt"}}, "choose only one"},
+		{[]Parameter{{Name: "payload"}}, "has no values"},
+		{[]Parameter{{Name: "payload", Values: []string{"a"}}, {Name: "payload", Values: []string{"b"}}}, "is repeated"},
+		{[]Parameter{{Name: "payload", File: "/nonexistent/payloads.txt"}}, "no such file"},
+		{[]Parameter{{Name: "payload", Values: []string{"a"}}}, "bad data"},
+	} {
+		test := Test{TestTitle: "1", Parameters: c.parameters, Stages: stages}
+		if _, err := test.expand(); err == nil || !strings.Contains(err.Error(), c.expected) {
+			t.Errorf("Failed ! expected an error with %q, got %v", c.expected, err)
+		}
+	}
+}
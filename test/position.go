@@ -0,0 +1,179 @@
+package test
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/goccy/go-yaml/ast"
+)
+
+// Position is where a test, stage, input or output was read from. Line and Column are 0 when unknown.
+type Position struct {
+	File   string
+	Line   int
+	Column int
+}
+
+// String formats the position as `file:line:column`
+func (p Position) String() string {
+	if p.Line == 0 {
+		return p.File
+	}
+	return fmt.Sprintf("%s:%d:%d", p.File, p.Line, p.Column)
+}
+
+// yamlErrorPosition matches the position goccy/go-yaml adds to its errors, like `[3:5] unknown field`
+var yamlErrorPosition = regexp.MustCompile(`^\[(\d+):(\d+)\]\s*(.*)`)
+
+// unwrapNode returns the node behind anchors and tags
+func unwrapNode(n ast.Node) ast.Node {
+	for {
+		switch v := n.(type) {
+		case *ast.AnchorNode:
+			n = v.Value
+		case *ast.TagNode:
+			n = v.Value
+		default:
+			return n
+		}
+	}
+}
+
+// mappingValues returns the key-value pairs of a mapping node, or nil if it is not a mapping
+func mappingValues(n ast.Node) []*ast.MappingValueNode {
+	switch v := unwrapNode(n).(type) {
+	case *ast.MappingNode:
+		return v.Values
+	case *ast.MappingValueNode:
+		return []*ast.MappingValueNode{v}
+	}
+	return nil
+}
+
+// sequenceValues returns the items of a sequence node, or nil if it is not a sequence
+func sequenceValues(n ast.Node) []ast.Node {
+	if v, ok := unwrapNode(n).(*ast.SequenceNode); ok {
+		return v.Values
+	}
+	return nil
+}
+
+// keyName returns the name of the key in a mapping
+func keyName(kv *ast.MappingValueNode) string {
+	key := unwrapNode(kv.Key)
+	if k, ok := key.(*ast.MappingKeyNode); ok {
+		key = unwrapNode(k.Value)
+	}
+	if key == nil || key.GetToken() == nil {
+		return ""
+	}
+	return key.GetToken().Value
+}
+
+// mappingValue returns the pair with the key in the mapping node, or nil
+func mappingValue(n ast.Node, key string) *ast.MappingValueNode {
+	for _, kv := range mappingValues(n) {
+		if keyName(kv) == key {
+			return kv
+		}
+	}
+	return nil
+}
+
+// nodeAt follows the path from n, using strings for mapping keys and ints for sequence items.
+// It returns the deepest node found, so positions point as close as possible to the problem.
+// When the last element is a key, the key node is returned.
+func nodeAt(n ast.Node, path ...interface{}) ast.Node {
+	for i, p := range path {
+		switch key := p.(type) {
+		case string:
+			kv := mappingValue(n, key)
+			if kv == nil {
+				return n
+			}
+			if i == len(path)-1 {
+				return kv.Key
+			}
+			n = kv.Value
+		case int:
+			items := sequenceValues(n)
+			if key < 0 || key >= len(items) {
+				return n
+			}
+			n = items[key]
+		}
+	}
+	return n
+}
+
+// ValueAt follows the path from n like nodeAt, returning the value at the end of it,
+// or nil when a key or item in the path is missing
+func ValueAt(n ast.Node, path ...interface{}) ast.Node {
+	for _, p := range path {
+		switch key := p.(type) {
+		case string:
+			kv := mappingValue(n, key)
+			if kv == nil {
+				return nil
+			}
+			n = kv.Value
+		case int:
+			items := sequenceValues(n)
+			if key < 0 || key >= len(items) {
+				return nil
+			}
+			n = items[key]
+		}
+	}
+	return n
+}
+
+// NodePosition returns the line and column of the node, or zeros when unknown
+func NodePosition(n ast.Node) (int, int) {
+	if n == nil {
+		return 0, 0
+	}
+	if kv, ok := n.(*ast.MappingValueNode); ok && kv.Key != nil {
+		n = kv.Key
+	}
+	if m, ok := n.(*ast.MappingNode); ok && !m.IsFlowStyle && len(m.Values) > 0 {
+		// block mappings start at their first key
+		n = m.Values[0].Key
+	}
+	tk := n.GetToken()
+	if tk == nil || tk.Position == nil {
+		return 0, 0
+	}
+	return tk.Position.Line, tk.Position.Column
+}
+
+// errorPosition extracts the position from goccy/go-yaml errors, returning the message without it
+func errorPosition(err error) (int, int, string) {
+	m := yamlErrorPosition.FindStringSubmatch(err.Error())
+	if m == nil {
+		return 0, 0, err.Error()
+	}
+	line, _ := strconv.Atoi(m[1])
+	column, _ := strconv.Atoi(m[2])
+	return line, column, m[3]
+}
+
+// recordPositions sets the position of every test, stage, input and output, using the nodes in the file
+func (f *FTWTest) recordPositions(root ast.Node) {
+	at := func(path ...interface{}) Position {
+		line, column := NodePosition(nodeAt(root, path...))
+		return Position{File: f.FileName, Line: line, Column: column}
+	}
+	for i := range f.Tests {
+		test := &f.Tests[i]
+		test.Position = at("tests", i)
+		for s := range test.Stages {
+			stage := &test.Stages[s].Stage
+			path := []interface{}{"tests", i, "stages", s, "stage"}
+			stage.Position = at(path...)
+			stage.Input.Position = at(append(path, "input")...)
+			stage.Output.Position = at(append(path, "output")...)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Output formats for the diagnostics
+const (
+	// TextFormat shows the diagnostics as `file:line:column: message`, with a summary per file
+	TextFormat = "text"
+	// JSONFormat writes a JSON document with the diagnostics for every file, and the totals
+	JSONFormat = "json"
+	// GitHubFormat writes GitHub Actions workflow commands, shown as annotations in pull requests
+	GitHubFormat = "github"
+)
+
+// Formats are the output formats accepted by WriteReport
+var Formats = []string{TextFormat, JSONFormat, GitHubFormat}
+
+// FileReport has the diagnostics found in one file
+type FileReport struct {
+	File        string       `json:"file"`
+	Diagnostics []Diagnostic `json:"diagnostics"`
+}
+
+// Report has the diagnostics for all the files checked
+type Report struct {
+	Files []FileReport `json:"files"`
+}
+
+// ReportSummary has the totals of a Report
+type ReportSummary struct {
+	Files       int `json:"files"`
+	FailedFiles int `json:"failed_files"`
+	Problems    int `json:"problems"`
+	// Warnings are the problems that are only warnings, included in Problems
+	Warnings int `json:"warnings"`
+}
+
+// CheckFiles checks every file, even after finding problems in some of them
+func CheckFiles(files []string) Report {
+	var report Report
+	for _, file := range files {
+		diagnostics := CheckFile(file)
+		if diagnostics == nil {
+			diagnostics = []Diagnostic{}
+		}
+		report.Files = append(report.Files, FileReport{File: file, Diagnostics: diagnostics})
+	}
+	return report
+}
+
+// Summary returns the totals of the report
+func (r Report) Summary() ReportSummary {
+	s := ReportSummary{Files: len(r.Files)}
+	for _, f := range r.Files {
+		if len(f.Diagnostics) > 0 {
+			s.FailedFiles++
+			s.Problems += len(f.Diagnostics)
+		}
+		for _, d := range f.Diagnostics {
+			if d.IsWarning() {
+				s.Warnings++
+			}
+		}
+	}
+	return s
+}
+
+// OK is true when no problems were found, other than warnings
+func (r Report) OK() bool {
+	s := r.Summary()
+	return s.Problems == s.Warnings
+}
+
+// String describes the totals, like `checked 3 files, found 2 problems in 1 file`
+func (s ReportSummary) String() string {
+	if s.Problems == 0 {
+		return fmt.Sprintf("checked %s, everything looks good!", plural(s.Files, "file"))
+	}
+	summary := fmt.Sprintf("checked %s, found %s in %s", plural(s.Files, "file"), plural(s.Problems, "problem"), plural(s.FailedFiles, "file"))
+	if s.Warnings > 0 {
+		summary += fmt.Sprintf(" (%s)", plural(s.Warnings, "warning"))
+	}
+	return summary
+}
+
+// WriteReport writes the report to w in one of the Formats
+func WriteReport(w io.Writer, r Report, format string) error {
+	switch format {
+	case TextFormat, "":
+		return writeTextReport(w, r)
+	case JSONFormat:
+		return writeJSONReport(w, r)
+	case GitHubFormat:
+		return writeGitHubReport(w, r)
+	}
+	return fmt.Errorf("ftw/test: unknown output format %q, use one of %s", format, strings.Join(Formats, ", "))
+}
+
+func writeTextReport(w io.Writer, r Report) error {
+	for _, f := range r.Files {
+		for _, d := range f.Diagnostics {
+			if _, err := fmt.Fprintln(w, d); err != nil {
+				return err
+			}
+		}
+	}
+
+	s := r.Summary()
+	if s.FailedFiles > 0 {
+		fmt.Fprintln(w)
+	}
+	for _, f := range r.Files {
+		if len(f.Diagnostics) > 0 {
+			fmt.Fprintf(w, "%s: %s\n", f.File, plural(len(f.Diagnostics), "problem"))
+		}
+	}
+	_, err := fmt.Fprintln(w, s)
+	return err
+}
+
+func writeJSONReport(w io.Writer, r Report) error {
+	if r.Files == nil {
+		r.Files = []FileReport{}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(struct {
+		Report
+		Summary ReportSummary `json:"summary"`
+	}{r, r.Summary()})
+}
+
+// writeGitHubReport writes an error command per diagnostic, see
+// https://docs.github.com/en/actions/using-workflows/workflow-commands-for-github-actions
+func writeGitHubReport(w io.Writer, r Report) error {
+	for _, f := range r.Files {
+		for _, d := range f.Diagnostics {
+			properties := "file=" + escapeGitHubProperty(d.File)
+			if d.Line > 0 {
+				properties += fmt.Sprintf(",line=%d,col=%d", d.Line, d.Column)
+			}
+			title := "ftw check"
+			if d.Rule != "" {
+				title = "ftw lint: " + d.Rule
+			}
+			properties += ",title=" + escapeGitHubProperty(title)
+			command := SeverityError
+			if d.IsWarning() {
+				command = SeverityWarning
+			}
+			if _, err := fmt.Fprintf(w, "::%s %s::%s\n", command, properties, escapeGitHubData(d.Message)); err != nil {
+				return err
+			}
+		}
+	}
+	_, err := fmt.Fprintln(w, r.Summary())
+	return err
+}
+
+func escapeGitHubData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+func escapeGitHubProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
+
+// plural returns the count with the noun, adding an s when needed
+func plural(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
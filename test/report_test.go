@@ -0,0 +1,130 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/fzipi/go-ftw/utils"
+)
+
+func testReport() Report {
+	return Report{Files: []FileReport{
+		{File: "tests/a.yaml", Diagnostics: []Diagnostic{
+			{File: "tests/a.yaml", Line: 3, Column: 5, Message: `unknown field "stauts"`},
+			{File: "tests/a.yaml", Line: 9, Column: 13, Message: "bad regexp in log_contains: 100%\nwrong"},
+		}},
+		{File: "tests/b.yaml", Diagnostics: []Diagnostic{}},
+		{File: "tests/c,d.yaml", Diagnostics: []Diagnostic{{File: "tests/c,d.yaml", Message: "the file has no tests"}}},
+	}}
+}
+
+func TestCheckFilesKeepsGoing(t *testing.T) {
+	bad, err := utils.CreateTempFileWithContent(yamlBadTest, "goftw-test-*.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(bad)
+	good, err := utils.CreateTempFileWithContent(yamlGoodTest, "goftw-test-*.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(good)
+
+	report := CheckFiles([]string{bad, bad, good})
+	summary := report.Summary()
+	if report.OK() || summary.Files != 3 || summary.FailedFiles != 2 || summary.Problems != 12 {
+		t.Errorf("Failed ! got %+v", summary)
+	}
+	if !CheckFiles([]string{good}).OK() {
+		t.Errorf("Failed ! good file should be ok")
+	}
+}
+
+func TestWriteTextReport(t *testing.T) {
+	var b bytes.Buffer
+	if err := WriteReport(&b, testReport(), TextFormat); err != nil {
+		t.Fatal(err)
+	}
+	expected := `tests/a.yaml:3:5: unknown field "stauts"
+tests/a.yaml:9:13: bad regexp in log_contains: 100%
+wrong
+tests/c,d.yaml: the file has no tests
+
+tests/a.yaml: 2 problems
+tests/c,d.yaml: 1 problem
+checked 3 files, found 3 problems in 2 files
+`
+	if b.String() != expected {
+		t.Errorf("Failed ! got %s", b.String())
+	}
+
+	b.Reset()
+	_ = WriteReport(&b, Report{Files: []FileReport{{File: "tests/b.yaml"}}}, TextFormat)
+	if b.String() != "checked 1 file, everything looks good!\n" {
+		t.Errorf("Failed ! got %s", b.String())
+	}
+}
+
+func TestWriteJSONReport(t *testing.T) {
+	var b bytes.Buffer
+	if err := WriteReport(&b, testReport(), JSONFormat); err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded struct {
+		Files   []FileReport  `json:"files"`
+		Summary ReportSummary `json:"summary"`
+	}
+	if err := json.Unmarshal(b.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.Files) != 3 || decoded.Files[0].Diagnostics[1].Line != 9 || decoded.Summary.Problems != 3 || decoded.Summary.FailedFiles != 2 {
+		t.Errorf("Failed ! got %s", b.String())
+	}
+}
+
+func TestWriteGitHubReport(t *testing.T) {
+	var b bytes.Buffer
+	if err := WriteReport(&b, testReport(), GitHubFormat); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(b.String(), "\n")
+	expected := []string{
+		`::error file=tests/a.yaml,line=3,col=5,title=ftw check::unknown field "stauts"`,
+		`::error file=tests/a.yaml,line=9,col=13,title=ftw check::bad regexp in log_contains: 100%25%0Awrong`,
+		`::error file=tests/c%2Cd.yaml,title=ftw check::the file has no tests`,
+		`checked 3 files, found 3 problems in 2 files`,
+	}
+	for i, line := range expected {
+		if lines[i] != line {
+			t.Errorf("Failed ! got %s, expected %s", lines[i], line)
+		}
+	}
+}
+
+func TestWriteReportUnknownFormat(t *testing.T) {
+	if err := WriteReport(&bytes.Buffer{}, Report{}, "xml"); err == nil {
+		t.Errorf("Failed ! unknown format should error")
+	}
+}
+
+func TestReportWarnings(t *testing.T) {
+	r := Report{Files: []FileReport{{File: "tests/a.yaml", Diagnostics: []Diagnostic{
+		{File: "tests/a.yaml", Line: 5, Column: 5, Message: "test 1 has no desc", Rule: "desc", Severity: SeverityWarning},
+	}}}}
+	if !r.OK() || r.Summary().String() != "checked 1 file, found 1 problem in 1 file (1 warning)" {
+		t.Errorf("Failed ! warnings must not fail, got %s", r.Summary())
+	}
+	if d := r.Files[0].Diagnostics[0].String(); d != "tests/a.yaml:5:5: warning: test 1 has no desc [desc]" {
+		t.Errorf("Failed ! got %s", d)
+	}
+
+	var b bytes.Buffer
+	_ = WriteReport(&b, r, GitHubFormat)
+	if !strings.HasPrefix(b.String(), "::warning file=tests/a.yaml,line=5,col=5,title=ftw lint%3A desc::test 1 has no desc\n") {
+		t.Errorf("Failed ! got %s", b.String())
+	}
+}
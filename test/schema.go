@@ -0,0 +1,12 @@
+package test
+
+import (
+	// embed is needed for the schema
+	_ "embed"
+)
+
+// Schema is the JSON Schema for test files. Editors using the yaml language server can use it
+// for completion and validation, with a `# yaml-language-server: $schema=<url>` comment.
+//
+//go:embed schema.json
+var Schema []byte
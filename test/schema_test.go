@@ -0,0 +1,100 @@
+package test
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/fzipi/go-ftw/ftwhttp"
+)
+
+// schemaNode resolves references to the definitions in the schema
+func schemaNode(t *testing.T, schema map[string]interface{}, node map[string]interface{}) map[string]interface{} {
+	ref, ok := node["$ref"].(string)
+	if !ok {
+		return node
+	}
+	definitions := schema["definitions"].(map[string]interface{})
+	resolved, ok := definitions[strings.TrimPrefix(ref, "#/definitions/")].(map[string]interface{})
+	if !ok {
+		t.Fatalf("Failed ! bad reference %s", ref)
+	}
+	return resolved
+}
+
+// checkSchema checks the schema has the same fields as the type, at the same place
+func checkSchema(t *testing.T, schema map[string]interface{}, node map[string]interface{}, typ reflect.Type, path string) {
+	node = schemaNode(t, schema, node)
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ == reflect.TypeOf(ExpectError("")) {
+		return
+	}
+
+	switch typ.Kind() {
+	case reflect.Struct:
+		properties, _ := node["properties"].(map[string]interface{})
+		if node["additionalProperties"] != false {
+			t.Errorf("Failed ! %s should not allow additional properties", path)
+		}
+		fields := yamlFields(typ)
+		for name, field := range fields {
+			property, ok := properties[name].(map[string]interface{})
+			if !ok {
+				t.Errorf("Failed ! %s.%s is not in the schema", path, name)
+				continue
+			}
+			checkSchema(t, schema, property, field.Type, path+"."+name)
+		}
+		for name := range properties {
+			if _, ok := fields[name]; !ok {
+				t.Errorf("Failed ! %s.%s is in the schema, but not in the types", path, name)
+			}
+		}
+	case reflect.Slice:
+		items, ok := node["items"].(map[string]interface{})
+		if !ok {
+			t.Errorf("Failed ! %s should be an array", path)
+			return
+		}
+		checkSchema(t, schema, items, typ.Elem(), path+"[]")
+	}
+}
+
+func TestSchemaMatchesTypes(t *testing.T) {
+	var schema map[string]interface{}
+	if err := json.Unmarshal(Schema, &schema); err != nil {
+		t.Fatalf("Failed ! schema is not valid json: %s", err.Error())
+	}
+	checkSchema(t, schema, schema, reflect.TypeOf(FTWTest{}), "")
+}
+
+func TestSchemaErrorClasses(t *testing.T) {
+	var schema struct {
+		Definitions struct {
+			Output struct {
+				Properties struct {
+					ExpectError struct {
+						OneOf []struct {
+							Enum []string `json:"enum"`
+						} `json:"oneOf"`
+					} `json:"expect_error"`
+				} `json:"properties"`
+			} `json:"output"`
+		} `json:"definitions"`
+	}
+	if err := json.Unmarshal(Schema, &schema); err != nil {
+		t.Fatal(err)
+	}
+
+	enum := strings.Join(schema.Definitions.Output.Properties.ExpectError.OneOf[1].Enum, ",")
+	expected := string(AnyError)
+	for _, class := range ftwhttp.ErrorClasses {
+		expected += "," + string(class)
+	}
+	if enum != expected {
+		t.Errorf("Failed ! schema has %s, expected %s", enum, expected)
+	}
+}
@@ -0,0 +1,36 @@
+package test
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// tagName matches the names allowed for tags, so they can be used in tag expressions
+var tagName = regexp.MustCompile(`^[A-Za-z0-9_.:/-]+$`)
+
+// IsValidTag is true when the tag can be used in tag expressions: letters, digits, and `_ . : / -`
+func IsValidTag(tag string) bool {
+	return tagName.MatchString(tag)
+}
+
+func validateTag(tag string) error {
+	if !IsValidTag(tag) {
+		return fmt.Errorf("bad tag %q: use only letters, digits, and _ . : / -", tag)
+	}
+	return nil
+}
+
+// TestTags returns the tags of the test, including the ones in the meta of the file, which all its tests inherit
+func (f *FTWTest) TestTags(t Test) []string {
+	var tags []string
+	seen := make(map[string]bool)
+	for _, list := range [][]string{f.Meta.Tags, t.Tags} {
+		for _, tag := range list {
+			if !seen[tag] {
+				seen[tag] = true
+				tags = append(tags, tag)
+			}
+		}
+	}
+	return tags
+}
@@ -0,0 +1,56 @@
+package test
+
+import (
+	"reflect"
+	"testing"
+)
+
+var yamlTagsTest = `---
+meta:
+  author: "tester"
+  enabled: true
+  tags: [sqli, pl1]
+tests:
+  - test_title: 942100-1
+    tags: [pl1, slow]
+    stages:
+      - stage:
+          input:
+            uri: "/"
+          output:
+            status: [200]
+  - test_title: 942100-2
+    tags: ["bad tag"]
+    stages:
+      - stage:
+          input:
+            uri: "/"
+          output:
+            status: [200]
+`
+
+func TestTestTags(t *testing.T) {
+	var f FTWTest
+	f.Meta.Tags = []string{"sqli", "pl1"}
+	if tags := f.TestTags(Test{Tags: []string{"pl1", "slow"}}); !reflect.DeepEqual(tags, []string{"sqli", "pl1", "slow"}) {
+		t.Errorf("Failed ! got %v", tags)
+	}
+	if tags := (&FTWTest{}).TestTags(Test{}); len(tags) != 0 {
+		t.Errorf("Failed ! got %v", tags)
+	}
+}
+
+func TestIsValidTag(t *testing.T) {
+	for tag, expected := range map[string]bool{"sqli": true, "crs:942": true, "pl-2": true, "a/b.c_d": true, "": false, "a b": false, "a&b": false, "!a": false} {
+		if IsValidTag(tag) != expected {
+			t.Errorf("Failed ! tag %q should be valid: %v", tag, expected)
+		}
+	}
+}
+
+func TestCheckFileTags(t *testing.T) {
+	diagnostics := checkString(t, yamlTagsTest)
+	if len(diagnostics) != 1 || diagnostics[0].String() != `test.yaml:16:12: bad tag "bad tag": use only letters, digits, and _ . : / -` {
+		t.Errorf("Failed ! got %v", diagnostics)
+	}
+}
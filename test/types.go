@@ -13,31 +13,92 @@ type Input struct {
 	Headers        ftwhttp.Header `yaml:"headers,omitempty"`
 	Method         *string        `yaml:"method,omitempty"`
 	Data           *string        `yaml:"data,omitempty"`
-	SaveCookie     bool           `yaml:"save_cookie,omitempty"`
-	StopMagic      bool           `yaml:"stop_magic"`
+	DataBase64     string         `yaml:"data_base64,omitempty"`
+	DataHex        string         `yaml:"data_hex,omitempty"`
+	DataFile       string         `yaml:"data_file,omitempty"`
+	SaveCookie     *bool          `yaml:"save_cookie,omitempty"`
+	StopMagic      *bool          `yaml:"stop_magic,omitempty"`
 	EncodedRequest string         `yaml:"encoded_request,omitempty"`
 	RAWRequest     string         `yaml:"raw_request,omitempty"`
+	// Chunked is a body sent using chunked transfer encoding
+	Chunked *ftwhttp.ChunkedBody `yaml:"chunked,omitempty"`
+	// Multipart is a multipart/form-data body
+	Multipart *ftwhttp.MultipartBody `yaml:"multipart,omitempty"`
+	// Compression is the content encoding used for compressing the body: gzip, deflate or br
+	Compression string `yaml:"compression,omitempty"`
+	// WebSocket has the frames sent after a websocket handshake
+	WebSocket *ftwhttp.WebSocket `yaml:"websocket,omitempty"`
+	// GRPC is a gRPC unary call, sent instead of the HTTP/1 request
+	GRPC *ftwhttp.GRPC `yaml:"grpc,omitempty"`
+	// Transmission controls how the request is written to the connection
+	Transmission *ftwhttp.Transmission `yaml:"transmission,omitempty"`
+	// Position is where the input is in the test file
+	Position Position `yaml:"-"`
+	// Arguments are the values of the parameters of the test, used in the templates in data
+	Arguments map[string]string `yaml:"-"`
 }
 
 // Output is the response expected from the test
 type Output struct {
-	Status           []int  `yaml:"status,flow,omitempty"`
-	ResponseContains string `yaml:"response_contains,omitempty"`
-	LogContains      string `yaml:"log_contains,omitempty"`
-	NoLogContains    string `yaml:"no_log_contains,omitempty"`
-	ExpectError      bool   `yaml:"expect_error,omitempty"`
+	Status           []int       `yaml:"status,flow,omitempty"`
+	ResponseContains string      `yaml:"response_contains,omitempty"`
+	LogContains      string      `yaml:"log_contains,omitempty"`
+	NoLogContains    string      `yaml:"no_log_contains,omitempty"`
+	ExpectError      ExpectError `yaml:"expect_error,omitempty"`
+	// GRPCStatus is the list of grpc-status values expected in gRPC calls
+	GRPCStatus []int `yaml:"grpc_status,flow,omitempty"`
+	// WebSocket has what is expected after a websocket handshake
+	WebSocket *WebSocketOutput `yaml:"websocket,omitempty"`
+	// Position is where the output is in the test file
+	Position Position `yaml:"-"`
+}
+
+// WebSocketOutput is what is expected after a websocket handshake. All the fields set must match.
+type WebSocketOutput struct {
+	// MessageContains must be in some message received
+	MessageContains string `yaml:"message_contains,omitempty"`
+	// Closed is true if the server must close the connection, false if it must keep it open
+	Closed *bool `yaml:"closed,omitempty"`
+}
+
+// StageData is the input request and the expected output of a stage
+type StageData struct {
+	Input  Input  `yaml:"input"`
+	Output Output `yaml:"output"`
+	// Position is where the stage is in the test file
+	Position Position `yaml:"-"`
+}
+
+// Stage is a step in a test
+type Stage struct {
+	Stage StageData `yaml:"stage"`
 }
 
 // Test is an individual test
 type Test struct {
 	TestTitle       string `yaml:"test_title"`
 	TestDescription string `yaml:"desc,omitempty"`
-	Stages          []struct {
-		Stage struct {
-			Input  Input  `yaml:"input"`
-			Output Output `yaml:"output"`
-		} `yaml:"stage"`
-	} `yaml:"stages"`
+	// Tags are used for selecting tests, like `sqli` or `pl2`
+	Tags []string `yaml:"tags,flow,omitempty"`
+	// Defaults are used in the input of every stage of the test, over the defaults of the file
+	Defaults *Input `yaml:"defaults,omitempty"`
+	// Parameters expand the test into one test for each combination of their values
+	Parameters []Parameter `yaml:"parameters,omitempty"`
+	// Attack is the attack string in the input of the stages, which is changed for checking evasions
+	Attack string  `yaml:"attack,omitempty"`
+	Stages []Stage `yaml:"stages"`
+	// Position is where the test is in the test file
+	Position Position `yaml:"-"`
+	// ParentTitle is the title of the test this one was expanded from, when it has parameters
+	ParentTitle string `yaml:"-"`
+}
+
+// Parameter is a value that changes in each test expanded from a test, used in templates like `{{ .payload }}`.
+// Values are written inline, or read from a file with one value per line.
+type Parameter struct {
+	Name   string   `yaml:"name"`
+	Values []string `yaml:"values,omitempty"`
+	File   string   `yaml:"file,omitempty"`
 }
 
 // FTWTest is the base type used when unmarshaling
@@ -48,6 +109,10 @@ type FTWTest struct {
 		Enabled     bool   `yaml:"enabled,omitempty"`
 		Name        string `yaml:"name,omitempty"`
 		Description string `yaml:"description,omitempty"`
+		// Tags are inherited by all the tests in the file
+		Tags []string `yaml:"tags,flow,omitempty"`
 	} `yaml:"meta"`
-	Tests []Test `yaml:"tests"`
+	// Defaults are used in the input of every stage of every test in the file
+	Defaults *Input `yaml:"defaults,omitempty"`
+	Tests    []Test `yaml:"tests"`
 }
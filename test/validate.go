@@ -0,0 +1,168 @@
+package test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/fzipi/go-ftw/ftwhttp"
+	"github.com/fzipi/go-ftw/utils"
+)
+
+// bodySources returns the names of the fields used for the body of the request
+func (i *Input) bodySources() []string {
+	var sources []string
+	for _, s := range []struct {
+		name    string
+		present bool
+	}{
+		{"data", utils.IsNotEmpty(i.Data)},
+		{"data_base64", i.DataBase64 != ""},
+		{"data_hex", i.DataHex != ""},
+		{"data_file", i.DataFile != ""},
+		{"chunked", i.Chunked != nil},
+		{"multipart", i.Multipart != nil},
+		{"encoded_request", i.EncodedRequest != ""},
+		{"raw_request", i.RAWRequest != ""},
+	} {
+		if s.present {
+			sources = append(sources, s.name)
+		}
+	}
+	return sources
+}
+
+// bodySourcesError is the error for inputs using more than one body source
+func bodySourcesError(sources []string) error {
+	return fmt.Errorf("choose only one of data, data_base64, data_hex, data_file, chunked, multipart, encoded_request, or raw_request; found %s",
+		strings.Join(sources, ", "))
+}
+
+// Validate checks the input can be used for sending a request: only one body source
+// is used, encoded bodies can be decoded, and the options for the body and transmission make sense.
+func (i *Input) Validate() error {
+	if sources := i.bodySources(); len(sources) > 1 {
+		return bodySourcesError(sources)
+	}
+	if i.DataFile != "" {
+		if _, err := os.Stat(i.DataFile); err != nil {
+			return fmt.Errorf("bad data_file: %w", err)
+		}
+	} else if _, err := i.GetExactData(); err != nil {
+		return fmt.Errorf("cannot decode body: %w", err)
+	}
+	if _, err := i.GetRawRequest(); err != nil {
+		return fmt.Errorf("cannot decode encoded_request: %w", err)
+	}
+	if i.Multipart != nil {
+		if err := i.Multipart.Validate(); err != nil {
+			return err
+		}
+	}
+	if i.Compression != "" {
+		if err := ftwhttp.ValidateEncoding(i.Compression); err != nil {
+			return err
+		}
+		if i.Chunked != nil || i.EncodedRequest != "" || i.RAWRequest != "" {
+			return errors.New("compression cannot be used with chunked, encoded_request or raw_request")
+		}
+	}
+	if i.WebSocket != nil {
+		if err := i.WebSocket.Validate(); err != nil {
+			return err
+		}
+	}
+	if i.GRPC != nil {
+		if err := i.GRPC.Validate(); err != nil {
+			return err
+		}
+		if len(i.bodySources()) > 0 || i.WebSocket != nil {
+			return errors.New("grpc cannot be used with a body, websocket, encoded_request or raw_request")
+		}
+	}
+	if i.Transmission != nil {
+		if err := i.Transmission.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Validate checks the expected output can be used: status codes are valid, and log regexps compile
+func (o *Output) Validate() error {
+	for _, status := range o.Status {
+		if err := validateStatus(status); err != nil {
+			return err
+		}
+	}
+	for _, status := range o.GRPCStatus {
+		if err := validateGRPCStatus(status); err != nil {
+			return err
+		}
+	}
+	for _, l := range o.logRegexps() {
+		if err := validateLogRegexp(l.name, l.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// logRegexp is a regexp used for matching logs, with the name of its field
+type logRegexp struct {
+	name  string
+	value string
+}
+
+// logRegexps returns the regexps used for matching logs
+func (o *Output) logRegexps() []logRegexp {
+	return []logRegexp{{"log_contains", o.LogContains}, {"no_log_contains", o.NoLogContains}}
+}
+
+func validateStatus(status int) error {
+	if status < 100 || status > 599 {
+		return fmt.Errorf("status %d is not a valid HTTP status code", status)
+	}
+	return nil
+}
+
+func validateGRPCStatus(status int) error {
+	if status < 0 || status > 16 {
+		return fmt.Errorf("grpc_status %d is not a valid gRPC status code", status)
+	}
+	return nil
+}
+
+func validateLogRegexp(name string, value string) error {
+	if _, err := regexp.Compile(value); err != nil {
+		return fmt.Errorf("bad regexp in %s: %w", name, err)
+	}
+	return nil
+}
+
+// Validate checks the tags, and the input and output in every stage of every test in the file
+func (f *FTWTest) Validate() error {
+	for _, tag := range f.Meta.Tags {
+		if err := validateTag(tag); err != nil {
+			return fmt.Errorf("%s: %w", f.FileName, err)
+		}
+	}
+	for _, test := range f.Tests {
+		for _, tag := range test.Tags {
+			if err := validateTag(tag); err != nil {
+				return fmt.Errorf("%s: test %s: %w", f.FileName, test.TestTitle, err)
+			}
+		}
+		for n, stage := range test.Stages {
+			if err := stage.Stage.Input.Validate(); err != nil {
+				return fmt.Errorf("%s: test %s, stage %d: %w", f.FileName, test.TestTitle, n+1, err)
+			}
+			if err := stage.Stage.Output.Validate(); err != nil {
+				return fmt.Errorf("%s: test %s, stage %d: %w", f.FileName, test.TestTitle, n+1, err)
+			}
+		}
+	}
+	return nil
+}
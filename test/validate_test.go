@@ -0,0 +1,164 @@
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fzipi/go-ftw/ftwhttp"
+)
+
+func TestValidateBodySources(t *testing.T) {
+	data := "a=1"
+	chunked := &ftwhttp.ChunkedBody{Chunks: []ftwhttp.Chunk{{Data: "a=1"}}}
+
+	good := []Input{
+		{},
+		{Data: &data},
+		{Chunked: chunked},
+		{DataBase64: "AAEC"},
+		{DataHex: "00 01\n02"},
+	}
+	for _, i := range good {
+		i := i
+		if err := i.Validate(); err != nil {
+			t.Errorf("Failed ! %s", err.Error())
+		}
+	}
+
+	bad := []Input{
+		{Chunked: chunked, Data: &data},
+		{Chunked: chunked, RAWRequest: "GET / HTTP/1.1\r\n\r\n"},
+		{DataBase64: "AAEC", DataHex: "000102"},
+		{DataBase64: "%%%"},
+		{DataHex: "0g"},
+		{DataFile: "/this/file/does/not/exist"},
+		{EncodedRequest: "%%%"},
+		{Transmission: &ftwhttp.Transmission{ChunkSize: -1}},
+	}
+	for _, i := range bad {
+		i := i
+		if err := i.Validate(); err == nil {
+			t.Errorf("Failed ! %+v should not be valid", i)
+		}
+	}
+}
+
+func TestValidateReportsSources(t *testing.T) {
+	data := "a=1"
+	i := Input{Data: &data, DataHex: "00"}
+	err := i.Validate()
+	if err == nil || !strings.Contains(err.Error(), "found data, data_hex") {
+		t.Errorf("Failed ! got %v", err)
+	}
+}
+
+func TestGetExactData(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "body.bin")
+	if err := os.WriteFile(file, []byte{0, 0xff, 'a'}, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, i := range []Input{
+		{DataBase64: "AP9h"},
+		{DataHex: "00ff61"},
+		{DataHex: "00 ff\n61"},
+		{DataFile: file},
+	} {
+		i := i
+		data, err := i.GetExactData()
+		if err != nil || string(data) != "\x00\xffa" {
+			t.Errorf("Failed ! got %q, %v", data, err)
+		}
+	}
+
+	i := Input{}
+	if data, err := i.GetExactData(); data != nil || err != nil {
+		t.Errorf("Failed !")
+	}
+}
+
+func TestFTWTestValidate(t *testing.T) {
+	data := "a=1"
+	ft := FTWTest{FileName: "test.yaml", Tests: []Test{
+		{TestTitle: "1", Stages: []Stage{{Stage: StageData{Input: Input{Data: &data}}}}},
+		{TestTitle: "2", Stages: []Stage{
+			{Stage: StageData{Input: Input{}}},
+			{Stage: StageData{Input: Input{Data: &data, RAWRequest: "a"}}},
+		}},
+	}}
+
+	err := ft.Validate()
+	if err == nil || !strings.HasPrefix(err.Error(), "test.yaml: test 2, stage 2:") {
+		t.Errorf("Failed ! got %v", err)
+	}
+}
+
+func TestValidateCompression(t *testing.T) {
+	data := "a=1"
+	good := Input{Data: &data, Compression: "br"}
+	if err := good.Validate(); err != nil {
+		t.Errorf("Failed ! %s", err.Error())
+	}
+
+	for _, i := range []Input{
+		{Data: &data, Compression: "zstd"},
+		{RAWRequest: "GET / HTTP/1.1\r\n\r\n", Compression: "gzip"},
+		{Chunked: &ftwhttp.ChunkedBody{}, Compression: "gzip"},
+	} {
+		i := i
+		if err := i.Validate(); err == nil {
+			t.Errorf("Failed ! %+v should not be valid", i)
+		}
+	}
+}
+
+func TestValidateGRPC(t *testing.T) {
+	data := "a=1"
+	call := &ftwhttp.GRPC{Service: "helloworld.Greeter", Method: "SayHello", MessageBase64: "CgNmdHc="}
+
+	good := Input{GRPC: call}
+	if err := good.Validate(); err != nil {
+		t.Errorf("Failed ! %s", err.Error())
+	}
+
+	for _, i := range []Input{
+		{GRPC: &ftwhttp.GRPC{Method: "SayHello"}},
+		{GRPC: call, Data: &data},
+		{GRPC: call, WebSocket: &ftwhttp.WebSocket{}},
+	} {
+		i := i
+		if err := i.Validate(); err == nil {
+			t.Errorf("Failed ! %+v should not be valid", i)
+		}
+	}
+}
+
+func TestValidateOutput(t *testing.T) {
+	good := []Output{
+		{},
+		{Status: []int{200, 403}, LogContains: `id "942100"`},
+		{GRPCStatus: []int{0, 7}},
+	}
+	for _, o := range good {
+		o := o
+		if err := o.Validate(); err != nil {
+			t.Errorf("Failed ! %s", err.Error())
+		}
+	}
+
+	bad := []Output{
+		{Status: []int{42}},
+		{Status: []int{200, 1000}},
+		{GRPCStatus: []int{17}},
+		{LogContains: "id (1"},
+		{NoLogContains: "[a"},
+	}
+	for _, o := range bad {
+		o := o
+		if o.Validate() == nil {
+			t.Errorf("Failed ! %+v should not be valid", o)
+		}
+	}
+}
@@ -13,11 +13,19 @@ import (
 
 // Contains looks in logfile for regex
 func (ll *FTWLogLines) Contains(match string) bool {
-	// this should be a flag
-	lines := ll.getLinesSinceUntil()
-	// if we need to truncate file
-	if ll.LogTruncate {
-		ll.truncateLogFile()
+	var lines [][]byte
+	if ll.Sink != nil {
+		lines = ll.Sink.LinesBetween(ll.Since, ll.Until)
+		if ll.LogTruncate {
+			ll.Sink.Reset()
+		}
+	} else {
+		// this should be a flag
+		lines = ll.getLinesSinceUntil()
+		// if we need to truncate file
+		if ll.LogTruncate {
+			ll.truncateLogFile()
+		}
 	}
 	log.Trace().Msgf("ftw/waflog: got %d lines", len(lines))
 
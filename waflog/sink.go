@@ -0,0 +1,85 @@
+package waflog
+
+import (
+	"bytes"
+	"sync"
+	"time"
+)
+
+// MemorySink keeps WAF log lines in memory, so tests can check logs without a log file.
+// Each line written is stored with the time it was received, so no time regex or format is needed.
+// It is safe for concurrent use.
+type MemorySink struct {
+	mu      sync.Mutex
+	lines   []sinkLine
+	partial []byte
+}
+
+type sinkLine struct {
+	received time.Time
+	line     []byte
+}
+
+// NewMemorySink creates an empty MemorySink
+func NewMemorySink() *MemorySink {
+	return &MemorySink{}
+}
+
+// Write implements io.Writer. Data is split in lines, and an unterminated line
+// is kept until the rest of it arrives.
+func (s *MemorySink) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	data := append(s.partial, p...)
+	for {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			break
+		}
+		s.addLine(now, data[:i])
+		data = data[i+1:]
+	}
+	s.partial = append([]byte(nil), data...)
+
+	return len(p), nil
+}
+
+// WriteLine adds one line to the sink
+func (s *MemorySink) WriteLine(line string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.addLine(time.Now(), []byte(line))
+}
+
+// Reset removes all lines from the sink
+func (s *MemorySink) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lines = nil
+	s.partial = nil
+}
+
+// LinesBetween returns the lines received between since and until, both included
+func (s *MemorySink) LinesBetween(since time.Time, until time.Time) [][]byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var found [][]byte
+	for _, l := range s.lines {
+		if l.received.Before(since) || l.received.After(until) {
+			continue
+		}
+		found = append(found, l.line)
+	}
+	return found
+}
+
+func (s *MemorySink) addLine(received time.Time, line []byte) {
+	saneCopy := make([]byte, len(line))
+	copy(saneCopy, line)
+	s.lines = append(s.lines, sinkLine{received: received, line: saneCopy})
+}
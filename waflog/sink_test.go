@@ -0,0 +1,52 @@
+package waflog
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemorySinkLines(t *testing.T) {
+	sink := NewMemorySink()
+	since := time.Now()
+
+	_, _ = sink.Write([]byte("first line\nsecond "))
+	_, _ = sink.Write([]byte("line\nunfinished"))
+	sink.WriteLine("third line")
+
+	lines := sink.LinesBetween(since, time.Now())
+	if len(lines) != 3 {
+		t.Fatalf("Expected 3 lines, got %d: %q", len(lines), lines)
+	}
+	if string(lines[1]) != "second line" {
+		t.Errorf("Lines split in several writes should be joined, got %q", lines[1])
+	}
+
+	if lines = sink.LinesBetween(time.Now().Add(time.Hour), time.Now().Add(2*time.Hour)); len(lines) != 0 {
+		t.Errorf("No lines expected in the future, got %q", lines)
+	}
+
+	sink.Reset()
+	if lines = sink.LinesBetween(since, time.Now()); len(lines) != 0 {
+		t.Errorf("No lines expected after reset, got %q", lines)
+	}
+}
+
+func TestMemorySinkContains(t *testing.T) {
+	sink := NewMemorySink()
+	ll := &FTWLogLines{
+		Since:       time.Now(),
+		LogTruncate: true,
+		Sink:        sink,
+	}
+
+	sink.WriteLine(`ModSecurity: Warning. [id "949110"]`)
+	ll.Until = time.Now()
+
+	if !ll.Contains(`id "949110"`) {
+		t.Error("Expected to find the log line in the sink")
+	}
+
+	if ll.Contains(`id "949110"`) {
+		t.Error("Sink should be empty after truncating")
+	}
+}